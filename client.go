@@ -0,0 +1,438 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ktr0731/go-mcp/protocol"
+	"golang.org/x/exp/jsonrpc2"
+)
+
+// ToolCallError is an error converted from a tool call result whose isError is true.
+// It is returned by client methods that call tools, e.g. Client.CallTool.
+type ToolCallError struct {
+	// Message is the text content of the result, joined by newlines.
+	Message string
+	// Result is the raw result of the tool call, which contains non-text content as well.
+	Result json.RawMessage
+}
+
+func (e *ToolCallError) Error() string {
+	if e.Message == "" {
+		return "tool call failed"
+	}
+	return "tool call failed: " + e.Message
+}
+
+// ToolResultError converts a raw tool call result into an error.
+// It returns a *ToolCallError if isError of the result is true, and nil otherwise.
+func ToolResultError(result json.RawMessage) error {
+	var res struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		IsError bool `json:"isError"`
+	}
+	if err := json.Unmarshal(result, &res); err != nil {
+		return fmt.Errorf("failed to unmarshal tool call result: %w", err)
+	}
+	if !res.IsError {
+		return nil
+	}
+
+	var texts []string
+	for _, c := range res.Content {
+		if c.Type == "text" {
+			texts = append(texts, c.Text)
+		}
+	}
+	return &ToolCallError{Message: strings.Join(texts, "\n"), Result: result}
+}
+
+// ClientOptions are options of a Client.
+type ClientOptions struct {
+	// ClientInfo is the name and version of the client sent by the initialize request.
+	ClientInfo protocol.Implementation
+	// Capabilities are the capabilities of the client sent by the initialize request.
+	// The client doesn't handle server-initiated requests other than ping,
+	// so they shouldn't declare features such as roots and sampling.
+	Capabilities protocol.ClientCapabilities
+	// ProtocolVersion is the protocol version requested by the initialize request.
+	// If this is not set, protocol.LatestProtocolVersion is used.
+	ProtocolVersion string
+
+	// OnLog is called with each log notification (notifications/message) sent by the server.
+	// It is called by the reader of the connection, so it must not block.
+	OnLog func(*LogMessage)
+	// OnNotification is called with each notification other than log and progress notifications,
+	// e.g. notifications/resources/updated. It is called by the reader of the connection, so it must not block.
+	OnNotification func(method string, params json.RawMessage)
+}
+
+// LogMessage is the params of a log notification (notifications/message) sent by the server.
+type LogMessage struct {
+	Level protocol.LogLevel `json:"level"`
+	// Logger is the name of the logger which issued the message, if any.
+	Logger string `json:"logger,omitzero"`
+	// Data is the message, which is a string or an object of the attributes of the log record.
+	Data json.RawMessage `json:"data"`
+}
+
+// Client is a client of an MCP server.
+// It is initialized by NewClient, so its methods can be called right away.
+//
+// Progress notifications of a request can be received by passing a context from WithProgressHandler,
+// in the same way as server-initiated requests.
+type Client struct {
+	conn *jsonrpc2.Connection
+	// s tracks the progress tokens of requests carrying them.
+	s *session
+
+	initializeResult *protocol.InitializeResult
+	// onClose is called after the connection is closed, e.g. to stop the in-memory server.
+	onClose func()
+}
+
+// NewClient connects to the server by dialer and initializes the session.
+// Messages are delimited by newlines as the stdio transport does.
+// The connection is closed when ctx is canceled or the client is closed.
+//
+// See https://modelcontextprotocol.io/specification/2025-03-26/basic/lifecycle#initialization
+func NewClient(ctx context.Context, dialer jsonrpc2.Dialer, opts *ClientOptions) (*Client, error) {
+	if opts == nil {
+		opts = &ClientOptions{}
+	}
+
+	c, err := dialClient(ctx, dialer, opts)
+	if err != nil {
+		return nil, err
+	}
+	version := opts.ProtocolVersion
+	if version == "" {
+		version = protocol.LatestProtocolVersion
+	}
+	err = c.initialize(ctx, protocol.InitializeRequestParams{
+		ProtocolVersion: version,
+		Capabilities:    opts.Capabilities,
+		ClientInfo:      opts.ClientInfo,
+	})
+	if err != nil {
+		c.conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// dialClient connects to the server by dialer without initializing the session.
+func dialClient(ctx context.Context, dialer jsonrpc2.Dialer, opts *ClientOptions) (*Client, error) {
+	s := &session{ctx: ctx}
+	conn, err := jsonrpc2.Dial(ctx, dialer, jsonrpc2.ConnectionOptions{
+		Framer:  &framer{Framer: jsonrpc2.RawFramer(), onRead: s.handleProgress},
+		Handler: jsonrpc2.HandlerFunc(opts.handle),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial: %w", err)
+	}
+	s.conn = conn
+	return &Client{conn: conn, s: s}, nil
+}
+
+// initialize performs the initialization phase of the lifecycle by an initialize request with params
+// followed by notifications/initialized.
+func (c *Client) initialize(ctx context.Context, params protocol.InitializeRequestParams) error {
+	var res protocol.InitializeResult
+	if err := c.conn.Call(ctx, protocol.MethodInitialize, params).Await(ctx, &res); err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+	if err := c.conn.Notify(ctx, protocol.MethodNotificationsInitialized, struct{}{}); err != nil {
+		return fmt.Errorf("failed to send %s: %w", protocol.MethodNotificationsInitialized, err)
+	}
+	c.initializeResult = &res
+	return nil
+}
+
+// handle handles messages sent by the server.
+func (o *ClientOptions) handle(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+	switch {
+	case req.Method == protocol.MethodPing:
+		return struct{}{}, nil
+	case req.IsCall():
+		return nil, jsonrpc2.ErrMethodNotFound
+	case req.Method == protocol.MethodNotificationsMessage:
+		if o.OnLog == nil {
+			return nil, nil
+		}
+		var msg LogMessage
+		if err := json.Unmarshal(req.Params, &msg); err != nil {
+			// Notifications have no response, so malformed ones are ignored.
+			return nil, nil
+		}
+		o.OnLog(&msg)
+		return nil, nil
+	default:
+		if o.OnNotification != nil {
+			o.OnNotification(req.Method, req.Params)
+		}
+		return nil, nil
+	}
+}
+
+// InitializeResult returns the result of the initialize request, e.g. the capabilities of the server.
+func (c *Client) InitializeResult() *protocol.InitializeResult {
+	return c.initializeResult
+}
+
+// Call sends a request to the server and returns the raw result.
+// If the server returns an error response, the error has the code of the response (e.g. jsonrpc2.ErrInvalidParams).
+func (c *Client) Call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	var res json.RawMessage
+	if err := c.s.call(ctx, method, params, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Notify sends a notification to the server.
+func (c *Client) Notify(ctx context.Context, method string, params any) error {
+	return c.conn.Notify(ctx, method, params)
+}
+
+// ListTools returns all tools offered by the server, following pagination cursors.
+func (c *Client) ListTools(ctx context.Context) ([]protocol.Tool, error) {
+	return list[protocol.Tool](ctx, c, protocol.MethodToolsList, "tools")
+}
+
+// CallTool calls the tool with the given name and returns the result.
+// If the result has isError set, it returns a *ToolCallError, which also holds the raw result.
+// The binary data of image and audio content is decoded into a bytes.Reader.
+func (c *Client) CallTool(ctx context.Context, name string, args any) (*CallToolResult, error) {
+	b, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal arguments: %w", err)
+	}
+	res, err := c.Call(ctx, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: name, Arguments: b})
+	if err != nil {
+		return nil, err
+	}
+	if err := ToolResultError(res); err != nil {
+		return nil, err
+	}
+	var result CallToolResult
+	if err := json.Unmarshal(res, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the tool call result: %w", err)
+	}
+	return &result, nil
+}
+
+// ListPrompts returns all prompts offered by the server, following pagination cursors.
+func (c *Client) ListPrompts(ctx context.Context) ([]protocol.Prompt, error) {
+	return list[protocol.Prompt](ctx, c, protocol.MethodPromptsList, "prompts")
+}
+
+// GetPrompt gets the prompt with the given name.
+func (c *Client) GetPrompt(ctx context.Context, name string, args map[string]string) (*GetPromptResult, error) {
+	b, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal arguments: %w", err)
+	}
+	res, err := c.Call(ctx, protocol.MethodPromptsGet, protocol.GetPromptRequestParams{Name: name, Arguments: b})
+	if err != nil {
+		return nil, err
+	}
+	var result GetPromptResult
+	if err := json.Unmarshal(res, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the prompt: %w", err)
+	}
+	return &result, nil
+}
+
+// ListResources returns all resources offered by the server, following pagination cursors.
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	return list[Resource](ctx, c, protocol.MethodResourcesList, "resources")
+}
+
+// ReadResource reads the resource with the given URI.
+// The binary data of blob contents is decoded into a bytes.Reader.
+func (c *Client) ReadResource(ctx context.Context, uri string) (*ReadResourceResult, error) {
+	res, err := c.Call(ctx, protocol.MethodResourcesRead, ReadResourceRequest{URI: uri})
+	if err != nil {
+		return nil, err
+	}
+	var result ReadResourceResult
+	if err := json.Unmarshal(res, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the resource: %w", err)
+	}
+	return &result, nil
+}
+
+// Complete requests completion options for an argument of a prompt or a resource template.
+// Labeled values (CompleteResult.Items) are not supported, so Capabilities shouldn't declare CompletionItemsCapability.
+func (c *Client) Complete(ctx context.Context, params *CompleteRequestParams) (*CompleteResult, error) {
+	res, err := c.Call(ctx, protocol.MethodCompletionComplete, params)
+	if err != nil {
+		return nil, err
+	}
+	var v struct {
+		Completion CompleteResult `json:"completion"`
+	}
+	if err := json.Unmarshal(res, &v); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the completion result: %w", err)
+	}
+	return &v.Completion, nil
+}
+
+// Close closes the connection to the server.
+func (c *Client) Close() error {
+	err := c.conn.Close()
+	if c.onClose != nil {
+		c.onClose()
+	}
+	return err
+}
+
+// StdioClientTransportOptions are options of StdioClientTransport.
+type StdioClientTransportOptions struct {
+	// Stderr receives the standard error of the server process, which servers use for logs.
+	// If this is not set, it is forwarded to os.Stderr of the client process.
+	Stderr io.Writer
+	// ShutdownTimeout is how long Close waits for the server process to exit after closing its standard input.
+	// When it elapses, the process is killed. If this is not set, 5 seconds is used.
+	ShutdownTimeout time.Duration
+}
+
+// StdioClientTransport runs an MCP server as a child process and talks to it over its standard input and output,
+// as hosts launching local servers do. It is a jsonrpc2.Dialer to be passed to NewClient.
+//
+// See https://modelcontextprotocol.io/specification/2025-03-26/basic/transports#stdio
+type StdioClientTransport struct {
+	cmd   *exec.Cmd
+	stdio stdio
+
+	shutdownTimeout time.Duration
+	// exited is closed when the process exits.
+	exited    chan struct{}
+	waitErr   error
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewStdioClientTransport starts cmd as a server process. cmd must not have Stdin and Stdout set.
+// The process is stopped when ctx is canceled, or when the transport or the connection dialed by it is closed.
+func NewStdioClientTransport(ctx context.Context, cmd *exec.Cmd, opts *StdioClientTransportOptions) (*StdioClientTransport, error) {
+	if opts == nil {
+		opts = &StdioClientTransportOptions{}
+	}
+	if opts.ShutdownTimeout == 0 {
+		opts.ShutdownTimeout = 5 * time.Second
+	}
+	cmd.Stderr = opts.Stderr
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the stdin pipe: %w", err)
+	}
+	// cmd.StdoutPipe isn't used because Wait closes it, which would drop messages not read yet when the process exits.
+	stdout, w, err := os.Pipe()
+	if err != nil {
+		stdin.Close()
+		return nil, fmt.Errorf("failed to create the stdout pipe: %w", err)
+	}
+	cmd.Stdout = w
+	if err := cmd.Start(); err != nil {
+		stdin.Close()
+		stdout.Close()
+		w.Close()
+		return nil, fmt.Errorf("failed to start the server process: %w", err)
+	}
+	// The child has its own copy of the write end.
+	w.Close()
+
+	t := &StdioClientTransport{
+		cmd:             cmd,
+		stdio:           stdio{in: stdout, out: stdin},
+		shutdownTimeout: opts.ShutdownTimeout,
+		exited:          make(chan struct{}),
+	}
+	go func() {
+		t.waitErr = cmd.Wait()
+		close(t.exited)
+	}()
+	context.AfterFunc(ctx, func() { t.Close() })
+	return t, nil
+}
+
+// Dial returns the connection to the server process. Closing it closes the transport.
+func (t *StdioClientTransport) Dial(ctx context.Context) (io.ReadWriteCloser, error) {
+	return &stdioCloser{stdio: t.stdio, close: t.Close}, nil
+}
+
+// Close stops the server process. It closes the standard input of the process first so that it can exit gracefully,
+// and kills it if it doesn't exit within StdioClientTransportOptions.ShutdownTimeout.
+// It returns an error if the process exited with an error by itself.
+func (t *StdioClientTransport) Close() error {
+	t.closeOnce.Do(func() {
+		t.stdio.out.Close()
+		select {
+		case <-t.exited:
+			t.closeErr = t.waitErr
+		case <-time.After(t.shutdownTimeout):
+			t.cmd.Process.Kill()
+			<-t.exited
+		}
+		t.stdio.in.Close()
+	})
+	return t.closeErr
+}
+
+// list calls a paginated list method until the server returns no next cursor.
+// field is the name of the items in the result, e.g. "tools".
+// It fails if the server returns a cursor it has already returned, which would otherwise loop forever.
+func list[T any](ctx context.Context, c *Client, method, field string) ([]T, error) {
+	var (
+		all    []T
+		cursor string
+		seen   = map[string]bool{}
+	)
+	for {
+		res, err := c.Call(ctx, method, protocol.PaginationParams{Cursor: cursor})
+		if err != nil {
+			return nil, err
+		}
+		var page map[string]json.RawMessage
+		if err := json.Unmarshal(res, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal the result of %s: %w", method, err)
+		}
+		if b, ok := page[field]; ok {
+			var items []T
+			if err := json.Unmarshal(b, &items); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal %s: %w", field, err)
+			}
+			all = append(all, items...)
+		}
+
+		cursor = ""
+		if b, ok := page["nextCursor"]; ok {
+			if err := json.Unmarshal(b, &cursor); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal the next cursor: %w", err)
+			}
+		}
+		if cursor == "" {
+			return all, nil
+		}
+		if seen[cursor] {
+			return nil, fmt.Errorf("%s returned a repeated cursor: %s", method, cursor)
+		}
+		seen[cursor] = true
+	}
+}
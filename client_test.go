@@ -0,0 +1,309 @@
+package mcp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	mcp "github.com/ktr0731/go-mcp"
+	"github.com/ktr0731/go-mcp/protocol"
+	"golang.org/x/exp/jsonrpc2"
+)
+
+func TestInMemoryClient_CallTool(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			return &mcp.CallToolResult{
+				Content: []mcp.CallToolContent{mcp.TextContent{Text: "city not found"}},
+				IsError: req.Name == "fail",
+			}, nil
+		}),
+	}
+
+	ctx := context.Background()
+	client, err := mcp.NewInMemoryTransport(ctx, h)
+	if err != nil {
+		t.Fatalf("failed to create an in-memory transport: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if _, err := client.Initialize(ctx, protocol.InitializeRequestParams{ProtocolVersion: protocol.LatestProtocolVersion}); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+
+	if _, err := client.CallTool(ctx, "succeed", map[string]any{}); err != nil {
+		t.Errorf("CallTool returned an error: %v", err)
+	}
+
+	_, err = client.CallTool(ctx, "fail", map[string]any{})
+	var toolErr *mcp.ToolCallError
+	if !errors.As(err, &toolErr) {
+		t.Fatalf("want a *mcp.ToolCallError, got %v", err)
+	}
+	if toolErr.Message != "city not found" {
+		t.Errorf("want message %q, got %q", "city not found", toolErr.Message)
+	}
+	var res struct {
+		IsError bool `json:"isError"`
+	}
+	if err := json.Unmarshal(toolErr.Result, &res); err != nil || !res.IsError {
+		t.Errorf("want the raw result with isError, got %s", toolErr.Result)
+	}
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	h := toolsHandler(3)
+	h.PageSize = 2
+	h.Capabilities.Logging = &protocol.LoggingCapability{}
+	h.Capabilities.Completions = &protocol.CompletionsCapability{}
+	h.ToolHandler = toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+		mcp.Logger(ctx, "tool").Info("called", "name", req.Name)
+		if err := mcp.ReportProgress(ctx, 1, 2, "halfway"); err != nil {
+			return nil, err
+		}
+		return &mcp.CallToolResult{Content: []mcp.CallToolContent{mcp.TextContent{Text: "called " + req.Name}}}, nil
+	})
+	h.CompletionHandler = completionHandlerFunc(func(ctx context.Context, req *mcp.CompleteRequestParams) (*mcp.CompleteResult, error) {
+		return &mcp.CompleteResult{Values: []string{"tokyo", "toronto"}}, nil
+	})
+
+	logs := make(chan *mcp.LogMessage, 1)
+	ctx := context.Background()
+	client, err := mcp.NewInMemoryClient(ctx, h, &mcp.ClientOptions{
+		ClientInfo: protocol.Implementation{Name: "test-client", Version: "1.0.0"},
+		OnLog:      func(msg *mcp.LogMessage) { logs <- msg },
+	})
+	if err != nil {
+		t.Fatalf("failed to create a client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	if v := client.InitializeResult().ProtocolVersion; v != protocol.LatestProtocolVersion {
+		t.Errorf("want protocol version %s, got %s", protocol.LatestProtocolVersion, v)
+	}
+
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools returned an error: %v", err)
+	}
+	if len(tools) != 3 {
+		t.Errorf("want all 3 tools across pages, got %d", len(tools))
+	}
+
+	var progress []float64
+	res, err := client.CallTool(mcp.WithProgressHandler(ctx, func(p *protocol.ProgressNotificationParams) {
+		progress = append(progress, p.Progress)
+	}), "tool_0", map[string]any{"city": "tokyo"})
+	if err != nil {
+		t.Fatalf("CallTool returned an error: %v", err)
+	}
+	if want := (mcp.TextContent{Text: "called tool_0"}); len(res.Content) != 1 || res.Content[0] != want {
+		t.Errorf("want [%+v], got %+v", want, res.Content)
+	}
+	// Progress notifications are delivered before the response.
+	if len(progress) != 1 || progress[0] != 1 {
+		t.Errorf("want a progress notification of 1, got %v", progress)
+	}
+	msg := <-logs
+	if msg.Level != protocol.LevelInfo || msg.Logger != "tool" {
+		t.Errorf("want an info log of the tool logger, got %+v", msg)
+	}
+
+	completion, err := client.Complete(ctx, &mcp.CompleteRequestParams{
+		Ref:      mcp.Reference{Type: mcp.CompletionReferenceTypePrompt, Name: "weather_report"},
+		Argument: mcp.CompletionArgument{Name: "city", Value: "to"},
+	})
+	if err != nil {
+		t.Fatalf("Complete returned an error: %v", err)
+	}
+	if len(completion.Values) != 2 || completion.Values[0] != "tokyo" {
+		t.Errorf("want values [tokyo toronto], got %v", completion.Values)
+	}
+}
+
+func TestClient_TypedResults(t *testing.T) {
+	t.Parallel()
+
+	h := toolsHandler(1)
+	h.ToolHandler = toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+		return &mcp.CallToolResult{
+			Content: []mcp.CallToolContent{
+				mcp.ImageContent{Data: strings.NewReader("chart"), MimeType: "image/png"},
+				mcp.EmbeddedResource{Resource: mcp.TextResourceContent{URI: "file:///a.txt", Text: "a"}},
+				mcp.ResourceLink{URI: "file:///b.txt", Name: "b"},
+			},
+			StructuredContent: json.RawMessage(`{"ok":true}`),
+		}, nil
+	})
+	h.Capabilities.Prompts = &protocol.PromptCapability{}
+	h.Prompts = []protocol.Prompt{{Name: "greet"}}
+	h.PromptHandler = promptHandlerFunc(func(ctx context.Context, method string, req protocol.GetPromptRequestParams) (any, error) {
+		return &mcp.GetPromptResult{
+			Description: "A greeting",
+			Messages:    []mcp.PromptMessage{mcp.NewTextPromptMessage(mcp.RoleUser, "hello")},
+		}, nil
+	})
+	h.Capabilities.Resources = &protocol.ResourceCapability{}
+	h.ResourceHandler = &blobResourceHandler{data: []byte("blob")}
+
+	ctx := context.Background()
+	client, err := mcp.NewInMemoryClient(ctx, h, nil)
+	if err != nil {
+		t.Fatalf("failed to create a client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	res, err := client.CallTool(ctx, "tool_0", map[string]any{})
+	if err != nil {
+		t.Fatalf("CallTool returned an error: %v", err)
+	}
+	if len(res.Content) != 3 {
+		t.Fatalf("want 3 contents, got %+v", res.Content)
+	}
+	image, ok := res.Content[0].(mcp.ImageContent)
+	if !ok || image.MimeType != "image/png" {
+		t.Errorf("want image content, got %+v", res.Content[0])
+	} else if b, _ := io.ReadAll(image.Data); string(b) != "chart" {
+		t.Errorf("want the image data chart, got %s", b)
+	}
+	if want := (mcp.EmbeddedResource{Resource: mcp.TextResourceContent{URI: "file:///a.txt", Text: "a"}}); res.Content[1] != want {
+		t.Errorf("want %+v, got %+v", want, res.Content[1])
+	}
+	if want := (mcp.ResourceLink{URI: "file:///b.txt", Name: "b"}); res.Content[2] != want {
+		t.Errorf("want %+v, got %+v", want, res.Content[2])
+	}
+	if want := `{"ok":true}`; string(res.StructuredContent) != want {
+		t.Errorf("want structured content %s, got %s", want, res.StructuredContent)
+	}
+
+	prompt, err := client.GetPrompt(ctx, "greet", nil)
+	if err != nil {
+		t.Fatalf("GetPrompt returned an error: %v", err)
+	}
+	if prompt.Description != "A greeting" || len(prompt.Messages) != 1 || prompt.Messages[0] != mcp.NewTextPromptMessage(mcp.RoleUser, "hello") {
+		t.Errorf("want the greeting prompt, got %+v", prompt)
+	}
+
+	resource, err := client.ReadResource(ctx, "file:///blob")
+	if err != nil {
+		t.Fatalf("ReadResource returned an error: %v", err)
+	}
+	if len(resource.Contents) != 1 {
+		t.Fatalf("want 1 content, got %+v", resource.Contents)
+	}
+	blob, ok := resource.Contents[0].(mcp.BlobResourceContent)
+	if !ok || blob.URI != "file:///blob" {
+		t.Errorf("want blob content, got %+v", resource.Contents[0])
+	} else if b, _ := io.ReadAll(blob.Blob); string(b) != "blob" {
+		t.Errorf("want the blob data blob, got %s", b)
+	}
+}
+
+// TestStdioClientTransport launches the test binary itself as a server process, which runs TestStdioServerProcess.
+func TestStdioClientTransport(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestStdioServerProcess$")
+	cmd.Env = append(os.Environ(), "GO_MCP_STDIO_SERVER_PROCESS=1")
+	var stderr bytes.Buffer
+	transport, err := mcp.NewStdioClientTransport(ctx, cmd, &mcp.StdioClientTransportOptions{
+		Stderr:          &stderr,
+		ShutdownTimeout: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("failed to start the server process: %v", err)
+	}
+	t.Cleanup(func() { transport.Close() })
+
+	client, err := mcp.NewClient(ctx, transport, nil)
+	if err != nil {
+		t.Fatalf("failed to create a client: %v", err)
+	}
+	res, err := client.CallTool(ctx, "tool_0", map[string]any{})
+	if err != nil {
+		t.Fatalf("CallTool returned an error: %v", err)
+	}
+	if want := (mcp.TextContent{Text: "hello from tool_0"}); len(res.Content) != 1 || res.Content[0] != want {
+		t.Errorf("want [%+v], got %+v", want, res.Content)
+	}
+
+	client.Close()
+	if cmd.ProcessState == nil {
+		t.Error("want the server process to be stopped by Close")
+	}
+	if !strings.Contains(stderr.String(), "server started") {
+		t.Errorf("want the stderr of the server process, got %q", stderr.String())
+	}
+}
+
+// TestStdioServerProcess isn't a test by itself. It serves over stdio when launched by TestStdioClientTransport.
+func TestStdioServerProcess(t *testing.T) {
+	if os.Getenv("GO_MCP_STDIO_SERVER_PROCESS") != "1" {
+		t.Skip("run as a server process by TestStdioClientTransport")
+	}
+
+	fmt.Fprintln(os.Stderr, "server started")
+	h := toolsHandler(1)
+	h.ToolHandler = toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+		return &mcp.CallToolResult{Content: []mcp.CallToolContent{mcp.TextContent{Text: "hello from " + req.Name}}}, nil
+	})
+	ctx, listener, binder := mcp.NewStdioTransport(context.Background(), h, nil)
+	srv, err := jsonrpc2.Serve(ctx, listener, binder)
+	if err != nil {
+		t.Fatalf("failed to serve: %v", err)
+	}
+	srv.Wait()
+}
+
+func TestClient_RepeatedCursor(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	listener, err := jsonrpc2.NetPipe(ctx)
+	if err != nil {
+		t.Fatalf("failed to create a listener: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	// The server returns the same cursor forever.
+	server := jsonrpc2.ConnectionOptions{
+		Framer: jsonrpc2.RawFramer(),
+		Handler: jsonrpc2.HandlerFunc(func(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+			switch req.Method {
+			case protocol.MethodInitialize:
+				return map[string]any{"protocolVersion": protocol.LatestProtocolVersion, "capabilities": map[string]any{}, "serverInfo": map[string]any{"name": "test", "version": "1.0.0"}}, nil
+			case protocol.MethodToolsList:
+				return map[string]any{"tools": []any{}, "nextCursor": "same"}, nil
+			default:
+				return nil, nil
+			}
+		}),
+	}
+	if _, err := jsonrpc2.Serve(ctx, listener, server); err != nil {
+		t.Fatalf("failed to serve: %v", err)
+	}
+
+	client, err := mcp.NewClient(ctx, listener.Dialer(), nil)
+	if err != nil {
+		t.Fatalf("failed to create a client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	if _, err := client.ListTools(ctx); err == nil || !strings.Contains(err.Error(), "repeated cursor") {
+		t.Errorf("want a repeated cursor error, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+// Command mcp-codegen generates the Go code of an MCP server from a definition file.
+//
+// Usage:
+//
+//	mcp-codegen -def def.json -pkg weather -o mcp.gen.go
+//
+// With -dir, the code is split into multiple files in the directory instead, see codegen.GenerateFiles.
+//
+// The definition file is a JSON or YAML document read by codegen.ParseDefinition.
+// It can be used with go:generate, e.g.
+//
+//	//go:generate go run github.com/ktr0731/go-mcp/cmd/mcp-codegen -def def.yaml -pkg weather -o mcp.gen.go
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ktr0731/go-mcp/codegen"
+)
+
+func main() {
+	def := flag.String("def", "", "path to the server definition file (JSON or YAML)")
+	pkg := flag.String("pkg", "", "package name of the generated code (default \"mcpgen\")")
+	out := flag.String("o", "", "path to the output file (default stdout)")
+	dir := flag.String("dir", "", "path to the output directory to split the code into multiple files")
+	importPath := flag.String("import", "", "import path of the mcp package referred to by the generated code (default \"github.com/ktr0731/go-mcp\")")
+	flag.Parse()
+
+	if err := run(*def, *pkg, *out, *dir, *importPath); err != nil {
+		fmt.Fprintf(os.Stderr, "mcp-codegen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(defPath, pkg, out, dir, importPath string) error {
+	if defPath == "" {
+		flag.Usage()
+		return fmt.Errorf("-def is required")
+	}
+	if out != "" && dir != "" {
+		return fmt.Errorf("-o and -dir can't be used together")
+	}
+
+	f, err := os.Open(defPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	def, err := codegen.ParseDefinition(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", defPath, err)
+	}
+
+	var opts []codegen.GenerateOption
+	if importPath != "" {
+		opts = append(opts, codegen.WithImportPath(importPath))
+	}
+
+	if dir != "" {
+		if err := codegen.GenerateFiles(dir, def, pkg, opts...); err != nil {
+			return fmt.Errorf("failed to generate code: %w", err)
+		}
+		return nil
+	}
+
+	// Generate the code into a buffer first so that an existing output file isn't truncated on errors.
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, pkg, opts...); err != nil {
+		return fmt.Errorf("failed to generate code: %w", err)
+	}
+
+	if out == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return os.WriteFile(out, buf.Bytes(), 0644)
+}
@@ -0,0 +1,38 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Marshaler encodes v into JSON. json.Marshal is a Marshaler.
+type Marshaler func(v any) ([]byte, error)
+
+// Unmarshaler decodes JSON data into v. json.Unmarshal is an Unmarshaler.
+type Unmarshaler func(data []byte, v any) error
+
+var (
+	// JSONMarshal is used to encode results of requests handled by transports,
+	// and other JSON the server encodes while handling requests, e.g. redacted tool arguments.
+	// It defaults to json.Marshal and can be replaced with a faster encoder compatible with encoding/json,
+	// e.g. goccy/go-json or sonic. It must be set before serving and must not be changed while serving.
+	//
+	// Note that the JSON-RPC envelope is still encoded by encoding/json,
+	// which only validates the already-encoded result.
+	JSONMarshal Marshaler = json.Marshal
+	// JSONUnmarshal is used to decode params of requests, including _meta of them.
+	// It defaults to json.Unmarshal. Like JSONMarshal, it must be set before serving.
+	JSONUnmarshal Unmarshaler = json.Unmarshal
+)
+
+// marshalResult encodes the result of a request by JSONMarshal.
+func marshalResult(res any) (any, error) {
+	if res == nil {
+		return nil, nil
+	}
+	b, err := JSONMarshal(res)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the result: %w", err)
+	}
+	return json.RawMessage(b), nil
+}
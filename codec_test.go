@@ -0,0 +1,123 @@
+package mcp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	mcp "github.com/ktr0731/go-mcp"
+	"github.com/ktr0731/go-mcp/protocol"
+)
+
+// This test isn't parallel because it replaces the package-level codec.
+func TestJSONMarshal(t *testing.T) {
+	var called bool
+	setJSONMarshal(t, func(v any) ([]byte, error) {
+		called = true
+		return json.Marshal(v)
+	})
+
+	client := newInMemoryClient(t, toolsHandler(1))
+	if _, err := client.Call(context.Background(), protocol.MethodToolsList, struct{}{}); err != nil {
+		t.Fatalf("tools/list returned an error: %v", err)
+	}
+	if !called {
+		t.Error("the result was not encoded by JSONMarshal")
+	}
+}
+
+// This test isn't parallel because it replaces the package-level codec.
+func TestJSONUnmarshal(t *testing.T) {
+	var marshaled, unmarshaled bool
+	setJSONMarshal(t, func(v any) ([]byte, error) {
+		marshaled = true
+		return json.Marshal(v)
+	})
+	setJSONUnmarshal(t, func(data []byte, v any) error {
+		unmarshaled = true
+		return json.Unmarshal(data, v)
+	})
+
+	h := &mcp.Handler{SensitiveToolArguments: map[string][]string{"search": {"api_key"}}}
+	got := h.RedactToolArguments(protocol.CallToolRequestParams{Name: "search", Arguments: json.RawMessage(`{"api_key":"secret"}`)})
+	if want := `{"api_key":"[REDACTED]"}`; string(got) != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	if !marshaled || !unmarshaled {
+		t.Errorf("the arguments were not redacted by the codec: marshaled: %t, unmarshaled: %t", marshaled, unmarshaled)
+	}
+}
+
+func BenchmarkJSONMarshal(b *testing.B) {
+	codecs := map[string]mcp.Marshaler{
+		"encoding/json": json.Marshal,
+		// An alternative codec that skips HTML escaping.
+		"no HTML escape": func(v any) ([]byte, error) {
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false)
+			if err := enc.Encode(v); err != nil {
+				return nil, err
+			}
+			return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+		},
+	}
+	for name, marshal := range codecs {
+		b.Run(name, func(b *testing.B) {
+			setJSONMarshal(b, marshal)
+			client := newInMemoryClient(b, toolsHandler(100))
+			ctx := context.Background()
+
+			b.ResetTimer()
+			for range b.N {
+				if _, err := client.Call(ctx, protocol.MethodToolsList, struct{}{}); err != nil {
+					b.Fatalf("tools/list returned an error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func setJSONMarshal(tb testing.TB, marshal mcp.Marshaler) {
+	tb.Helper()
+
+	orig := mcp.JSONMarshal
+	mcp.JSONMarshal = marshal
+	tb.Cleanup(func() { mcp.JSONMarshal = orig })
+}
+
+func setJSONUnmarshal(tb testing.TB, unmarshal mcp.Unmarshaler) {
+	tb.Helper()
+
+	orig := mcp.JSONUnmarshal
+	mcp.JSONUnmarshal = unmarshal
+	tb.Cleanup(func() { mcp.JSONUnmarshal = orig })
+}
+
+func toolsHandler(n int) *mcp.Handler {
+	tools := make([]protocol.Tool, n)
+	for i := range tools {
+		tools[i] = protocol.Tool{
+			Name:        fmt.Sprintf("tool_%d", i),
+			Description: "A tool for <benchmark> & testing",
+			InputSchema: map[string]any{"type": "object", "properties": map[string]any{"city": map[string]any{"type": "string"}}},
+		}
+	}
+	return &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		Tools:        tools,
+	}
+}
+
+func newInMemoryClient(tb testing.TB, h *mcp.Handler) *mcp.Client {
+	tb.Helper()
+
+	client, err := mcp.NewInMemoryClient(context.Background(), h, nil)
+	if err != nil {
+		tb.Fatalf("failed to create an in-memory client: %v", err)
+	}
+	tb.Cleanup(func() { client.Close() })
+	return client
+}
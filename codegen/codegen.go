@@ -1,16 +1,26 @@
 package codegen
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"go/token"
 	"io"
+	"maps"
 	"os"
+	pathpkg "path"
+	"path/filepath"
 	"reflect"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/invopop/jsonschema"
+	mcp "github.com/ktr0731/go-mcp"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 	"golang.org/x/tools/imports"
@@ -34,7 +44,9 @@ type ServerCapabilities struct {
 
 // PromptCapability represents server capability for prompts.
 type PromptCapability struct {
-	// ListChanged is always false. See README.md for more details.
+	// ListChanged indicates whether this server supports notifications for changes to the prompt list.
+	// The notification is sent by mcp.Handler.NotifyPromptsListChanged.
+	ListChanged bool `json:"listChanged,omitempty"`
 }
 
 // ResourceCapability represents server capability for resources.
@@ -47,7 +59,9 @@ type ResourceCapability struct {
 
 // ToolCapability represents server capability for tools.
 type ToolCapability struct {
-	// ListChanged is always false. See README.md for more details.
+	// ListChanged indicates whether this server supports notifications for changes to the tool list.
+	// The list can be changed by mcp.Handler.SetTools.
+	ListChanged bool `json:"listChanged,omitempty"`
 }
 
 // LoggingCapability represents server capability for logging.
@@ -60,6 +74,22 @@ type CompletionsCapability struct{}
 type Implementation struct {
 	Name    string `json:"name"`
 	Version string `json:"version"`
+	// Title is a human-readable name of the implementation, which clients display instead of Name if set.
+	Title string `json:"title,omitempty"`
+	// WebsiteURL is the URL of the website of the implementation.
+	WebsiteURL string `json:"websiteUrl,omitempty"`
+	// Icons is a list of icons of the implementation, which clients may display in their user interfaces.
+	Icons []Icon `json:"icons,omitempty"`
+}
+
+// Icon represents an icon of an MCP implementation.
+type Icon struct {
+	// Src is a URL of the icon. It may also be a data URI containing a base64-encoded image.
+	Src string `json:"src"`
+	// MimeType is an optional MIME type of the icon, e.g. "image/png".
+	MimeType string `json:"mimeType,omitempty"`
+	// Sizes is an optional list of sizes of the icon, e.g. "48x48" or "any" for scalable formats.
+	Sizes []string `json:"sizes,omitempty"`
 }
 
 // Prompt represents a prompt or prompt template that the server offers.
@@ -80,6 +110,25 @@ type PromptArgument struct {
 	Description string `json:"description,omitempty"`
 	// Required indicates whether this argument must be provided.
 	Required bool `json:"required,omitempty"`
+	// Pattern is an optional regular expression which values of the argument must match.
+	// It is listed to clients so that they can validate input before sending it.
+	Pattern string `json:"pattern,omitempty"`
+	// Enum is an optional list of the possible values of the argument.
+	// It is listed to clients so that they can validate input before sending it.
+	Enum []string `json:"enum,omitempty"`
+	// Type is the type of the field of the argument in the generated request type,
+	// one of "string", "integer", "number" and "boolean". If this is empty, "string" is used.
+	// Clients always send arguments as strings, so values of other types are parsed from strings.
+	Type string `json:"type,omitempty"`
+}
+
+// promptArgumentGoTypes maps PromptArgument.Type to Go types.
+var promptArgumentGoTypes = map[string]string{
+	"":        "string",
+	"string":  "string",
+	"integer": "int",
+	"number":  "float64",
+	"boolean": "bool",
 }
 
 // Tool represents a definition for a tool the client can call.
@@ -92,8 +141,63 @@ type Tool struct {
 	Description string `json:"description,omitempty"`
 	// InputSchema is a Go struct that represents the input schema of the tool.
 	// The struct fields can specify JSON tags supported by https://github.com/invopop/jsonschema.
+	// Examples of a field can also be declared as a JSON array by the jsonschema_examples tag,
+	// e.g. `jsonschema_examples:"[\"Tokyo, Japan\"]"`, which supports values containing commas and objects.
 	// See README.md or examples directory for more details.
+	//
+	// InputSchema can also be an object JSON schema as a json.RawMessage (e.g. loaded by ParseDefinition) or a map[string]any.
+	// In that case, the fields of the generated request type are derived from the properties of the schema.
+	//
+	// Optional inputs, which aren't required by the schema (e.g. fields with `json:",omitempty"`),
+	// are generated as pointer fields with omitempty so that handlers can distinguish absent values from zero values.
 	InputSchema any `json:"inputSchema"`
+	// OutputSchema is an optional Go struct that represents the structured content of the tool result.
+	// It supports the same tags as InputSchema, and can also be a JSON schema as a json.RawMessage or a map[string]any.
+	// If this is set, results of the tool must have StructuredContent conforming to the schema.
+	OutputSchema any `json:"outputSchema,omitempty"`
+	// Deprecated is a message that describes why the tool is deprecated and what to use instead.
+	// If this is set, the tool is listed with the deprecation message in its annotations,
+	// and a warning log notification is sent to clients when it is called.
+	Deprecated string `json:"deprecated,omitempty"`
+	// Annotations are optional hints about the behavior of the tool.
+	// Clients may use them to decide whether to ask users before calling the tool, e.g. auto-approving read-only tools.
+	Annotations *ToolAnnotations `json:"annotations,omitempty"`
+	// Timeout is the optional execution timeout of the tool, e.g. for tools calling external APIs.
+	// The context passed to the handler is canceled after Timeout, and the call results in a CallToolResult with IsError set.
+	// The generated NewHandler sets it by mcp.Handler.SetToolTimeout with mcp.ToolTimeoutAsResult,
+	// so it replaces the RequestTimeout of the transport for calls to the tool.
+	// In definition files, it is written as a string parsed by time.ParseDuration, e.g. "30s".
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+// ToolAnnotations represents hints about the behavior of a tool. They are hints, so clients must not rely on them for security.
+type ToolAnnotations struct {
+	// Title is a human-readable title for the tool.
+	Title string `json:"title,omitempty"`
+	// ReadOnlyHint indicates if the tool does not modify its environment.
+	ReadOnlyHint bool `json:"readOnlyHint,omitempty"`
+	// DestructiveHint indicates if the tool may perform destructive updates to its environment.
+	// This is meaningful only when ReadOnlyHint is false.
+	DestructiveHint bool `json:"destructiveHint,omitempty"`
+	// IdempotentHint indicates if calling the tool repeatedly with the same arguments has no additional effect on its environment.
+	// This is meaningful only when ReadOnlyHint is false.
+	IdempotentHint bool `json:"idempotentHint,omitempty"`
+	// OpenWorldHint indicates if the tool may interact with an "open world" of external entities, e.g. a web search tool.
+	OpenWorldHint bool `json:"openWorldHint,omitempty"`
+}
+
+// Resource represents a concrete resource offered by the server.
+type Resource struct {
+	// URI is the URI of this resource.
+	URI string `json:"uri"`
+	// Name is a human-readable name for this resource.
+	// This can be used by clients to populate UI elements.
+	Name string `json:"name"`
+	// Description is a description of what this resource represents.
+	// This can be used by clients to improve the LLM's understanding of available resources.
+	Description string `json:"description,omitempty"`
+	// MimeType is the MIME type of this resource, if known.
+	MimeType string `json:"mimeType,omitempty"`
 }
 
 // ResourceTemplate represents a template description for resources available on the server.
@@ -110,66 +214,295 @@ type ResourceTemplate struct {
 	// MimeType is the MIME type for all resources that match this template. This should only be included
 	// if all resources matching this template have the same type.
 	MimeType string `json:"mimeType,omitempty"`
+	// CompletableVariables is the names of the template variables whose values can be completed by completion/complete.
+	// Completion requests for other variables are answered with no values without calling the completion handler.
+	// If this is empty, all variables are passed to the completion handler.
+	CompletableVariables []string `json:"completableVariables,omitempty"`
 }
 
 // ServerDefinition represents the definition of an MCP server.
 type ServerDefinition struct {
 	// Capabilities defines the capabilities that this server supports.
-	Capabilities ServerCapabilities
+	Capabilities ServerCapabilities `json:"capabilities"`
 	// Implementation contains information about this server implementation.
-	Implementation Implementation
+	Implementation Implementation `json:"implementation"`
+	// Instructions describe how to use the server and its features.
+	// Clients can use this to improve the LLM's understanding of the server.
+	Instructions string `json:"instructions,omitempty"`
 
 	// Prompts is the list of prompts offered by this server.
-	Prompts []Prompt
+	Prompts []Prompt `json:"prompts,omitempty"`
+	// Resources is the list of concrete resources offered by this server.
+	// They are returned by the generated ResourceListHandler.
+	Resources []Resource `json:"resources,omitempty"`
 	// ResourceTemplates is the list of resource templates offered by this server.
-	ResourceTemplates []ResourceTemplate
+	ResourceTemplates []ResourceTemplate `json:"resourceTemplates,omitempty"`
 	// Tools is the list of tools offered by this server.
-	Tools []Tool
+	Tools []Tool `json:"tools,omitempty"`
+}
+
+// defaultImportPath is the import path of the mcp package referred to by the generated code by default.
+const defaultImportPath = "github.com/ktr0731/go-mcp"
+
+// GenerateOption configures the code generation by Generate, GenerateFiles and GenerateMocks.
+type GenerateOption func(*generator)
+
+// WithImportPath sets the import path of the mcp package referred to by the generated code.
+// The protocol package is imported from the protocol directory under it.
+// It is useful for forks or vendored copies of this module. The default is github.com/ktr0731/go-mcp.
+func WithImportPath(path string) GenerateOption {
+	return func(g *generator) { g.importPath = path }
 }
 
 // Generate generates the server code from the server definition.
 // See README.md or examples directory for more details.
-func Generate(w io.Writer, def *ServerDefinition, pkgName string) error {
+func Generate(w io.Writer, def *ServerDefinition, pkgName string, opts ...GenerateOption) error {
 	if w == nil {
 		w = os.Stdout
 	}
 	if pkgName == "" {
 		pkgName = "mcpgen"
 	}
+	if err := validate(def); err != nil {
+		return err
+	}
+
+	return newGenerator(def, pkgName, opts).generate(w)
+}
+
+// GenerateFiles generates the server code from the server definition like Generate,
+// but splits it into prompts.gen.go, resources.gen.go, tools.gen.go and handler.gen.go in dir.
+// It is useful for servers with many tools, whose generated code is too large to review as a single file.
+// dir is created if it doesn't exist, and no files are written if the generation fails.
+func GenerateFiles(dir string, def *ServerDefinition, pkgName string, opts ...GenerateOption) error {
+	if pkgName == "" {
+		pkgName = "mcpgen"
+	}
+	if err := validate(def); err != nil {
+		return err
+	}
+
+	files := make([][]byte, len(generatedFiles))
+	for i, f := range generatedFiles {
+		g := newGenerator(def, pkgName, opts)
+		if err := g.generateHeader(); err != nil {
+			return err
+		}
+		f.generate(g)
+
+		var buf bytes.Buffer
+		if err := g.flush(&buf); err != nil {
+			return fmt.Errorf("failed to generate %s: %w", f.name, err)
+		}
+		files[i] = buf.Bytes()
+	}
 
-	return (&generator{
-		def: def,
-		pkg: pkgName,
-	}).generate(w)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for i, f := range generatedFiles {
+		if err := os.WriteFile(filepath.Join(dir, f.name), files[i], 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generatedFiles are the files written by GenerateFiles with the sections of the generated code in each of them.
+var generatedFiles = []struct {
+	name     string
+	generate func(g *generator)
+}{
+	{"prompts.gen.go", func(g *generator) {
+		g.generatePromptHandlers()
+		g.generatePromptList()
+	}},
+	{"resources.gen.go", func(g *generator) {
+		g.generateResourceList()
+		g.generateResourceTemplateList()
+		g.generateResourceTemplateHandlers()
+	}},
+	{"tools.gen.go", func(g *generator) {
+		g.generateToolHandlers()
+		g.generateToolList()
+	}},
+	{"handler.gen.go", (*generator).generateNewHandler},
+}
+
+// GenerateMocks generates mocks of the ServerPromptHandler and ServerToolHandler interfaces generated by Generate
+// with the same server definition, and writes it to w. pkgName must be the same package as the one passed to Generate.
+//
+// The mocks are named MockServerPromptHandler and MockServerToolHandler.
+// They have a function field per method, e.g. HandleToolConvertTemperatureFunc, which is called by the method.
+// If the field is nil, the method returns a not-implemented error.
+func GenerateMocks(w io.Writer, def *ServerDefinition, pkgName string, opts ...GenerateOption) error {
+	if w == nil {
+		w = os.Stdout
+	}
+	if pkgName == "" {
+		pkgName = "mcpgen"
+	}
+	if err := validate(def); err != nil {
+		return err
+	}
+
+	return newGenerator(def, pkgName, opts).generateMocks(w)
+}
+
+// validate validates the server definition before generating any code.
+func validate(def *ServerDefinition) error {
+	var promptNames, toolNames, templateURIs []string
+	for _, prompt := range def.Prompts {
+		promptNames = append(promptNames, prompt.Name)
+	}
+	for _, tool := range def.Tools {
+		toolNames = append(toolNames, tool.Name)
+	}
+	var errs []error
+	var resourceURIs []string
+	for _, resource := range def.Resources {
+		if resource.URI == "" {
+			errs = append(errs, fmt.Errorf("URI of resource %q must not be empty", resource.Name))
+			continue
+		}
+		if slices.Contains(resourceURIs, resource.URI) {
+			errs = append(errs, fmt.Errorf("duplicate resource URI %q", resource.URI))
+		}
+		resourceURIs = append(resourceURIs, resource.URI)
+	}
+	for _, tmpl := range def.ResourceTemplates {
+		if slices.Contains(templateURIs, tmpl.URITemplate) {
+			errs = append(errs, fmt.Errorf("duplicate resource template URI %q", tmpl.URITemplate))
+		}
+		templateURIs = append(templateURIs, tmpl.URITemplate)
+	}
+	errs = append(errs, validateNames("prompt", promptNames), validateNames("tool", toolNames))
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	var names []string
+	for _, tmpl := range def.ResourceTemplates {
+		t, err := mcp.ParseURITemplate(tmpl.URITemplate)
+		if err != nil {
+			return fmt.Errorf("invalid resource template %q: %w", tmpl.Name, err)
+		}
+		for _, v := range tmpl.CompletableVariables {
+			if !slices.Contains(t.Variables(), v) {
+				return fmt.Errorf("invalid resource template %q: completable variable %q is not in the template", tmpl.Name, v)
+			}
+		}
+		name := resourceTemplateName(tmpl)
+		if name == "" {
+			return fmt.Errorf("invalid resource template %q: name must contain letters or digits", tmpl.Name)
+		}
+		if slices.Contains(names, name) {
+			return fmt.Errorf("invalid resource template %q: name conflicts with another resource template", tmpl.Name)
+		}
+		names = append(names, name)
+		for _, v := range t.Variables() {
+			if name := resourceTemplateVariableName(v); !token.IsIdentifier(name) {
+				return fmt.Errorf("invalid resource template %q: variable %q can't be a Go identifier", tmpl.Name, v)
+			}
+		}
+	}
+	for _, prompt := range def.Prompts {
+		for _, arg := range prompt.Arguments {
+			if _, err := regexp.Compile(arg.Pattern); err != nil {
+				return fmt.Errorf("invalid pattern of argument %q of prompt %q: %w", arg.Name, prompt.Name, err)
+			}
+			if _, ok := promptArgumentGoTypes[arg.Type]; !ok {
+				return fmt.Errorf("invalid type of argument %q of prompt %q: %q", arg.Name, prompt.Name, arg.Type)
+			}
+		}
+	}
+	for _, tool := range def.Tools {
+		schema, err := reflectSchema(tool.InputSchema)
+		if err != nil {
+			return fmt.Errorf("invalid input schema of tool %q: %w", tool.Name, err)
+		}
+		if isRawSchema(tool.InputSchema) && schema.Type != "object" {
+			return fmt.Errorf("invalid input schema of tool %q: type must be object", tool.Name)
+		}
+		if tool.OutputSchema != nil {
+			if _, err := reflectSchema(tool.OutputSchema); err != nil {
+				return fmt.Errorf("invalid output schema of tool %q: %w", tool.Name, err)
+			}
+		}
+		if tool.Timeout < 0 {
+			return fmt.Errorf("invalid timeout of tool %q: must not be negative", tool.Name)
+		}
+	}
+	return nil
+}
+
+// validateNames validates the names of prompts or tools, which are named kind in errors.
+// Names must be non-empty and unique, and must be converted by pascalCase into unique Go identifiers,
+// because they are used in names of generated types and methods. All invalid names are reported at once.
+func validateNames(kind string, names []string) error {
+	var errs []error
+	seen := map[string]bool{}
+	goNames := map[string]string{}
+	for _, name := range names {
+		if name == "" {
+			errs = append(errs, fmt.Errorf("%s name must not be empty", kind))
+			continue
+		}
+		if seen[name] {
+			errs = append(errs, fmt.Errorf("duplicate %s name %q", kind, name))
+			continue
+		}
+		seen[name] = true
+
+		goName := pascalCase(name)
+		if !token.IsIdentifier(fieldName(name)) {
+			errs = append(errs, fmt.Errorf("%s name %q can't be converted to a Go identifier: %q", kind, name, goName))
+			continue
+		}
+		if other, ok := goNames[goName]; ok {
+			errs = append(errs, fmt.Errorf("%s names %q and %q conflict as Go identifier %s", kind, other, name, goName))
+			continue
+		}
+		goNames[goName] = name
+	}
+	return errors.Join(errs...)
 }
 
 type generator struct {
 	buf strings.Builder
 	def *ServerDefinition
 
-	pkg string
+	pkg        string
+	importPath string
 }
 
-func (g *generator) generate(w io.Writer) error {
-	g.println("// Code generated by mcp-codegen. DO NOT EDIT.")
-	g.println("package " + g.pkg)
+func newGenerator(def *ServerDefinition, pkg string, opts []GenerateOption) *generator {
+	g := &generator{
+		def:        def,
+		pkg:        pkg,
+		importPath: defaultImportPath,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
 
-	g.println("import (")
-	g.println(`	"context"`)
-	g.println(`	"encoding/json"`)
-	g.println(`	"fmt"`)
-	g.println(`	"slices"`)
-	g.println(`	"strconv"`)
-	g.println(`	mcp "github.com/ktr0731/go-mcp"`)
-	g.println(`	"github.com/ktr0731/go-mcp/protocol"`)
-	g.println(")")
+func (g *generator) generate(w io.Writer) error {
+	if err := g.generateHeader(); err != nil {
+		return err
+	}
 
 	// Generate prompt handlers and input types
 	g.generatePromptHandlers()
 
 	// Resource list
+	g.generateResourceList()
 	g.generateResourceTemplateList()
 
+	// Resource template handlers and the dispatcher
+	g.generateResourceTemplateHandlers()
+
 	// Tool handlers and input types
 	g.generateToolHandlers()
 
@@ -182,6 +515,71 @@ func (g *generator) generate(w io.Writer) error {
 	// NewHandler
 	g.generateNewHandler()
 
+	return g.flush(w)
+}
+
+// generateHeader generates the header comment, the package clause and the imports.
+// Unused imports are removed by flush.
+func (g *generator) generateHeader() error {
+	g.println("// Code generated by mcp-codegen. DO NOT EDIT.")
+	g.println("package " + g.pkg)
+
+	imports := map[string]string{
+		"context":                  "context",
+		"encoding/json":            "json",
+		"fmt":                      "fmt",
+		"slices":                   "slices",
+		"strconv":                  "strconv",
+		"time":                     "time",
+		g.importPath:               "mcp",
+		g.importPath + "/protocol": "protocol",
+	}
+	fieldImports := g.toolFieldImports()
+	for _, path := range slices.Sorted(maps.Keys(fieldImports)) {
+		if _, ok := imports[path]; ok {
+			continue
+		}
+		name := fieldImports[path]
+		for other, otherName := range imports {
+			if otherName == name {
+				return fmt.Errorf("package %q of tool input fields conflicts with package %q imported by the generated code", path, other)
+			}
+		}
+		imports[path] = name
+	}
+	paths := slices.Sorted(maps.Keys(imports))
+
+	g.println("import (")
+	for _, path := range paths {
+		if name := imports[path]; name != pathpkg.Base(path) {
+			g.printf("	%s %q\n", name, path)
+		} else {
+			g.printf("	%q\n", path)
+		}
+	}
+	g.println(")")
+	return nil
+}
+
+// toolFieldImports returns the packages of the types of the tool input fields, e.g. "time" for time.Time.
+// They are imported explicitly because imports.Process can't always find them.
+// It maps import paths to package names.
+func (g *generator) toolFieldImports() map[string]string {
+	imports := map[string]string{}
+	for _, tool := range g.def.Tools {
+		if isRawSchema(tool.InputSchema) {
+			continue
+		}
+		// The input type itself isn't referred to by the generated code, even if it is a named type.
+		for _, f := range schemaFields(reflect.TypeOf(tool.InputSchema)) {
+			typeImports(imports, f.Type)
+		}
+	}
+	return imports
+}
+
+// flush formats the generated code and writes it to w.
+func (g *generator) flush(w io.Writer) error {
 	out := []byte(g.buf.String())
 
 	b, err := imports.Process("", out, &imports.Options{
@@ -201,6 +599,90 @@ func (g *generator) generate(w io.Writer) error {
 	return nil
 }
 
+func (g *generator) generateMocks(w io.Writer) error {
+	g.println("// Code generated by mcp-codegen. DO NOT EDIT.")
+	g.println("package " + g.pkg)
+
+	g.println("import (")
+	g.println(`	"context"`)
+	g.println(`	"errors"`)
+	g.printf("	mcp %q\n", g.importPath)
+	g.println(")")
+
+	g.println("// MockServerPromptHandler is a mock of ServerPromptHandler.")
+	g.println("// Each method calls the corresponding function field, or returns a not-implemented error if it is nil.")
+	g.println("type MockServerPromptHandler struct {")
+	for _, prompt := range g.def.Prompts {
+		promptName := pascalCase(prompt.Name)
+		g.println("	HandlePrompt" + promptName + "Func func(ctx context.Context, req *Prompt" + promptName + "Request) (*mcp.GetPromptResult, error)")
+	}
+	g.println("}")
+	g.println("")
+	g.println("var _ ServerPromptHandler = (*MockServerPromptHandler)(nil)")
+	g.println("")
+	for _, prompt := range g.def.Prompts {
+		method := "HandlePrompt" + pascalCase(prompt.Name)
+		g.println("func (m *MockServerPromptHandler) " + method + "(ctx context.Context, req *Prompt" + pascalCase(prompt.Name) + "Request) (*mcp.GetPromptResult, error) {")
+		g.println("	if m." + method + "Func == nil {")
+		g.printf("		return nil, errors.New(%q)\n", method+" is not implemented")
+		g.println("	}")
+		g.println("	return m." + method + "Func(ctx, req)")
+		g.println("}")
+		g.println("")
+	}
+
+	if len(g.def.Tools) != 0 {
+		g.println("// MockServerToolHandler is a mock of ServerToolHandler.")
+		g.println("// Each method calls the corresponding function field, or returns a not-implemented error if it is nil.")
+		g.println("type MockServerToolHandler struct {")
+		for _, tool := range g.def.Tools {
+			toolName := pascalCase(tool.Name)
+			g.println("	HandleTool" + toolName + "Func func(ctx context.Context, req *Tool" + toolName + "Request) (*mcp.CallToolResult, error)")
+		}
+		g.println("}")
+		g.println("")
+		g.println("var _ ServerToolHandler = (*MockServerToolHandler)(nil)")
+		g.println("")
+		for _, tool := range g.def.Tools {
+			method := "HandleTool" + pascalCase(tool.Name)
+			g.println("func (m *MockServerToolHandler) " + method + "(ctx context.Context, req *Tool" + pascalCase(tool.Name) + "Request) (*mcp.CallToolResult, error) {")
+			g.println("	if m." + method + "Func == nil {")
+			g.printf("		return nil, errors.New(%q)\n", method+" is not implemented")
+			g.println("	}")
+			g.println("	return m." + method + "Func(ctx, req)")
+			g.println("}")
+			g.println("")
+		}
+	}
+
+	if len(g.def.ResourceTemplates) != 0 {
+		g.println("// MockServerResourceTemplateHandler is a mock of ServerResourceTemplateHandler.")
+		g.println("// Each method calls the corresponding function field, or returns a not-implemented error if it is nil.")
+		g.println("type MockServerResourceTemplateHandler struct {")
+		for _, tmpl := range g.def.ResourceTemplates {
+			name := resourceTemplateName(tmpl)
+			g.println("	HandleResource" + name + "Func func(ctx context.Context, req *Resource" + name + "Request) (*mcp.ReadResourceResult, error)")
+		}
+		g.println("}")
+		g.println("")
+		g.println("var _ ServerResourceTemplateHandler = (*MockServerResourceTemplateHandler)(nil)")
+		g.println("")
+		for _, tmpl := range g.def.ResourceTemplates {
+			name := resourceTemplateName(tmpl)
+			method := "HandleResource" + name
+			g.println("func (m *MockServerResourceTemplateHandler) " + method + "(ctx context.Context, req *Resource" + name + "Request) (*mcp.ReadResourceResult, error) {")
+			g.println("	if m." + method + "Func == nil {")
+			g.printf("		return nil, errors.New(%q)\n", method+" is not implemented")
+			g.println("	}")
+			g.println("	return m." + method + "Func(ctx, req)")
+			g.println("}")
+			g.println("")
+		}
+	}
+
+	return g.flush(w)
+}
+
 // generatePromptHandlers generates prompt handlers and input types.
 func (g *generator) generatePromptHandlers() {
 	g.println("// ServerPromptHandler is the interface for prompt handlers.")
@@ -217,8 +699,15 @@ func (g *generator) generatePromptHandlers() {
 		g.println("// Prompt" + promptName + "Request contains input parameters for the " + prompt.Name + " prompt.")
 		g.println("type Prompt" + promptName + "Request struct {")
 		for _, arg := range prompt.Arguments {
-			argName := pascalCase(arg.Name)
-			g.println("	" + argName + " string `json:\"" + arg.Name + "\"`")
+			argName := fieldName(arg.Name)
+			goType := promptArgumentGoTypes[arg.Type]
+			g.printFieldDoc(arg.Description)
+			if goType == "string" {
+				g.println("	" + argName + " string `json:\"" + arg.Name + "\"`")
+				continue
+			}
+			// Arguments are sent as strings, so decode the value from a string by the string option.
+			g.println("	" + argName + " " + goType + " `json:\"" + arg.Name + ",string\"`")
 		}
 		g.println("}")
 		g.println("")
@@ -227,8 +716,10 @@ func (g *generator) generatePromptHandlers() {
 
 // getEnumFields extracts enum fields from a tool's input schema
 func (g *generator) getEnumFields(tool Tool) map[string][]any {
-	reflector := jsonschema.Reflector{}
-	schema := reflector.Reflect(tool.InputSchema)
+	schema, err := reflectSchema(tool.InputSchema)
+	if err != nil {
+		panic(err)
+	}
 	schemaJSON, err := schema.MarshalJSON()
 	if err != nil {
 		panic(err)
@@ -343,41 +834,76 @@ func (g *generator) generateToolHandlers() {
 				})
 			}
 
+			strVals := make([]string, 0, len(sortedEnumValues))
 			for _, val := range sortedEnumValues {
-				strVal := fmt.Sprintf("%v", val)
-				constName := pascalCase(strVal)
+				strVals = append(strVals, fmt.Sprintf("%v", val))
+			}
+			constNames := enumConstNames(enumTypeName, strVals)
+			for i, val := range sortedEnumValues {
+				strVal := strVals[i]
+				constName := constNames[i]
 
 				if enumType == "int" {
 					// For integer enums, don't quote the value
 					intVal := int(val.(float64))
-					g.println("	" + enumTypeName + constName + " " + enumTypeName + " = " + strconv.Itoa(intVal))
+					g.println("	" + constName + " " + enumTypeName + " = " + strconv.Itoa(intVal))
 				} else {
 					// For string enums, quote the value
-					g.println("	" + enumTypeName + constName + " " + enumTypeName + " = \"" + strVal + "\"")
+					g.printf("	%s %s = %q\n", constName, enumTypeName, strVal)
 				}
 			}
 			g.println(")")
 			g.println("")
+
+			// Generate methods
+			g.println("// IsValid reports whether x is one of the possible values of " + enumTypeName + ".")
+			g.println("func (x " + enumTypeName + ") IsValid() bool {")
+			g.println("	switch x {")
+			g.println("	case " + strings.Join(constNames, ", ") + ":")
+			g.println("		return true")
+			g.println("	}")
+			g.println("	return false")
+			g.println("}")
+			g.println("")
+			g.println("// String returns the value of x as a string.")
+			g.println("func (x " + enumTypeName + ") String() string {")
+			if enumType == "int" {
+				g.println("	return strconv.Itoa(int(x))")
+			} else {
+				g.println("	return string(x)")
+			}
+			g.println("}")
+			g.println("")
 		}
 
 		g.println("// Tool" + toolName + "Request contains input parameters for the " + tool.Name + " tool.")
 		g.println("type Tool" + toolName + "Request struct {")
 
-		rt := reflect.TypeOf(tool.InputSchema)
-		// Generate fields from JSONSchema
-		for i := 0; i < rt.NumField(); i++ {
-			field := rt.Field(i)
+		if isRawSchema(tool.InputSchema) {
+			g.generateFieldsFromSchema(tool, enumFields)
+			g.println("}")
+			g.println("")
+			continue
+		}
+
+		schema, err := reflectSchema(tool.InputSchema)
+		if err != nil {
+			panic(err)
+		}
+		// Generate fields from JSONSchema. Embedded structs are flattened as in the schema.
+		for _, field := range schemaFields(reflect.TypeOf(tool.InputSchema)) {
 			fieldName := field.Name
 			fieldType := field.Type.String()
-			jsonTag := field.Tag.Get("json")
+			jsonName := field.name
 
 			// If this field has enum values, use the custom type
-			if _, hasEnum := enumFields[jsonTag]; hasEnum {
-				enumTypeName := toolName + pascalCase(jsonTag) + "Type"
-				g.println("	" + fieldName + " " + enumTypeName + " `json:\"" + jsonTag + "\"`")
-			} else {
-				g.println("	" + fieldName + " " + fieldType + " `json:\"" + jsonTag + "\"`")
+			if _, hasEnum := enumFields[jsonName]; hasEnum {
+				fieldType = toolName + pascalCase(jsonName) + "Type"
 			}
+			if prop, ok := schema.Properties.Get(jsonName); ok && prop != nil {
+				g.printFieldDoc(prop.Description)
+			}
+			g.println("	" + fieldName + " " + fieldTypeAndTag(schema, jsonName, fieldType))
 		}
 
 		g.println("}")
@@ -385,22 +911,80 @@ func (g *generator) generateToolHandlers() {
 	}
 }
 
+// generateFieldsFromSchema generates fields of the request type of tool from the properties of its JSON schema.
+func (g *generator) generateFieldsFromSchema(tool Tool, enumFields map[string][]any) {
+	schema, err := reflectSchema(tool.InputSchema)
+	if err != nil {
+		panic(err)
+	}
+	if schema.Properties == nil {
+		return
+	}
+	// Sort properties by name to ensure consistent generation order regardless of the order in the document.
+	names := make([]string, 0, schema.Properties.Len())
+	for pair := schema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+		names = append(names, pair.Key)
+	}
+	slices.Sort(names)
+
+	toolName := pascalCase(tool.Name)
+	for _, name := range names {
+		prop, _ := schema.Properties.Get(name)
+		fieldType := goType(prop)
+		if _, hasEnum := enumFields[name]; hasEnum {
+			fieldType = toolName + pascalCase(name) + "Type"
+		}
+		g.printFieldDoc(prop.Description)
+		g.println("	" + fieldName(name) + " " + fieldTypeAndTag(schema, name, fieldType))
+	}
+}
+
+// fieldTypeAndTag returns the type and the JSON tag of the field of the request type for the property name of schema.
+// Optional properties, which aren't listed in the required properties, are represented by pointers with omitempty,
+// so handlers can distinguish absent values from zero values.
+// Slices, maps and interfaces are kept as they are because they can already be nil.
+func fieldTypeAndTag(schema *jsonschema.Schema, name, typ string) string {
+	if slices.Contains(schema.Required, name) {
+		return typ + " `json:\"" + name + "\"`"
+	}
+	if !strings.HasPrefix(typ, "*") && !strings.HasPrefix(typ, "[]") && !strings.HasPrefix(typ, "map[") && typ != "any" && typ != "interface {}" {
+		typ = "*" + typ
+	}
+	return typ + " `json:\"" + name + ",omitempty\"`"
+}
+
 // generatePromptList generates the list of available prompts.
 func (g *generator) generatePromptList() {
+	if len(g.def.Prompts) != 0 {
+		g.println("// Names of the prompts.")
+		g.println("const (")
+		for _, prompt := range g.def.Prompts {
+			g.printf("	Prompt%sName = %q\n", pascalCase(prompt.Name), prompt.Name)
+		}
+		g.println(")")
+		g.println("")
+	}
+
 	g.println("// PromptList contains all available prompts.")
 	g.println("var PromptList = []protocol.Prompt{")
 	for _, prompt := range g.def.Prompts {
 		g.println("	{")
-		g.println("		Name: \"" + prompt.Name + "\",")
-		g.println("		Description: \"" + prompt.Description + "\",")
+		g.printf("		Name: Prompt%sName,\n", pascalCase(prompt.Name))
+		g.printf("		Description: %q,\n", prompt.Description)
 		g.println("		Arguments: []protocol.PromptArgument{")
 		for _, arg := range prompt.Arguments {
 			g.println("			{")
-			g.println("				Name: \"" + arg.Name + "\",")
-			g.println("				Description: \"" + arg.Description + "\",")
+			g.printf("				Name: %q,\n", arg.Name)
+			g.printf("				Description: %q,\n", arg.Description)
 			if arg.Required {
 				g.println("				Required: true,")
 			}
+			if arg.Pattern != "" {
+				g.printf("				Pattern: %q,\n", arg.Pattern)
+			}
+			if len(arg.Enum) != 0 {
+				g.printf("				Enum: %#v,\n", arg.Enum)
+			}
 			g.println("			},")
 		}
 		g.println("		},")
@@ -416,16 +1000,37 @@ func (g *generator) generateToolList() {
 		return
 	}
 
-	reflector := jsonschema.Reflector{}
+	g.println("// Names of the tools.")
+	g.println("const (")
+	for _, tool := range g.def.Tools {
+		g.printf("	Tool%sName = %q\n", pascalCase(tool.Name), tool.Name)
+	}
+	g.println(")")
+	g.println("")
+
 	g.println("// JSON Schema type definitions generated from inputSchema")
 	g.println("var (")
 	for _, tool := range g.def.Tools {
-		schema := reflector.Reflect(tool.InputSchema)
+		schema, err := reflectSchema(tool.InputSchema)
+		if err != nil {
+			panic(err)
+		}
 		b, err := schema.MarshalJSON()
 		if err != nil {
 			panic(err)
 		}
-		g.println("	Tool" + pascalCase(tool.Name) + "InputSchema = json.RawMessage(`" + string(b) + "`)")
+		g.println("	Tool" + pascalCase(tool.Name) + "InputSchema = json.RawMessage(" + rawStringLiteral(string(b)) + ")")
+		if tool.OutputSchema != nil {
+			schema, err := reflectSchema(tool.OutputSchema)
+			if err != nil {
+				panic(err)
+			}
+			b, err := schema.MarshalJSON()
+			if err != nil {
+				panic(err)
+			}
+			g.println("	Tool" + pascalCase(tool.Name) + "OutputSchema = json.RawMessage(" + rawStringLiteral(string(b)) + ")")
+		}
 	}
 	g.println(")")
 
@@ -433,15 +1038,47 @@ func (g *generator) generateToolList() {
 	g.println("var ToolList = []protocol.Tool{")
 	for _, tool := range g.def.Tools {
 		g.println("	{")
-		g.printf("		Name: %q,\n", tool.Name)
+		g.printf("		Name: Tool%sName,\n", pascalCase(tool.Name))
 		g.printf("		Description: %q,\n", tool.Description)
 		g.printf("		InputSchema: Tool%sInputSchema,\n", pascalCase(tool.Name))
+		if tool.OutputSchema != nil {
+			g.printf("		OutputSchema: Tool%sOutputSchema,\n", pascalCase(tool.Name))
+		}
+		if fields := toolAnnotationFields(tool); len(fields) != 0 {
+			g.println("		Annotations: &protocol.ToolAnnotations{" + strings.Join(fields, ", ") + "},")
+		}
 		g.println("	},")
 	}
 	g.println("}")
 	g.println("")
 }
 
+// toolAnnotationFields returns the fields of the protocol.ToolAnnotations literal of tool, which merges Annotations and Deprecated.
+func toolAnnotationFields(tool Tool) []string {
+	var fields []string
+	if a := tool.Annotations; a != nil {
+		if a.Title != "" {
+			fields = append(fields, "Title: "+strconv.Quote(a.Title))
+		}
+		if a.ReadOnlyHint {
+			fields = append(fields, "ReadOnlyHint: true")
+		}
+		if a.DestructiveHint {
+			fields = append(fields, "DestructiveHint: true")
+		}
+		if a.IdempotentHint {
+			fields = append(fields, "IdempotentHint: true")
+		}
+		if a.OpenWorldHint {
+			fields = append(fields, "OpenWorldHint: true")
+		}
+	}
+	if tool.Deprecated != "" {
+		fields = append(fields, "Deprecated: "+strconv.Quote(tool.Deprecated))
+	}
+	return fields
+}
+
 // generateResourceTemplateList generates the list of available ResourceTemplates.
 func (g *generator) generateResourceTemplateList() {
 	if len(g.def.ResourceTemplates) == 0 {
@@ -452,11 +1089,18 @@ func (g *generator) generateResourceTemplateList() {
 	g.println("var ResourceTemplateList = []mcp.ResourceTemplate{")
 	for _, resourceTemplate := range g.def.ResourceTemplates {
 		g.println("	{")
-		g.println("		URITemplate: \"" + resourceTemplate.URITemplate + "\",")
-		g.println("		Name: \"" + resourceTemplate.Name + "\",")
-		g.println("		Description: \"" + resourceTemplate.Description + "\",")
+		g.printf("		URITemplate: %q,\n", resourceTemplate.URITemplate)
+		g.printf("		Name: %q,\n", resourceTemplate.Name)
+		g.printf("		Description: %q,\n", resourceTemplate.Description)
 		if resourceTemplate.MimeType != "" {
-			g.println("		MimeType: \"" + resourceTemplate.MimeType + "\",")
+			g.printf("		MimeType: %q,\n", resourceTemplate.MimeType)
+		}
+		if len(resourceTemplate.CompletableVariables) > 0 {
+			quoted := make([]string, len(resourceTemplate.CompletableVariables))
+			for i, v := range resourceTemplate.CompletableVariables {
+				quoted[i] = strconv.Quote(v)
+			}
+			g.println("		CompletableVariables: []string{" + strings.Join(quoted, ", ") + "},")
 		}
 		g.println("	},")
 	}
@@ -464,9 +1108,133 @@ func (g *generator) generateResourceTemplateList() {
 	g.println("")
 }
 
+// generateResourceList generates the list of concrete resources and the default handler of resources/list requests returning them.
+func (g *generator) generateResourceList() {
+	if len(g.def.Resources) == 0 {
+		return
+	}
+
+	g.println("// ResourceList contains all available Resources.")
+	g.println("var ResourceList = []mcp.Resource{")
+	for _, resource := range g.def.Resources {
+		g.println("	{")
+		g.printf("		URI: %q,\n", resource.URI)
+		g.printf("		Name: %q,\n", resource.Name)
+		g.printf("		Description: %q,\n", resource.Description)
+		if resource.MimeType != "" {
+			g.printf("		MimeType: %q,\n", resource.MimeType)
+		}
+		g.println("	},")
+	}
+	g.println("}")
+	g.println("")
+
+	g.println("// ResourceListHandler handles resources/list requests by returning ResourceList.")
+	g.println("// Embed it in an mcp.ServerResourceHandler to offer the resources, or define HandleResourcesList to override it.")
+	g.println("type ResourceListHandler struct{}")
+	g.println("")
+	g.println("// HandleResourcesList returns ResourceList.")
+	g.println("func (ResourceListHandler) HandleResourcesList(ctx context.Context) (*mcp.ListResourcesResult, error) {")
+	g.println("	return &mcp.ListResourcesResult{Resources: slices.Clone(ResourceList)}, nil")
+	g.println("}")
+	g.println("")
+}
+
+// generateResourceTemplateHandlers generates resource template handlers, their input types and the dispatcher of them.
+func (g *generator) generateResourceTemplateHandlers() {
+	if len(g.def.ResourceTemplates) == 0 {
+		return
+	}
+
+	g.println("// ServerResourceTemplateHandler is the interface for resource template handlers.")
+	g.println("// Use ReadResourceByTemplate to dispatch resources/read requests to it.")
+	g.println("type ServerResourceTemplateHandler interface {")
+	for _, tmpl := range g.def.ResourceTemplates {
+		name := resourceTemplateName(tmpl)
+		g.println("	HandleResource" + name + "(ctx context.Context, req *Resource" + name + "Request) (*mcp.ReadResourceResult, error)")
+	}
+	g.println("}")
+	g.println("")
+
+	for _, tmpl := range g.def.ResourceTemplates {
+		name := resourceTemplateName(tmpl)
+		g.println("// Resource" + name + "Request contains the variables of the " + tmpl.URITemplate + " resource template.")
+		g.println("type Resource" + name + "Request struct {")
+		g.println("	// URI is the URI of the resource to read.")
+		g.println("	URI string")
+		for _, v := range resourceTemplateVariables(tmpl) {
+			g.println("	" + resourceTemplateVariableName(v) + " string")
+		}
+		g.println("}")
+		g.println("")
+	}
+
+	g.println("var (")
+	for _, tmpl := range g.def.ResourceTemplates {
+		g.printf("	uriTemplate%s = mcp.MustParseURITemplate(%q)\n", resourceTemplateName(tmpl), tmpl.URITemplate)
+	}
+	g.println(")")
+	g.println("")
+
+	g.println("// ReadResourceByTemplate reads the resource of req by the handler method of the resource template matching its URI.")
+	g.println("// Templates are matched in the order of ResourceTemplateList. If no template matches, it returns an error.")
+	g.println("func ReadResourceByTemplate(ctx context.Context, h ServerResourceTemplateHandler, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {")
+	for i, tmpl := range g.def.ResourceTemplates {
+		name := resourceTemplateName(tmpl)
+		vars := "_"
+		if len(resourceTemplateVariables(tmpl)) != 0 {
+			vars = "vars"
+		}
+		g.printf("	if %s, ok := uriTemplate%s.Match(req.URI); ok {\n", vars, name)
+		g.println("		return h.HandleResource" + name + "(ctx, &Resource" + name + "Request{")
+		g.println("			URI: req.URI,")
+		for _, v := range resourceTemplateVariables(tmpl) {
+			g.printf("			%s: vars[%q],\n", resourceTemplateVariableName(v), v)
+		}
+		g.println("		})")
+		g.println("	}")
+		if i == len(g.def.ResourceTemplates)-1 {
+			g.println("	return nil, fmt.Errorf(\"resource not found: %s\", req.URI)")
+		}
+	}
+	g.println("}")
+	g.println("")
+}
+
+// resourceTemplateName returns the Go name of a resource template, e.g. CityWeatherForecast for "City Weather Forecast".
+// Characters other than letters and digits separate words.
+func resourceTemplateName(tmpl ResourceTemplate) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, tmpl.Name)
+	return pascalCase(name)
+}
+
+// resourceTemplateVariables returns the distinct variable names of a resource template in order of appearance.
+// tmpl must be validated.
+func resourceTemplateVariables(tmpl ResourceTemplate) []string {
+	t, _ := mcp.ParseURITemplate(tmpl.URITemplate)
+	var vars []string
+	for _, v := range t.Variables() {
+		if !slices.Contains(vars, v) {
+			vars = append(vars, v)
+		}
+	}
+	return vars
+}
+
+// resourceTemplateVariableName returns the Go field name of a resource template variable, e.g. CityName for "city.name".
+func resourceTemplateVariableName(v string) string {
+	return fieldName(strings.ReplaceAll(v, ".", "_"))
+}
+
 // generateNewHandler generates the NewHandler function.
 func (g *generator) generateNewHandler() {
 	g.println("// NewHandler creates a new MCP handler.")
+	g.println("// opts are applied after the generated configuration, so they can override it, e.g. by mcp.WithInstructions.")
 
 	var handlerParams []string
 	// Generate handler parameters
@@ -482,12 +1250,17 @@ func (g *generator) generateNewHandler() {
 	if g.def.Capabilities.Completions != nil {
 		handlerParams = append(handlerParams, "completionHandler mcp.ServerCompletionHandler")
 	}
+	handlerParams = append(handlerParams, "opts ...mcp.HandlerOption")
 
 	g.println("func NewHandler(" + strings.Join(handlerParams, ", ") + ") *mcp.Handler {")
 	g.println("	h := &mcp.Handler{}")
 	g.println("	h.Capabilities = protocol.ServerCapabilities{")
 	if g.def.Capabilities.Prompts != nil {
-		g.println("		Prompts: &protocol.PromptCapability{},")
+		if g.def.Capabilities.Prompts.ListChanged {
+			g.println("		Prompts: &protocol.PromptCapability{ListChanged: true},")
+		} else {
+			g.println("		Prompts: &protocol.PromptCapability{},")
+		}
 	}
 	if g.def.Capabilities.Resources != nil {
 		g.println("		Resources: &protocol.ResourceCapability{")
@@ -496,7 +1269,11 @@ func (g *generator) generateNewHandler() {
 		g.println("		},")
 	}
 	if g.def.Capabilities.Tools != nil {
-		g.println("		Tools: &protocol.ToolCapability{},")
+		if g.def.Capabilities.Tools.ListChanged {
+			g.println("		Tools: &protocol.ToolCapability{ListChanged: true},")
+		} else {
+			g.println("		Tools: &protocol.ToolCapability{},")
+		}
 	}
 	if g.def.Capabilities.Completions != nil {
 		g.println("		Completions: &protocol.CompletionsCapability{},")
@@ -506,9 +1283,32 @@ func (g *generator) generateNewHandler() {
 	}
 	g.println("	}")
 	g.println("	h.Implementation = protocol.Implementation{")
-	g.println("		Name: \"" + g.def.Implementation.Name + "\",")
-	g.println("		Version: \"" + g.def.Implementation.Version + "\",")
+	g.printf("		Name: %q,\n", g.def.Implementation.Name)
+	g.printf("		Version: %q,\n", g.def.Implementation.Version)
+	if g.def.Implementation.Title != "" {
+		g.println("		Title: " + strconv.Quote(g.def.Implementation.Title) + ",")
+	}
+	if g.def.Implementation.WebsiteURL != "" {
+		g.println("		WebsiteURL: " + strconv.Quote(g.def.Implementation.WebsiteURL) + ",")
+	}
+	if len(g.def.Implementation.Icons) != 0 {
+		g.println("		Icons: []protocol.Icon{")
+		for _, icon := range g.def.Implementation.Icons {
+			g.printf("			{Src: %q", icon.Src)
+			if icon.MimeType != "" {
+				g.printf(", MimeType: %q", icon.MimeType)
+			}
+			if len(icon.Sizes) != 0 {
+				g.printf(", Sizes: %#v", icon.Sizes)
+			}
+			g.println("},")
+		}
+		g.println("		},")
+	}
 	g.println("	}")
+	if g.def.Instructions != "" {
+		g.println("	h.Instructions = " + strconv.Quote(g.def.Instructions))
+	}
 
 	// Set prompt handler
 	if g.def.Capabilities.Prompts != nil {
@@ -519,7 +1319,18 @@ func (g *generator) generateNewHandler() {
 		g.println("			switch req.Name {")
 		for _, prompt := range g.def.Prompts {
 			promptName := pascalCase(prompt.Name)
-			g.println("			case \"" + prompt.Name + "\":")
+			g.println("			case Prompt" + promptName + "Name:")
+			var required []string
+			for _, arg := range prompt.Arguments {
+				if arg.Required {
+					required = append(required, strconv.Quote(arg.Name))
+				}
+			}
+			if len(required) != 0 {
+				g.println("				if err := protocol.ValidateRequiredPromptArguments(req.Arguments, " + strings.Join(required, ", ") + "); err != nil {")
+				g.println("					return nil, err")
+				g.println("				}")
+			}
 			g.println("				var in Prompt" + promptName + "Request")
 			g.println("				if err := json.Unmarshal(req.Arguments, &in); err != nil {")
 			g.println("					return nil, err")
@@ -547,6 +1358,7 @@ func (g *generator) generateNewHandler() {
 	// Set tool handler
 	if g.def.Capabilities.Tools != nil {
 		g.println("	h.Tools = ToolList")
+		g.generateSensitiveToolArguments()
 		g.println("	h.ToolHandler = protocol.ServerHandlerFunc[protocol.CallToolRequestParams](func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {")
 		g.println("		idx := slices.IndexFunc(ToolList, func(t protocol.Tool) bool {")
 		g.println("			return t.Name == req.Name")
@@ -559,10 +1371,10 @@ func (g *generator) generateNewHandler() {
 		g.println("			switch req.Name {")
 		for _, tool := range g.def.Tools {
 			toolName := pascalCase(tool.Name)
-			g.println("			case \"" + tool.Name + "\":")
+			g.println("			case Tool" + toolName + "Name:")
 			g.println("				var in Tool" + toolName + "Request")
 			g.println("				if err := json.Unmarshal(req.Arguments, &in); err != nil {")
-			g.println("					return nil, err")
+			g.println("					return nil, fmt.Errorf(\"%w: %w\", protocol.ErrInvalidToolArguments, err)")
 			g.println("				}")
 
 			// Keep the schema validation for all fields
@@ -579,6 +1391,11 @@ func (g *generator) generateNewHandler() {
 		g.println("			return nil, fmt.Errorf(\"method %s not found\", method)")
 		g.println("		}")
 		g.println("	})")
+		for _, tool := range g.def.Tools {
+			if tool.Timeout > 0 {
+				g.println("	h.SetToolTimeout(Tool" + pascalCase(tool.Name) + "Name, " + durationLiteral(tool.Timeout) + ", mcp.ToolTimeoutAsResult())")
+			}
+		}
 	}
 
 	// Set completion handler
@@ -586,15 +1403,74 @@ func (g *generator) generateNewHandler() {
 		g.println("	h.CompletionHandler = completionHandler")
 	}
 
+	g.println("	for _, opt := range opts {")
+	g.println("		opt(h)")
+	g.println("	}")
 	g.println("	return h")
 	g.println("}")
 }
 
+// generateSensitiveToolArguments generates the names of sensitive arguments of tools, if any.
+func (g *generator) generateSensitiveToolArguments() {
+	var lines []string
+	for _, tool := range g.def.Tools {
+		names := sensitiveArguments(tool.InputSchema)
+		if len(names) == 0 {
+			continue
+		}
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			quoted[i] = strconv.Quote(name)
+		}
+		lines = append(lines, "		Tool"+pascalCase(tool.Name)+"Name: {"+strings.Join(quoted, ", ")+"},")
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	g.println("	h.SensitiveToolArguments = map[string][]string{")
+	for _, line := range lines {
+		g.println(line)
+	}
+	g.println("	}")
+}
+
+// durationLiteral returns a Go expression of d, e.g. 30 * time.Second.
+func durationLiteral(d time.Duration) string {
+	for _, unit := range []struct {
+		d    time.Duration
+		name string
+	}{
+		{time.Hour, "time.Hour"},
+		{time.Minute, "time.Minute"},
+		{time.Second, "time.Second"},
+		{time.Millisecond, "time.Millisecond"},
+		{time.Microsecond, "time.Microsecond"},
+	} {
+		if d%unit.d == 0 {
+			return strconv.FormatInt(int64(d/unit.d), 10) + " * " + unit.name
+		}
+	}
+	return "time.Duration(" + strconv.FormatInt(int64(d), 10) + ")"
+}
+
+// rawStringLiteral returns a Go raw string literal of s for readability.
+// If s can't be represented as a raw string literal, it returns an interpreted string literal instead.
+func rawStringLiteral(s string) string {
+	if !strconv.CanBackquote(s) {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
+
 // pascalCase converts prompt.Name to PascalCase
-// e.g. "prompt_name" -> "PromptName"
+// e.g. "prompt_name" -> "PromptName", "get-weather" -> "GetWeather", "123abc" -> "123Abc"
+// The result may start with a digit, so it must be used after a prefix such as "HandleTool". See fieldName.
 func pascalCase(name string) string {
-	name = strings.NewReplacer(";", "_", " ", "").Replace(name)
-	words := strings.Split(name, "_")
+	// Runes which can't be in identifiers (e.g. "-", "." and "/") separate words like "_".
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
 	title := cases.Title(language.Und)
 	for i, word := range words {
 		words[i] = title.String(word)
@@ -602,6 +1478,73 @@ func pascalCase(name string) string {
 	return strings.Join(words, "")
 }
 
+// fieldName converts name into an exported Go identifier used by itself, e.g. a struct field name.
+// It is pascalCase prefixed with "X" if it starts with a digit, e.g. "3d" -> "X3D".
+func fieldName(name string) string {
+	s := pascalCase(name)
+	if s != "" && unicode.IsDigit([]rune(s)[0]) {
+		s = "X" + s
+	}
+	return s
+}
+
+// enumConstNames returns the constant names of the sorted enum values prefixed with typeName.
+// Values which map to the same name (e.g. "read-only" and "read_only") are disambiguated by numeric suffixes
+// in the order of values, so the first one keeps the name and the others become e.g. "ReadOnly2".
+// An empty value is named "Empty".
+func enumConstNames(typeName string, values []string) []string {
+	names := make([]string, len(values))
+	count := make(map[string]int, len(values))
+	for i, v := range values {
+		name := pascalCase(v)
+		if name == "" {
+			name = "Empty"
+		}
+		names[i] = typeName + name
+		count[names[i]]++
+	}
+
+	used := make(map[string]bool, len(values))
+	for name, n := range count {
+		if n == 1 {
+			used[name] = true
+		}
+	}
+	for i, name := range names {
+		if count[name] == 1 {
+			continue
+		}
+		if !used[name] {
+			used[name] = true
+			continue
+		}
+		// Separate the suffix by "_" if the name ends with a digit to keep the number readable, e.g. "1_2".
+		sep := ""
+		if r := []rune(name); unicode.IsDigit(r[len(r)-1]) {
+			sep = "_"
+		}
+		for n := 2; ; n++ {
+			candidate := name + sep + strconv.Itoa(n)
+			if !used[candidate] && count[candidate] == 0 {
+				used[candidate] = true
+				names[i] = candidate
+				break
+			}
+		}
+	}
+	return names
+}
+
+// printFieldDoc prints description as the doc comment of a struct field. It prints nothing if description is empty.
+func (g *generator) printFieldDoc(description string) {
+	if description == "" {
+		return
+	}
+	for _, line := range strings.Split(description, "\n") {
+		g.println(strings.TrimRight("	// "+line, " "))
+	}
+}
+
 func (g *generator) println(s string) error {
 	_, err := fmt.Fprintln(&g.buf, s)
 	return err
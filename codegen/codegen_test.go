@@ -2,19 +2,105 @@ package codegen_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"flag"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ktr0731/go-mcp/codegen"
+	"github.com/ktr0731/go-mcp/protocol"
+	"gopkg.in/yaml.v3"
 )
 
 var update = flag.Bool("update", false, "update golden files")
 
 func TestGenerate(t *testing.T) {
 	t.Parallel()
-	def := &codegen.ServerDefinition{
+	def := weatherServerDefinition()
+
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, "weather"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+
+	assertGolden(t, "weather_server.go.golden", buf.Bytes())
+}
+
+func TestGenerateFiles(t *testing.T) {
+	t.Parallel()
+	def := weatherServerDefinition()
+
+	dir := filepath.Join(t.TempDir(), "weather")
+	if err := codegen.GenerateFiles(dir, def, "weather"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+
+	wants := map[string]string{
+		"prompts.gen.go":   "var PromptList = ",
+		"resources.gen.go": "var ResourceTemplateList = ",
+		"tools.gen.go":     "var ToolList = ",
+		"handler.gen.go":   "func NewHandler(",
+	}
+	var files [][]byte
+	for name, want := range wants {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("failed to read the generated file: %v", err)
+		}
+		if !bytes.Contains(b, []byte(want)) {
+			t.Errorf("want %s to contain %s, got:\n%s", name, want, b)
+		}
+		files = append(files, b)
+	}
+	typeCheck(t, "weather", files...)
+}
+
+func TestGenerate_ImportPath(t *testing.T) {
+	t.Parallel()
+	def := weatherServerDefinition()
+
+	var code, mocks bytes.Buffer
+	if err := codegen.Generate(&code, def, "weather", codegen.WithImportPath("example.com/fork/go-mcp")); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	if err := codegen.GenerateMocks(&mocks, def, "weather", codegen.WithImportPath("example.com/fork/go-mcp")); err != nil {
+		t.Fatalf("failed to generate mocks: %v", err)
+	}
+
+	for _, b := range [][]byte{code.Bytes(), mocks.Bytes()} {
+		if !bytes.Contains(b, []byte(`mcp "example.com/fork/go-mcp"`)) {
+			t.Errorf("want the generated code to import the mcp package from the fork, got:\n%s", b)
+		}
+		if bytes.Contains(b, []byte("github.com/ktr0731/go-mcp")) {
+			t.Errorf("want the generated code not to import the original module, got:\n%s", b)
+		}
+	}
+	if !bytes.Contains(code.Bytes(), []byte(`"example.com/fork/go-mcp/protocol"`)) {
+		t.Errorf("want the generated code to import the protocol package from the fork, got:\n%s", code.String())
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	t.Parallel()
+	def := weatherServerDefinition()
+
+	out := codegen.Describe(def)
+
+	assertGolden(t, "weather_server.md.golden", []byte(out))
+}
+
+func weatherServerDefinition() *codegen.ServerDefinition {
+	return &codegen.ServerDefinition{
 		Capabilities: codegen.ServerCapabilities{
 			Prompts: &codegen.PromptCapability{},
 			Resources: &codegen.ResourceCapability{
@@ -28,14 +114,16 @@ func TestGenerate(t *testing.T) {
 		Implementation: codegen.Implementation{
 			Name:    "Weather Forecast MCP Server",
 			Version: "1.0.0",
+			Title:   "Weather Forecast",
 		},
+		Instructions: "Use the weather://forecast resources for \"current\" forecasts and the tools for unit conversions.\nCity names must be in English.",
 		Prompts: []codegen.Prompt{
 			{
 				Name:        "weather_report",
 				Description: "Generate a weather report based on weather data",
 				Arguments: []codegen.PromptArgument{
 					{Name: "city", Description: "City name", Required: true},
-					{Name: "language", Description: "Report language (e.g. 'en', 'ja')", Required: false},
+					{Name: "language", Description: "Report language (e.g. 'en', 'ja')", Required: false, Pattern: "^[a-z]{2}$"},
 				},
 			},
 			{
@@ -43,7 +131,7 @@ func TestGenerate(t *testing.T) {
 				Description: "Generate a weather alert message",
 				Arguments: []codegen.PromptArgument{
 					{Name: "alert_type", Description: "Type of alert (e.g. 'rain', 'snow', 'heat')", Required: true},
-					{Name: "severity", Description: "Alert severity (1-5)", Required: true},
+					{Name: "severity", Description: "Alert severity (1-5)", Required: true, Enum: []string{"1", "2", "3", "4", "5"}, Type: "integer"},
 				},
 			},
 		},
@@ -56,6 +144,12 @@ func TestGenerate(t *testing.T) {
 					FromUnit    string  `json:"from_unit" jsonschema:"description=Source temperature unit,enum=celsius,enum=fahrenheit"`
 					ToUnit      string  `json:"to_unit" jsonschema:"description=Target temperature unit,enum=celsius,enum=fahrenheit"`
 				}{},
+				OutputSchema: struct {
+					Temperature float64 `json:"temperature" jsonschema:"description=Converted temperature value"`
+					Unit        string  `json:"unit" jsonschema:"description=Unit of the converted temperature,enum=celsius,enum=fahrenheit"`
+				}{},
+				// The conversion doesn't have side effects, so clients can call it without asking users.
+				Annotations: &codegen.ToolAnnotations{Title: "Convert Temperature", ReadOnlyHint: true},
 			},
 			{
 				Name:        "calculate_humidity_index",
@@ -66,6 +160,11 @@ func TestGenerate(t *testing.T) {
 				}{},
 			},
 		},
+		Resources: []codegen.Resource{
+			{URI: "weather://forecast/tokyo", Name: "Tokyo Weather Forecast", Description: "Current weather data for Tokyo", MimeType: "application/json"},
+			{URI: "weather://forecast/new_york", Name: "New York Weather Forecast", Description: "Current weather data for New York", MimeType: "application/json"},
+			{URI: "weather://forecast/london", Name: "London Weather Forecast", Description: "Current weather data for London", MimeType: "application/json"},
+		},
 		ResourceTemplates: []codegen.ResourceTemplate{
 			{
 				URITemplate: "weather://forecast/{city}",
@@ -78,23 +177,24 @@ func TestGenerate(t *testing.T) {
 				Name:        "Historical Weather Data",
 				Description: "Historical weather data for a specific city and date",
 				MimeType:    "application/json",
+				// Only city is completed by the completion handler.
+				CompletableVariables: []string{"city"},
 			},
 		},
 	}
+}
 
-	var buf bytes.Buffer
-	if err := codegen.Generate(&buf, def, "weather"); err != nil {
-		t.Fatalf("failed to generate code: %v", err)
-	}
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
 
 	goldenDir := filepath.Join("testdata", "golden")
 	if err := os.MkdirAll(goldenDir, 0755); err != nil {
 		t.Fatalf("failed to create golden directory: %v", err)
 	}
 
-	goldenFile := filepath.Join(goldenDir, "weather_server.go.golden")
+	goldenFile := filepath.Join(goldenDir, name)
 	if *update {
-		if err := os.WriteFile(goldenFile, buf.Bytes(), 0644); err != nil {
+		if err := os.WriteFile(goldenFile, got, 0644); err != nil {
 			t.Fatalf("failed to update golden file: %v", err)
 		}
 	}
@@ -104,7 +204,871 @@ func TestGenerate(t *testing.T) {
 		t.Fatalf("failed to read golden file: %v", err)
 	}
 
-	if string(expected) != buf.String() {
-		t.Errorf("generated code does not match golden file")
+	if string(expected) != string(got) {
+		t.Errorf("generated code does not match golden file %s", name)
+	}
+}
+
+func TestGenerateMocks(t *testing.T) {
+	t.Parallel()
+	def := weatherServerDefinition()
+
+	var code, mocks bytes.Buffer
+	if err := codegen.Generate(&code, def, "weather"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	if err := codegen.GenerateMocks(&mocks, def, "weather"); err != nil {
+		t.Fatalf("failed to generate mocks: %v", err)
+	}
+
+	assertGolden(t, "weather_server_mock.go.golden", mocks.Bytes())
+	typeCheck(t, "weather", code.Bytes(), mocks.Bytes())
+}
+
+// typeCheck type-checks files as a package named pkg.
+func typeCheck(t *testing.T, pkg string, files ...[]byte) {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	var parsed []*ast.File
+	for i, src := range files {
+		f, err := parser.ParseFile(fset, fmt.Sprintf("%d.go", i), src, parser.AllErrors)
+		if err != nil {
+			t.Fatalf("failed to parse the generated code: %v\n%s", err, src)
+		}
+		parsed = append(parsed, f)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check(pkg, fset, parsed, nil); err != nil {
+		t.Fatalf("the generated code doesn't compile: %v", err)
+	}
+}
+
+func TestGenerate_InvalidResourceTemplate(t *testing.T) {
+	t.Parallel()
+	def := weatherServerDefinition()
+	def.ResourceTemplates[0].URITemplate = "weather://forecast/{city"
+
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, "weather"); err == nil {
+		t.Fatal("want an error, got nil")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("want no output, got %d bytes", buf.Len())
+	}
+}
+
+func TestGenerate_ConflictingResourceTemplateNames(t *testing.T) {
+	t.Parallel()
+	def := weatherServerDefinition()
+	def.ResourceTemplates[1].Name = "City weather forecast!"
+
+	if err := codegen.Generate(io.Discard, def, "weather"); err == nil {
+		t.Error("want an error for names which conflict with each other, got nil")
+	}
+}
+
+func TestGenerate_DuplicateResourceURIs(t *testing.T) {
+	t.Parallel()
+	def := weatherServerDefinition()
+	def.Resources[1].URI = def.Resources[0].URI
+
+	if err := codegen.Generate(io.Discard, def, "weather"); err == nil {
+		t.Error("want an error for duplicate resource URIs, got nil")
+	}
+}
+
+func TestGenerate_InvalidNames(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		modify func(def *codegen.ServerDefinition)
+		want   []string
+	}{
+		"duplicate tool and prompt names": {
+			modify: func(def *codegen.ServerDefinition) {
+				def.Tools = append(def.Tools, def.Tools[0])
+				def.Prompts = append(def.Prompts, def.Prompts[0])
+			},
+			want: []string{`duplicate tool name "convert_temperature"`, `duplicate prompt name "weather_report"`},
+		},
+		"conflicting Go identifiers": {
+			modify: func(def *codegen.ServerDefinition) {
+				tool := def.Tools[0]
+				tool.Name = "Convert_Temperature"
+				def.Tools = append(def.Tools, tool)
+			},
+			want: []string{`tool names "convert_temperature" and "Convert_Temperature" conflict`},
+		},
+		"empty name": {
+			modify: func(def *codegen.ServerDefinition) { def.Prompts[0].Name = "" },
+			want:   []string{"prompt name must not be empty"},
+		},
+		"duplicate resource template URI": {
+			modify: func(def *codegen.ServerDefinition) {
+				tmpl := def.ResourceTemplates[0]
+				tmpl.Name = "Another forecast"
+				def.ResourceTemplates = append(def.ResourceTemplates, tmpl)
+			},
+			want: []string{"duplicate resource template URI"},
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			def := weatherServerDefinition()
+			c.modify(def)
+
+			var buf bytes.Buffer
+			err := codegen.Generate(&buf, def, "weather")
+			if err == nil {
+				t.Fatal("want an error, got nil")
+			}
+			for _, want := range c.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("want the error to contain %q, got %v", want, err)
+				}
+			}
+			if buf.Len() != 0 {
+				t.Errorf("want no output, got:\n%s", buf.String())
+			}
+		})
+	}
+}
+
+func TestGenerate_NonIdentifierNames(t *testing.T) {
+	t.Parallel()
+	def := weatherServerDefinition()
+	tool := def.Tools[1]
+	def.Tools = nil
+	for _, name := range []string{"get-weather", "v2.tool", "123abc", "weather/daily"} {
+		tool.Name = name
+		def.Tools = append(def.Tools, tool)
+	}
+	def.Prompts[0].Name = "weather-report"
+	def.Prompts[0].Arguments = append(def.Prompts[0].Arguments, codegen.PromptArgument{Name: "3d_model"})
+
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, "weather"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	typeCheck(t, "weather", buf.Bytes())
+	for _, want := range []string{
+		"HandleToolGetWeather(", "HandleToolV2Tool(", "HandleTool123Abc(", "HandleToolWeatherDaily(", "HandlePromptWeatherReport(",
+		"X3DModel ", "case ToolGetWeatherName:", "case PromptWeatherReportName:",
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("want the generated code to contain %s, got:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestGenerate_ResourceTemplateDispatch(t *testing.T) {
+	t.Parallel()
+	def := weatherServerDefinition()
+
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, "weather"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	typeCheck(t, "weather", buf.Bytes())
+	want := "\tif vars, ok := uriTemplateHistoricalWeatherData.Match(req.URI); ok {\n" +
+		"\t\treturn h.HandleResourceHistoricalWeatherData(ctx, &ResourceHistoricalWeatherDataRequest{\n" +
+		"\t\t\tURI:  req.URI,\n" +
+		"\t\t\tCity: vars[\"city\"],\n" +
+		"\t\t\tDate: vars[\"date\"],\n"
+	if !bytes.Contains(buf.Bytes(), []byte(want)) {
+		t.Errorf("want the generated code to contain %s, got:\n%s", want, buf.String())
+	}
+}
+
+func TestGenerate_UnknownCompletableVariable(t *testing.T) {
+	t.Parallel()
+	def := weatherServerDefinition()
+	def.ResourceTemplates[1].CompletableVariables = []string{"country"}
+
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, "weather"); err == nil {
+		t.Fatal("want an error, got nil")
+	}
+}
+
+func TestGenerate_ToolArgumentExamples(t *testing.T) {
+	t.Parallel()
+
+	type location struct {
+		City string `json:"city" jsonschema_examples:"[\"Tokyo, Japan\"]"`
+	}
+	def := &codegen.ServerDefinition{
+		Capabilities:   codegen.ServerCapabilities{Tools: &codegen.ToolCapability{}},
+		Implementation: codegen.Implementation{Name: "Weather Server"},
+		Tools: []codegen.Tool{
+			{
+				Name: "get_forecast",
+				InputSchema: struct {
+					Date     string     `json:"date" jsonschema:"example=2025-01-01"`
+					Days     int        `json:"days" jsonschema_examples:"[3, 7]"`
+					Location location   `json:"location"`
+					Stops    []location `json:"stops"`
+				}{},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, "weather"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	for _, want := range []string{
+		`"date":{"type":"string","examples":["2025-01-01"]}`,
+		`"days":{"type":"integer","examples":[3,7]}`,
+		`"city":{"type":"string","examples":["Tokyo, Japan"]}`,
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("want the generated schema to contain %s, got:\n%s", want, buf.String())
+		}
+	}
+
+	t.Run("invalid examples", func(t *testing.T) {
+		t.Parallel()
+
+		def := &codegen.ServerDefinition{
+			Capabilities:   codegen.ServerCapabilities{Tools: &codegen.ToolCapability{}},
+			Implementation: codegen.Implementation{Name: "Weather Server"},
+			Tools: []codegen.Tool{
+				{
+					Name: "get_forecast",
+					InputSchema: struct {
+						Days int `json:"days" jsonschema_examples:"3"`
+					}{},
+				},
+			},
+		}
+		if err := codegen.Generate(&bytes.Buffer{}, def, "weather"); err == nil {
+			t.Fatal("want an error, got nil")
+		}
+	})
+}
+
+func TestGenerate_SensitiveToolArguments(t *testing.T) {
+	t.Parallel()
+
+	def := &codegen.ServerDefinition{
+		Capabilities:   codegen.ServerCapabilities{Tools: &codegen.ToolCapability{}},
+		Implementation: codegen.Implementation{Name: "Search Server"},
+		Tools: []codegen.Tool{
+			{
+				Name: "search",
+				InputSchema: struct {
+					APIKey string `json:"api_key" mcp:"sensitive"`
+					Query  string `json:"query"`
+				}{},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, "search"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	want := `ToolSearchName: {"api_key"},`
+	if !bytes.Contains(buf.Bytes(), []byte(want)) {
+		t.Errorf("want the generated code to contain %s, got:\n%s", want, buf.String())
+	}
+}
+
+func TestGenerate_DeprecatedTool(t *testing.T) {
+	t.Parallel()
+	def := &codegen.ServerDefinition{
+		Capabilities:   codegen.ServerCapabilities{Tools: &codegen.ToolCapability{}},
+		Implementation: codegen.Implementation{Name: "Search Server"},
+		Tools: []codegen.Tool{
+			{
+				Name:        "search",
+				InputSchema: struct{}{},
+				Deprecated:  "Use search_v2 instead",
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, "search"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	want := `&protocol.ToolAnnotations{Deprecated: "Use search_v2 instead"}`
+	if !bytes.Contains(buf.Bytes(), []byte(want)) {
+		t.Errorf("want the generated code to contain %s, got:\n%s", want, buf.String())
+	}
+}
+
+func TestGenerate_ToolAnnotations(t *testing.T) {
+	t.Parallel()
+	def := &codegen.ServerDefinition{
+		Capabilities:   codegen.ServerCapabilities{Tools: &codegen.ToolCapability{}},
+		Implementation: codegen.Implementation{Name: "File Server"},
+		Tools: []codegen.Tool{
+			{
+				Name:        "delete_file",
+				InputSchema: struct{}{},
+				Annotations: &codegen.ToolAnnotations{Title: "Delete File", DestructiveHint: true, IdempotentHint: true},
+				Deprecated:  "Use remove_file instead",
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, "file"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	typeCheck(t, "file", buf.Bytes())
+	want := `&protocol.ToolAnnotations{Title: "Delete File", DestructiveHint: true, IdempotentHint: true, Deprecated: "Use remove_file instead"}`
+	if !bytes.Contains(buf.Bytes(), []byte(want)) {
+		t.Errorf("want the generated code to contain %s, got:\n%s", want, buf.String())
+	}
+}
+
+func TestGenerate_ImplementationIcons(t *testing.T) {
+	t.Parallel()
+	def := &codegen.ServerDefinition{
+		Implementation: codegen.Implementation{
+			Name: "Search Server",
+			Icons: []codegen.Icon{
+				{Src: "https://example.com/logo.png", MimeType: "image/png", Sizes: []string{"48x48"}},
+				{Src: "data:image/svg+xml;base64,PHN2Zy8+"},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, "search"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	for _, want := range []string{
+		`{Src: "https://example.com/logo.png", MimeType: "image/png", Sizes: []string{"48x48"}},`,
+		`{Src: "data:image/svg+xml;base64,PHN2Zy8+"},`,
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("want the generated code to contain %s, got:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestGenerate_EscapeStrings(t *testing.T) {
+	t.Parallel()
+	def := &codegen.ServerDefinition{
+		Capabilities: codegen.ServerCapabilities{
+			Prompts:   &codegen.PromptCapability{},
+			Resources: &codegen.ResourceCapability{},
+			Tools:     &codegen.ToolCapability{},
+		},
+		Implementation: codegen.Implementation{Name: `Search "Server"`, Version: `1.0.0\beta`},
+		Prompts: []codegen.Prompt{
+			{
+				Name:        "summarize",
+				Description: "Summarize \"quoted\" text.\nIt supports C:\\paths.",
+				Arguments:   []codegen.PromptArgument{{Name: "text", Description: "Text with \"quotes\"\nand newlines"}},
+			},
+		},
+		ResourceTemplates: []codegen.ResourceTemplate{
+			{URITemplate: "search://{query}", Name: `"Search"`, Description: "Search results\nfor a query"},
+		},
+		Tools: []codegen.Tool{
+			{
+				Name:        "search",
+				Description: "Search for \"quotes\".\nReturns `results`.",
+				InputSchema: struct {
+					Query string "json:\"query\" jsonschema:\"description=A query with `backquotes`\""
+				}{},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, "search"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	typeCheck(t, "search", buf.Bytes())
+	want := `Description: "Search for \"quotes\".\nReturns ` + "`results`" + `.",`
+	if !bytes.Contains(buf.Bytes(), []byte(want)) {
+		t.Errorf("want the generated code to contain %s, got:\n%s", want, buf.String())
+	}
+}
+
+func TestGenerate_IntEnum(t *testing.T) {
+	t.Parallel()
+	def := &codegen.ServerDefinition{
+		Capabilities:   codegen.ServerCapabilities{Tools: &codegen.ToolCapability{}},
+		Implementation: codegen.Implementation{Name: "Alert Server"},
+		Tools: []codegen.Tool{
+			{
+				Name: "alert",
+				InputSchema: struct {
+					Severity int `json:"severity" jsonschema:"enum=1,enum=2,enum=3"`
+				}{},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, "alert"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	for _, want := range []string{"return strconv.Itoa(int(x))", "AlertSeverityType1 AlertSeverityType = 1"} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("want the generated code to contain %s, got:\n%s", want, buf.String())
+		}
+	}
+	typeCheck(t, "alert", buf.Bytes())
+}
+
+func TestGenerate_EnumConstNameCollision(t *testing.T) {
+	t.Parallel()
+	def := &codegen.ServerDefinition{
+		Capabilities:   codegen.ServerCapabilities{Tools: &codegen.ToolCapability{}},
+		Implementation: codegen.Implementation{Name: "File Server"},
+		Tools: []codegen.Tool{
+			{
+				Name: "open",
+				InputSchema: struct {
+					Mode  string `json:"mode" jsonschema:"enum=read-only,enum=read_only,enum=read-only-2"`
+					Level int    `json:"level" jsonschema:"enum=-1,enum=1"`
+				}{},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, "file"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	typeCheck(t, "file", buf.Bytes())
+	for _, want := range []string{
+		`OpenModeTypeReadOnly2 OpenModeType = "read-only-2"`,
+		`OpenModeTypeReadOnly  OpenModeType = "read-only"`,
+		`OpenModeTypeReadOnly3 OpenModeType = "read_only"`,
+		"OpenLevelType1   OpenLevelType = -1",
+		"OpenLevelType1_2 OpenLevelType = 1",
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("want the generated code to contain %s, got:\n%s", want, buf.String())
+		}
+	}
+}
+
+// ForecastInput is a named input type of tools in TestGenerate_OptionalFields,
+// whose schema is reflected as a reference to a definition unlike anonymous structs.
+type ForecastInput struct {
+	City     string   `json:"city"`
+	Days     int      `json:"days,omitempty"`
+	Lat      *float64 `json:"lat,omitempty"`
+	Language string   `json:"language,omitempty" jsonschema:"required"`
+	Units    string   `json:"units,omitempty" jsonschema:"enum=metric,enum=imperial"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+func TestGenerate_OptionalFields(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]any{
+		"anonymous struct": struct {
+			City     string   `json:"city"`
+			Days     int      `json:"days,omitempty"`
+			Lat      *float64 `json:"lat,omitempty"`
+			Language string   `json:"language,omitempty" jsonschema:"required"`
+			Units    string   `json:"units,omitempty" jsonschema:"enum=metric,enum=imperial"`
+			Tags     []string `json:"tags,omitempty"`
+		}{},
+		"named struct": ForecastInput{},
+	}
+	for name, input := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			def := &codegen.ServerDefinition{
+				Capabilities:   codegen.ServerCapabilities{Tools: &codegen.ToolCapability{}},
+				Implementation: codegen.Implementation{Name: "Weather Server"},
+				Tools: []codegen.Tool{
+					{Name: "get_forecast", InputSchema: input},
+				},
+			}
+
+			var buf bytes.Buffer
+			if err := codegen.Generate(&buf, def, "weather"); err != nil {
+				t.Fatalf("failed to generate code: %v", err)
+			}
+			typeCheck(t, "weather", buf.Bytes())
+			want := "type ToolGetForecastRequest struct {\n" +
+				"\tCity     string                `json:\"city\"`\n" +
+				"\tDays     *int                  `json:\"days,omitempty\"`\n" +
+				"\tLat      *float64              `json:\"lat,omitempty\"`\n" +
+				"\tLanguage string                `json:\"language\"`\n" +
+				"\tUnits    *GetForecastUnitsType `json:\"units,omitempty\"`\n" +
+				"\tTags     []string              `json:\"tags,omitempty\"`\n" +
+				"}"
+			if !bytes.Contains(buf.Bytes(), []byte(want)) {
+				t.Errorf("want the generated code to contain %s, got:\n%s", want, buf.String())
+			}
+		})
+	}
+}
+
+func TestGenerate_FieldImports(t *testing.T) {
+	t.Parallel()
+	def := &codegen.ServerDefinition{
+		Capabilities:   codegen.ServerCapabilities{Tools: &codegen.ToolCapability{}},
+		Implementation: codegen.Implementation{Name: "Calendar Server"},
+		Tools: []codegen.Tool{
+			{
+				Name: "create_event",
+				InputSchema: struct {
+					Start     time.Time                `json:"start"`
+					Reminders map[string]time.Duration `json:"reminders"`
+					Labels    map[string]string        `json:"labels"`
+					Level     protocol.LogLevel        `json:"level"`
+					Extra     *yaml.Node               `json:"extra"`
+				}{},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, "calendar"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	typeCheck(t, "calendar", buf.Bytes())
+	for _, want := range []string{`"time"`, `"github.com/ktr0731/go-mcp/protocol"`, `"gopkg.in/yaml.v3"`} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("want the generated code to import %s, got:\n%s", want, buf.String())
+		}
+	}
+}
+
+// Pagination is embedded in tool inputs in TestGenerate_IgnoredAndEmbeddedFields.
+type Pagination struct {
+	Page   int    `json:"page"`
+	Cursor string `json:"-"`
+}
+
+func TestGenerate_IgnoredAndEmbeddedFields(t *testing.T) {
+	t.Parallel()
+	def := &codegen.ServerDefinition{
+		Capabilities:   codegen.ServerCapabilities{Tools: &codegen.ToolCapability{}},
+		Implementation: codegen.Implementation{Name: "Search Server"},
+		Tools: []codegen.Tool{
+			{
+				Name: "search",
+				InputSchema: struct {
+					Pagination
+					Query    string `json:"query"`
+					Internal string `json:"-"`
+					Debug    bool   `json:"debug" jsonschema:"-"`
+				}{},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, "search"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	typeCheck(t, "search", buf.Bytes())
+	for _, want := range []string{"Page  int    `json:\"page\"`", "Query string `json:\"query\"`"} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("want the generated code to contain %s, got:\n%s", want, buf.String())
+		}
+	}
+	for _, notWant := range []string{"Pagination", "Cursor", "Internal", "Debug"} {
+		if bytes.Contains(buf.Bytes(), []byte(notWant)) {
+			t.Errorf("want the generated code not to contain %s, got:\n%s", notWant, buf.String())
+		}
+	}
+}
+
+func TestGenerate_FieldDocComments(t *testing.T) {
+	t.Parallel()
+	def := &codegen.ServerDefinition{
+		Capabilities:   codegen.ServerCapabilities{Tools: &codegen.ToolCapability{}},
+		Implementation: codegen.Implementation{Name: "Weather Server"},
+		Tools: []codegen.Tool{
+			{
+				Name:        "get_forecast",
+				InputSchema: json.RawMessage(`{"type":"object","properties":{"city":{"type":"string","description":"City name.\nIt must be in English."},"days":{"type":"integer"}},"required":["city","days"]}`),
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, "weather"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	typeCheck(t, "weather", buf.Bytes())
+	want := "type ToolGetForecastRequest struct {\n" +
+		"\t// City name.\n" +
+		"\t// It must be in English.\n" +
+		"\tCity string `json:\"city\"`\n" +
+		"\tDays int    `json:\"days\"`\n" +
+		"}"
+	if !bytes.Contains(buf.Bytes(), []byte(want)) {
+		t.Errorf("want the generated code to contain %s, got:\n%s", want, buf.String())
+	}
+}
+
+// ReportInput is a named input type of tools in TestGenerate_NamedStructFieldDocComments.
+type ReportInput struct {
+	City string `json:"city" jsonschema:"description=City name"`
+	Days int    `json:"days,omitempty"`
+}
+
+func TestGenerate_NamedStructFieldDocComments(t *testing.T) {
+	t.Parallel()
+	def := &codegen.ServerDefinition{
+		Capabilities:   codegen.ServerCapabilities{Tools: &codegen.ToolCapability{}},
+		Implementation: codegen.Implementation{Name: "Weather Server"},
+		Tools: []codegen.Tool{
+			{Name: "get_report", InputSchema: ReportInput{}},
+			{Name: "get_report_ptr", InputSchema: &ReportInput{}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, "weather"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	typeCheck(t, "weather", buf.Bytes())
+	for _, name := range []string{"ToolGetReportRequest", "ToolGetReportPtrRequest"} {
+		want := "type " + name + " struct {\n" +
+			"\t// City name\n" +
+			"\tCity string `json:\"city\"`\n" +
+			"\tDays *int   `json:\"days,omitempty\"`\n" +
+			"}"
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("want the generated code to contain %s, got:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestGenerate_InvalidPromptArgumentPattern(t *testing.T) {
+	t.Parallel()
+	def := &codegen.ServerDefinition{
+		Capabilities:   codegen.ServerCapabilities{Prompts: &codegen.PromptCapability{}},
+		Implementation: codegen.Implementation{Name: "Weather Server"},
+		Prompts: []codegen.Prompt{
+			{Name: "weather_report", Arguments: []codegen.PromptArgument{{Name: "language", Pattern: "[a-z"}}},
+		},
+	}
+
+	if err := codegen.Generate(io.Discard, def, "weather"); err == nil {
+		t.Error("want an error for an invalid pattern, got nil")
+	}
+}
+
+func TestParseDefinition(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open(filepath.Join("testdata", "search.json"))
+	if err != nil {
+		t.Fatalf("failed to open the definition: %v", err)
+	}
+	defer f.Close()
+	def, err := codegen.ParseDefinition(f)
+	if err != nil {
+		t.Fatalf("failed to parse the definition: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, "search"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	typeCheck(t, "search", buf.Bytes())
+	want := "type ToolSearchRequest struct {\n" +
+		"\tLimit *int `json:\"limit,omitempty\"`\n" +
+		"\t// Search query\n" +
+		"\tQuery string          `json:\"query\"`\n" +
+		"\tSort  *SearchSortType `json:\"sort,omitempty\"`\n" +
+		"\tTags  []string        `json:\"tags,omitempty\"`\n" +
+		"}"
+	if !bytes.Contains(buf.Bytes(), []byte(want)) {
+		t.Errorf("want the generated code to contain %s, got:\n%s", want, buf.String())
+	}
+
+	// The same definition in YAML generates the same request type.
+	yamlDef, err := codegen.ParseDefinition(strings.NewReader(`
+capabilities:
+  tools: {}
+implementation:
+  name: Search Server
+  version: 1.0.0
+tools:
+  - name: search
+    description: Search documents
+    inputSchema:
+      type: object
+      properties:
+        query: {type: string, description: Search query}
+        limit: {type: integer}
+        sort: {type: string, enum: [relevance, date]}
+        tags: {type: array, items: {type: string}}
+      required: [query]
+`))
+	if err != nil {
+		t.Fatalf("failed to parse the YAML definition: %v", err)
+	}
+	var yamlBuf bytes.Buffer
+	if err := codegen.Generate(&yamlBuf, yamlDef, "search"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	if !bytes.Contains(yamlBuf.Bytes(), []byte(want)) {
+		t.Errorf("want the generated code to contain %s, got:\n%s", want, yamlBuf.String())
+	}
+}
+
+func TestGenerate_ToolTimeout(t *testing.T) {
+	t.Parallel()
+	def, err := codegen.ParseDefinition(strings.NewReader(`
+capabilities:
+  tools: {}
+implementation:
+  name: Search Server
+tools:
+  - name: search
+    inputSchema: {type: object}
+    timeout: 1500ms
+  - name: count
+    inputSchema: {type: object}
+`))
+	if err != nil {
+		t.Fatalf("failed to parse the definition: %v", err)
+	}
+	if def.Tools[0].Timeout != 1500*time.Millisecond {
+		t.Errorf("want the timeout 1.5s, got %s", def.Tools[0].Timeout)
+	}
+
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, "search"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	typeCheck(t, "search", buf.Bytes())
+	if n := bytes.Count(buf.Bytes(), []byte("h.SetToolTimeout(")); n != 1 {
+		t.Errorf("want only the search tool to have the timeout, got %d timeouts:\n%s", n, buf.String())
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("h.SetToolTimeout(ToolSearchName, 1500*time.Millisecond, mcp.ToolTimeoutAsResult())")) {
+		t.Errorf("want the timeout of the search tool to be set, got:\n%s", buf.String())
+	}
+
+	if _, err := codegen.ParseDefinition(strings.NewReader(`{"tools": [{"name": "search", "inputSchema": {"type": "object"}, "timeout": "soon"}]}`)); err == nil {
+		t.Error("want an error for an invalid timeout, got nil")
+	}
+}
+
+func TestParseDefinition_InvalidInputSchema(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"missing":    `{"implementation": {"name": "Search Server"}, "tools": [{"name": "search"}]}`,
+		"not object": `{"implementation": {"name": "Search Server"}, "tools": [{"name": "search", "inputSchema": {"type": "string"}}]}`,
+	}
+	for name, def := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := codegen.ParseDefinition(strings.NewReader(def)); err == nil {
+				t.Error("want an error, got nil")
+			}
+		})
+	}
+}
+
+func TestGenerate_RawInputSchema(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]any{
+		"json.RawMessage": json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"},"days":{"type":"integer","enum":[1,3,7]}},"required":["city"]}`),
+		"map[string]any": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"city": map[string]any{"type": "string"},
+				"days": map[string]any{"type": "integer", "enum": []any{1, 3, 7}},
+			},
+			"required": []any{"city"},
+		},
+	}
+	for name, schema := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			def := &codegen.ServerDefinition{
+				Capabilities:   codegen.ServerCapabilities{Tools: &codegen.ToolCapability{}},
+				Implementation: codegen.Implementation{Name: "Weather Server"},
+				Tools:          []codegen.Tool{{Name: "get_forecast", InputSchema: schema}},
+			}
+
+			var buf bytes.Buffer
+			if err := codegen.Generate(&buf, def, "weather"); err != nil {
+				t.Fatalf("failed to generate code: %v", err)
+			}
+			typeCheck(t, "weather", buf.Bytes())
+			want := "type ToolGetForecastRequest struct {\n" +
+				"\tCity string               `json:\"city\"`\n" +
+				"\tDays *GetForecastDaysType `json:\"days,omitempty\"`\n" +
+				"}"
+			if !bytes.Contains(buf.Bytes(), []byte(want)) {
+				t.Errorf("want the generated code to contain %s, got:\n%s", want, buf.String())
+			}
+		})
+	}
+}
+
+func TestGenerate_TypedPromptArguments(t *testing.T) {
+	t.Parallel()
+	def := &codegen.ServerDefinition{
+		Capabilities:   codegen.ServerCapabilities{Prompts: &codegen.PromptCapability{}},
+		Implementation: codegen.Implementation{Name: "Weather Server"},
+		Prompts: []codegen.Prompt{
+			{
+				Name: "weather_alert",
+				Arguments: []codegen.PromptArgument{
+					{Name: "city"},
+					{Name: "severity", Type: "integer"},
+					{Name: "threshold", Type: "number"},
+					{Name: "urgent", Type: "boolean"},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, "weather"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	typeCheck(t, "weather", buf.Bytes())
+	want := "type PromptWeatherAlertRequest struct {\n" +
+		"\tCity      string  `json:\"city\"`\n" +
+		"\tSeverity  int     `json:\"severity,string\"`\n" +
+		"\tThreshold float64 `json:\"threshold,string\"`\n" +
+		"\tUrgent    bool    `json:\"urgent,string\"`\n" +
+		"}"
+	if !bytes.Contains(buf.Bytes(), []byte(want)) {
+		t.Errorf("want the generated code to contain %s, got:\n%s", want, buf.String())
+	}
+
+	def.Prompts[0].Arguments[0].Type = "date"
+	if err := codegen.Generate(io.Discard, def, "weather"); err == nil {
+		t.Error("want an error for an unknown type, got nil")
+	}
+}
+
+func TestGenerate_RequiredPromptArguments(t *testing.T) {
+	t.Parallel()
+	def := weatherServerDefinition()
+
+	var buf bytes.Buffer
+	if err := codegen.Generate(&buf, def, "weather"); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	want := "case PromptWeatherReportName:\n" +
+		"\t\t\t\tif err := protocol.ValidateRequiredPromptArguments(req.Arguments, \"city\"); err != nil {\n" +
+		"\t\t\t\t\treturn nil, err\n" +
+		"\t\t\t\t}\n"
+	if !bytes.Contains(buf.Bytes(), []byte(want)) {
+		t.Errorf("want the generated code to contain %s, got:\n%s", want, buf.String())
 	}
 }
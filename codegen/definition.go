@@ -0,0 +1,87 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParseDefinition reads a server definition from a JSON or YAML document.
+// The document has the same structure as the JSON representation of ServerDefinition, e.g.
+//
+//	{
+//	  "capabilities": {"tools": {}},
+//	  "implementation": {"name": "Weather Server", "version": "1.0.0"},
+//	  "tools": [
+//	    {
+//	      "name": "get_forecast",
+//	      "inputSchema": {"type": "object", "properties": {"city": {"type": "string"}}, "required": ["city"]}
+//	    }
+//	  ]
+//	}
+//
+// Input and output schemas of tools are JSON schemas, which are kept as json.RawMessage.
+// The returned definition is validated in the same way as Generate.
+func ParseDefinition(r io.Reader) (*ServerDefinition, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the definition: %w", err)
+	}
+
+	// JSON is a subset of YAML, but decode JSON documents directly to report JSON syntax errors as they are.
+	if trimmed := bytes.TrimSpace(b); len(trimmed) == 0 || trimmed[0] != '{' {
+		var v any
+		if err := yaml.Unmarshal(b, &v); err != nil {
+			return nil, fmt.Errorf("failed to decode the definition as YAML: %w", err)
+		}
+		b, err = json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert the YAML definition to JSON: %w", err)
+		}
+	}
+
+	var def ServerDefinition
+	if err := json.Unmarshal(b, &def); err != nil {
+		return nil, fmt.Errorf("failed to decode the definition: %w", err)
+	}
+	if err := validate(&def); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// UnmarshalJSON decodes a tool definition, keeping its schemas as json.RawMessage.
+// The timeout is decoded from a string parsed by time.ParseDuration, e.g. "30s".
+func (t *Tool) UnmarshalJSON(b []byte) error {
+	type tool Tool
+	var v struct {
+		tool
+		InputSchema  json.RawMessage `json:"inputSchema"`
+		OutputSchema json.RawMessage `json:"outputSchema"`
+		Timeout      string          `json:"timeout"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	*t = Tool(v.tool)
+	t.InputSchema, t.OutputSchema, t.Timeout = nil, nil, 0
+	if v.Timeout != "" {
+		d, err := time.ParseDuration(v.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout of tool %q: %w", t.Name, err)
+		}
+		t.Timeout = d
+	}
+	if len(v.InputSchema) != 0 {
+		t.InputSchema = v.InputSchema
+	}
+	if len(v.OutputSchema) != 0 {
+		t.OutputSchema = v.OutputSchema
+	}
+	return nil
+}
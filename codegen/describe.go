@@ -0,0 +1,214 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Describe renders a human-readable Markdown summary of the server definition.
+// The summary contains the capabilities, tools with their input schemas, prompts with their arguments,
+// and resource templates, so that reviewers can understand the server's surface without reading the generated code.
+func Describe(def *ServerDefinition) string {
+	d := &describer{def: def}
+	d.describe()
+	return d.buf.String()
+}
+
+type describer struct {
+	buf strings.Builder
+	def *ServerDefinition
+}
+
+func (d *describer) describe() {
+	d.printf("# %s\n\n", d.def.Implementation.Name)
+	if d.def.Implementation.Version != "" {
+		d.printf("Version: %s\n\n", d.def.Implementation.Version)
+	}
+
+	d.describeCapabilities()
+	d.describeTools()
+	d.describePrompts()
+	d.describeResources()
+	d.describeResourceTemplates()
+}
+
+func (d *describer) describeCapabilities() {
+	d.printf("## Capabilities\n\n")
+
+	caps := d.def.Capabilities
+	var lines []string
+	if caps.Prompts != nil {
+		lines = append(lines, "- Prompts")
+	}
+	if caps.Resources != nil {
+		lines = append(lines, fmt.Sprintf("- Resources (subscribe: %t, listChanged: %t)", caps.Resources.Subscribe, caps.Resources.ListChanged))
+	}
+	if caps.Tools != nil {
+		lines = append(lines, "- Tools")
+	}
+	if caps.Completions != nil {
+		lines = append(lines, "- Completions")
+	}
+	if caps.Logging != nil {
+		lines = append(lines, "- Logging")
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "None")
+	}
+	d.printf("%s\n\n", strings.Join(lines, "\n"))
+}
+
+func (d *describer) describeTools() {
+	if len(d.def.Tools) == 0 {
+		return
+	}
+
+	d.printf("## Tools\n\n")
+	for _, tool := range d.def.Tools {
+		d.printf("### %s\n\n", tool.Name)
+		if tool.Deprecated != "" {
+			d.printf("**Deprecated**: %s\n\n", tool.Deprecated)
+		}
+		if tool.Description != "" {
+			d.printf("%s\n\n", tool.Description)
+		}
+		if hints := describeToolHints(tool.Annotations); len(hints) != 0 {
+			d.printf("Hints: %s\n\n", strings.Join(hints, ", "))
+		}
+		if tool.Timeout > 0 {
+			d.printf("Timeout: %s\n\n", tool.Timeout)
+		}
+
+		d.describeSchema("Input schema", tool.InputSchema)
+		if tool.OutputSchema != nil {
+			d.describeSchema("Output schema", tool.OutputSchema)
+		}
+	}
+}
+
+// describeToolHints returns the names of the hints set in a.
+func describeToolHints(a *ToolAnnotations) []string {
+	if a == nil {
+		return nil
+	}
+	var hints []string
+	if a.ReadOnlyHint {
+		hints = append(hints, "read-only")
+	}
+	if a.DestructiveHint {
+		hints = append(hints, "destructive")
+	}
+	if a.IdempotentHint {
+		hints = append(hints, "idempotent")
+	}
+	if a.OpenWorldHint {
+		hints = append(hints, "open world")
+	}
+	return hints
+}
+
+// describeSchema prints the JSON schema reflected from v with the label.
+func (d *describer) describeSchema(label string, v any) {
+	schema, err := reflectSchema(v)
+	if err != nil {
+		d.printf("%s: invalid (%s)\n\n", label, err)
+		return
+	}
+	b, err := schema.MarshalJSON()
+	if err != nil {
+		panic(err)
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, b, "", "  "); err != nil {
+		panic(err)
+	}
+	d.printf("%s:\n\n```json\n%s\n```\n\n", label, indented.String())
+}
+
+func (d *describer) describePrompts() {
+	if len(d.def.Prompts) == 0 {
+		return
+	}
+
+	d.printf("## Prompts\n\n")
+	for _, prompt := range d.def.Prompts {
+		d.printf("### %s\n\n", prompt.Name)
+		if prompt.Description != "" {
+			d.printf("%s\n\n", prompt.Description)
+		}
+		if len(prompt.Arguments) == 0 {
+			continue
+		}
+
+		d.printf("| Argument | Required | Description |\n")
+		d.printf("| --- | --- | --- |\n")
+		for _, arg := range prompt.Arguments {
+			d.printf("| %s | %t | %s |\n", escapeTableCell(arg.Name), arg.Required, escapeTableCell(describeArgument(arg)))
+		}
+		d.printf("\n")
+	}
+}
+
+// describeArgument returns the description of arg followed by its expected format if any.
+func describeArgument(arg PromptArgument) string {
+	desc := arg.Description
+	if arg.Pattern != "" {
+		desc += fmt.Sprintf(" (pattern: `%s`)", arg.Pattern)
+	}
+	if len(arg.Enum) != 0 {
+		desc += fmt.Sprintf(" (one of: %s)", strings.Join(arg.Enum, ", "))
+	}
+	return strings.TrimSpace(desc)
+}
+
+func (d *describer) describeResources() {
+	if len(d.def.Resources) == 0 {
+		return
+	}
+
+	d.printf("## Resources\n\n")
+	d.printf("| URI | Name | MIME Type | Description |\n")
+	d.printf("| --- | --- | --- | --- |\n")
+	for _, resource := range d.def.Resources {
+		d.printf("| `%s` | %s | %s | %s |\n",
+			escapeTableCell(resource.URI),
+			escapeTableCell(resource.Name),
+			escapeTableCell(resource.MimeType),
+			escapeTableCell(resource.Description),
+		)
+	}
+	d.printf("\n")
+}
+
+func (d *describer) describeResourceTemplates() {
+	if len(d.def.ResourceTemplates) == 0 {
+		return
+	}
+
+	d.printf("## Resource Templates\n\n")
+	d.printf("| URI Template | Name | MIME Type | Description |\n")
+	d.printf("| --- | --- | --- | --- |\n")
+	for _, tmpl := range d.def.ResourceTemplates {
+		d.printf("| `%s` | %s | %s | %s |\n",
+			escapeTableCell(tmpl.URITemplate),
+			escapeTableCell(tmpl.Name),
+			escapeTableCell(tmpl.MimeType),
+			escapeTableCell(tmpl.Description),
+		)
+	}
+	d.printf("\n")
+}
+
+func (d *describer) printf(format string, args ...any) {
+	fmt.Fprintf(&d.buf, format, args...)
+}
+
+// tableCellReplacer escapes characters that break a Markdown table row.
+var tableCellReplacer = strings.NewReplacer("|", `\|`, "\n", " ")
+
+// escapeTableCell escapes a string so that it can be placed in a Markdown table cell.
+func escapeTableCell(s string) string {
+	return tableCellReplacer.Replace(s)
+}
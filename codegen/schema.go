@@ -0,0 +1,270 @@
+package codegen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// examplesTag is the struct tag that declares examples of a field as a JSON array, e.g. `jsonschema_examples:"[\"Tokyo, Japan\"]"`.
+// Unlike `jsonschema:"example=..."`, it can declare examples that contain commas or are objects.
+const examplesTag = "jsonschema_examples"
+
+// sensitiveTagValue is the value of the mcp struct tag that marks a tool input field as sensitive, e.g. `mcp:"sensitive"`.
+// Sensitive arguments are redacted by mcp.Handler.RedactToolArguments.
+const sensitiveTagValue = "sensitive"
+
+// sensitiveArguments returns the JSON names of the fields of a tool input schema marked as sensitive.
+func sensitiveArguments(v any) []string {
+	var names []string
+	for _, f := range schemaFields(reflect.TypeOf(v)) {
+		if slices.Contains(strings.Split(f.Tag.Get("mcp"), ","), sensitiveTagValue) {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+// schemaField is a field of a struct which is a property of the reflected JSON schema of the struct.
+type schemaField struct {
+	reflect.StructField
+	// name is the name of the property.
+	name string
+}
+
+// schemaFields returns the fields of struct type t which are properties of its reflected JSON schema, in order.
+// Like invopop/jsonschema, fields ignored by json:"-" or jsonschema:"-" and unexported fields are skipped,
+// and fields of embedded structs without JSON names are flattened. If names of fields conflict, the first one is used.
+func schemaFields(t reflect.Type) []schemaField {
+	var fields []schemaField
+	seen := map[string]bool{}
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		for i := range t.NumField() {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			schemaTag, _, _ := strings.Cut(f.Tag.Get("jsonschema"), ",")
+			if name == "-" || schemaTag == "-" {
+				continue
+			}
+			if f.Anonymous && name == "" {
+				ft := f.Type
+				if ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					walk(ft)
+					continue
+				}
+			}
+			if !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			fields = append(fields, schemaField{StructField: f, name: name})
+		}
+	}
+	walk(t)
+	return fields
+}
+
+// typeImports adds the packages of the named types which t refers to, e.g. "time" for time.Time, to imports.
+// imports maps import paths to package names.
+func typeImports(imports map[string]string, t reflect.Type) {
+	if t.Name() != "" {
+		if t.PkgPath() != "" {
+			imports[t.PkgPath()] = strings.TrimSuffix(t.String(), "."+t.Name())
+		}
+		return
+	}
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Chan:
+		typeImports(imports, t.Elem())
+	case reflect.Map:
+		typeImports(imports, t.Key())
+		typeImports(imports, t.Elem())
+	case reflect.Struct:
+		for i := range t.NumField() {
+			typeImports(imports, t.Field(i).Type)
+		}
+	}
+}
+
+// reflectSchema reflects the JSON schema of a tool input or output schema.
+// In addition to the tags supported by invopop/jsonschema, it applies examples declared by examplesTag.
+// If v is a json.RawMessage or a map[string]any, it is decoded as a JSON schema instead.
+func reflectSchema(v any) (*jsonschema.Schema, error) {
+	if m, ok := v.(map[string]any); ok {
+		b, err := json.Marshal(m)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON schema: %w", err)
+		}
+		v = json.RawMessage(b)
+	}
+	if raw, ok := v.(json.RawMessage); ok {
+		var schema jsonschema.Schema
+		if err := json.Unmarshal(raw, &schema); err != nil {
+			return nil, fmt.Errorf("invalid JSON schema: %w", err)
+		}
+		return &schema, nil
+	}
+	if v == nil {
+		return nil, errors.New("schema is missing")
+	}
+
+	// Expand a named root type so that the required fields and the properties are read from the root schema
+	// rather than from a definition it refers to, as the mcp package does when it validates arguments.
+	typ := reflect.TypeOf(v)
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	reflector := jsonschema.Reflector{ExpandedStruct: typ.Name() != ""}
+	schema := reflector.Reflect(v)
+	a := &examplesApplier{root: schema, visited: map[*jsonschema.Schema]bool{}}
+	if err := a.apply(schema, reflect.TypeOf(v)); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// examplesApplier applies examples declared by examplesTag to a reflected schema.
+type examplesApplier struct {
+	// root is used to resolve references to definitions.
+	root *jsonschema.Schema
+	// visited prevents infinite recursion on recursive types.
+	visited map[*jsonschema.Schema]bool
+}
+
+// apply applies examples declared by the fields of t to the properties of schema.
+func (a *examplesApplier) apply(schema *jsonschema.Schema, t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	schema = a.resolveRef(schema)
+	if schema == nil || a.visited[schema] {
+		return nil
+	}
+	a.visited[schema] = true
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if schema.Items != nil {
+			return a.apply(schema.Items, t.Elem())
+		}
+		return nil
+	case reflect.Struct:
+		return a.applyFields(schema, t)
+	default:
+		return nil
+	}
+}
+
+// applyFields applies examples declared by the fields of struct type t to the properties of schema.
+func (a *examplesApplier) applyFields(schema *jsonschema.Schema, t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			// Embedded struct fields are inlined to the parent.
+			if err := a.applyFields(schema, f.Type); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if schema.Properties == nil {
+			continue
+		}
+		prop, ok := schema.Properties.Get(name)
+		if !ok {
+			continue
+		}
+
+		if tag, ok := f.Tag.Lookup(examplesTag); ok {
+			var examples []any
+			if err := json.Unmarshal([]byte(tag), &examples); err != nil {
+				return fmt.Errorf("invalid %s tag of field %s: must be a JSON array: %w", examplesTag, f.Name, err)
+			}
+			prop.Examples = examples
+		}
+		if err := a.apply(prop, f.Type); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveRef returns the definition that schema refers to, or schema itself if it isn't a reference.
+func (a *examplesApplier) resolveRef(schema *jsonschema.Schema) *jsonschema.Schema {
+	name, ok := strings.CutPrefix(schema.Ref, "#/$defs/")
+	if !ok {
+		return schema
+	}
+	return a.root.Definitions[name]
+}
+
+// isRawSchema reports whether v is a JSON schema itself rather than a Go struct to reflect.
+func isRawSchema(v any) bool {
+	switch v.(type) {
+	case json.RawMessage, map[string]any:
+		return true
+	default:
+		return false
+	}
+}
+
+// goType returns the Go type of values conforming to schema.
+// Types which can't be determined by schema are represented by any.
+func goType(schema *jsonschema.Schema) string {
+	switch schema.Type {
+	case "string":
+		return "string"
+	case "integer":
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		if schema.Items == nil {
+			return "[]any"
+		}
+		return "[]" + goType(schema.Items)
+	case "object":
+		return "map[string]any"
+	default:
+		return "any"
+	}
+}
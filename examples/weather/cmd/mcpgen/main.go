@@ -37,7 +37,9 @@ func main() {
 		Implementation: codegen.Implementation{
 			Name:    "Weather Forecast MCP Server",
 			Version: "1.0.0",
+			Title:   "Weather Forecast",
 		},
+		Instructions: "Use the weather://forecast resources for \"current\" forecasts and the tools for unit conversions.\nCity names must be in English.",
 		// Prompt definitions
 		Prompts: []codegen.Prompt{
 			{
@@ -45,7 +47,7 @@ func main() {
 				Description: "Generate a weather report based on weather data",
 				Arguments: []codegen.PromptArgument{
 					{Name: "city", Description: "City name", Required: true},
-					{Name: "language", Description: "Report language (e.g. 'en', 'ja')", Required: false},
+					{Name: "language", Description: "Report language (e.g. 'en', 'ja')", Required: false, Pattern: "^[a-z]{2}$"},
 				},
 			},
 			{
@@ -53,7 +55,7 @@ func main() {
 				Description: "Generate a weather alert message",
 				Arguments: []codegen.PromptArgument{
 					{Name: "alert_type", Description: "Type of alert (e.g. 'rain', 'snow', 'heat')", Required: true},
-					{Name: "severity", Description: "Alert severity (1-5)", Required: true},
+					{Name: "severity", Description: "Alert severity (1-5)", Required: true, Enum: []string{"1", "2", "3", "4", "5"}, Type: "integer"},
 				},
 			},
 		},
@@ -67,6 +69,12 @@ func main() {
 					FromUnit    string  `json:"from_unit" jsonschema:"description=Source temperature unit,enum=celsius,enum=fahrenheit"`
 					ToUnit      string  `json:"to_unit" jsonschema:"description=Target temperature unit,enum=celsius,enum=fahrenheit"`
 				}{},
+				OutputSchema: struct {
+					Temperature float64 `json:"temperature" jsonschema:"description=Converted temperature value"`
+					Unit        string  `json:"unit" jsonschema:"description=Unit of the converted temperature,enum=celsius,enum=fahrenheit"`
+				}{},
+				// The conversion doesn't have side effects, so clients can call it without asking users.
+				Annotations: &codegen.ToolAnnotations{Title: "Convert Temperature", ReadOnlyHint: true},
 			},
 			{
 				Name:        "calculate_humidity_index",
@@ -77,6 +85,12 @@ func main() {
 				}{},
 			},
 		},
+		// Resource definitions
+		Resources: []codegen.Resource{
+			{URI: "weather://forecast/tokyo", Name: "Tokyo Weather Forecast", Description: "Current weather data for Tokyo", MimeType: "application/json"},
+			{URI: "weather://forecast/new_york", Name: "New York Weather Forecast", Description: "Current weather data for New York", MimeType: "application/json"},
+			{URI: "weather://forecast/london", Name: "London Weather Forecast", Description: "Current weather data for London", MimeType: "application/json"},
+		},
 		// Resource template definitions
 		ResourceTemplates: []codegen.ResourceTemplate{
 			{
@@ -90,6 +104,8 @@ func main() {
 				Name:        "Historical Weather Data",
 				Description: "Historical weather data for a specific city and date",
 				MimeType:    "application/json",
+				// Only city is completed by the completion handler.
+				CompletableVariables: []string{"city"},
 			},
 		},
 	}
@@ -19,14 +19,49 @@ type ServerPromptHandler interface {
 
 // PromptWeatherReportRequest contains input parameters for the weather_report prompt.
 type PromptWeatherReportRequest struct {
-	City     string `json:"city"`
+	// City name
+	City string `json:"city"`
+	// Report language (e.g. 'en', 'ja')
 	Language string `json:"language"`
 }
 
 // PromptWeatherAlertRequest contains input parameters for the weather_alert prompt.
 type PromptWeatherAlertRequest struct {
+	// Type of alert (e.g. 'rain', 'snow', 'heat')
 	AlertType string `json:"alert_type"`
-	Severity  string `json:"severity"`
+	// Alert severity (1-5)
+	Severity int `json:"severity,string"`
+}
+
+// ResourceList contains all available Resources.
+var ResourceList = []mcp.Resource{
+	{
+		URI:         "weather://forecast/tokyo",
+		Name:        "Tokyo Weather Forecast",
+		Description: "Current weather data for Tokyo",
+		MimeType:    "application/json",
+	},
+	{
+		URI:         "weather://forecast/new_york",
+		Name:        "New York Weather Forecast",
+		Description: "Current weather data for New York",
+		MimeType:    "application/json",
+	},
+	{
+		URI:         "weather://forecast/london",
+		Name:        "London Weather Forecast",
+		Description: "Current weather data for London",
+		MimeType:    "application/json",
+	},
+}
+
+// ResourceListHandler handles resources/list requests by returning ResourceList.
+// Embed it in an mcp.ServerResourceHandler to offer the resources, or define HandleResourcesList to override it.
+type ResourceListHandler struct{}
+
+// HandleResourcesList returns ResourceList.
+func (ResourceListHandler) HandleResourcesList(ctx context.Context) (*mcp.ListResourcesResult, error) {
+	return &mcp.ListResourcesResult{Resources: slices.Clone(ResourceList)}, nil
 }
 
 // ResourceTemplateList contains all available ResourceTemplates.
@@ -38,13 +73,60 @@ var ResourceTemplateList = []mcp.ResourceTemplate{
 		MimeType:    "application/json",
 	},
 	{
-		URITemplate: "weather://historical/{city}/{date}",
-		Name:        "Historical Weather Data",
-		Description: "Historical weather data for a specific city and date",
-		MimeType:    "application/json",
+		URITemplate:          "weather://historical/{city}/{date}",
+		Name:                 "Historical Weather Data",
+		Description:          "Historical weather data for a specific city and date",
+		MimeType:             "application/json",
+		CompletableVariables: []string{"city"},
 	},
 }
 
+// ServerResourceTemplateHandler is the interface for resource template handlers.
+// Use ReadResourceByTemplate to dispatch resources/read requests to it.
+type ServerResourceTemplateHandler interface {
+	HandleResourceCityWeatherForecast(ctx context.Context, req *ResourceCityWeatherForecastRequest) (*mcp.ReadResourceResult, error)
+	HandleResourceHistoricalWeatherData(ctx context.Context, req *ResourceHistoricalWeatherDataRequest) (*mcp.ReadResourceResult, error)
+}
+
+// ResourceCityWeatherForecastRequest contains the variables of the weather://forecast/{city} resource template.
+type ResourceCityWeatherForecastRequest struct {
+	// URI is the URI of the resource to read.
+	URI  string
+	City string
+}
+
+// ResourceHistoricalWeatherDataRequest contains the variables of the weather://historical/{city}/{date} resource template.
+type ResourceHistoricalWeatherDataRequest struct {
+	// URI is the URI of the resource to read.
+	URI  string
+	City string
+	Date string
+}
+
+var (
+	uriTemplateCityWeatherForecast   = mcp.MustParseURITemplate("weather://forecast/{city}")
+	uriTemplateHistoricalWeatherData = mcp.MustParseURITemplate("weather://historical/{city}/{date}")
+)
+
+// ReadResourceByTemplate reads the resource of req by the handler method of the resource template matching its URI.
+// Templates are matched in the order of ResourceTemplateList. If no template matches, it returns an error.
+func ReadResourceByTemplate(ctx context.Context, h ServerResourceTemplateHandler, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	if vars, ok := uriTemplateCityWeatherForecast.Match(req.URI); ok {
+		return h.HandleResourceCityWeatherForecast(ctx, &ResourceCityWeatherForecastRequest{
+			URI:  req.URI,
+			City: vars["city"],
+		})
+	}
+	if vars, ok := uriTemplateHistoricalWeatherData.Match(req.URI); ok {
+		return h.HandleResourceHistoricalWeatherData(ctx, &ResourceHistoricalWeatherDataRequest{
+			URI:  req.URI,
+			City: vars["city"],
+			Date: vars["date"],
+		})
+	}
+	return nil, fmt.Errorf("resource not found: %s", req.URI)
+}
+
 // ServerToolHandler is the interface for tool handlers.
 type ServerToolHandler interface {
 	HandleToolConvertTemperature(ctx context.Context, req *ToolConvertTemperatureRequest) (*mcp.CallToolResult, error)
@@ -59,6 +141,20 @@ const (
 	ConvertTemperatureFromUnitTypeFahrenheit ConvertTemperatureFromUnitType = "fahrenheit"
 )
 
+// IsValid reports whether x is one of the possible values of ConvertTemperatureFromUnitType.
+func (x ConvertTemperatureFromUnitType) IsValid() bool {
+	switch x {
+	case ConvertTemperatureFromUnitTypeCelsius, ConvertTemperatureFromUnitTypeFahrenheit:
+		return true
+	}
+	return false
+}
+
+// String returns the value of x as a string.
+func (x ConvertTemperatureFromUnitType) String() string {
+	return string(x)
+}
+
 // ConvertTemperatureToUnitType represents possible values for to_unit
 type ConvertTemperatureToUnitType string
 
@@ -67,23 +163,48 @@ const (
 	ConvertTemperatureToUnitTypeFahrenheit ConvertTemperatureToUnitType = "fahrenheit"
 )
 
+// IsValid reports whether x is one of the possible values of ConvertTemperatureToUnitType.
+func (x ConvertTemperatureToUnitType) IsValid() bool {
+	switch x {
+	case ConvertTemperatureToUnitTypeCelsius, ConvertTemperatureToUnitTypeFahrenheit:
+		return true
+	}
+	return false
+}
+
+// String returns the value of x as a string.
+func (x ConvertTemperatureToUnitType) String() string {
+	return string(x)
+}
+
 // ToolConvertTemperatureRequest contains input parameters for the convert_temperature tool.
 type ToolConvertTemperatureRequest struct {
-	Temperature float64                        `json:"temperature"`
-	FromUnit    ConvertTemperatureFromUnitType `json:"from_unit"`
-	ToUnit      ConvertTemperatureToUnitType   `json:"to_unit"`
+	// Temperature value to convert
+	Temperature float64 `json:"temperature"`
+	// Source temperature unit
+	FromUnit ConvertTemperatureFromUnitType `json:"from_unit"`
+	// Target temperature unit
+	ToUnit ConvertTemperatureToUnitType `json:"to_unit"`
 }
 
 // ToolCalculateHumidityIndexRequest contains input parameters for the calculate_humidity_index tool.
 type ToolCalculateHumidityIndexRequest struct {
+	// Temperature in Celsius
 	Temperature float64 `json:"temperature"`
-	Humidity    float64 `json:"humidity"`
+	// Relative humidity percentage (0-100)
+	Humidity float64 `json:"humidity"`
 }
 
+// Names of the prompts.
+const (
+	PromptWeatherReportName = "weather_report"
+	PromptWeatherAlertName  = "weather_alert"
+)
+
 // PromptList contains all available prompts.
 var PromptList = []protocol.Prompt{
 	{
-		Name:        "weather_report",
+		Name:        PromptWeatherReportName,
 		Description: "Generate a weather report based on weather data",
 		Arguments: []protocol.PromptArgument{
 			{
@@ -94,11 +215,12 @@ var PromptList = []protocol.Prompt{
 			{
 				Name:        "language",
 				Description: "Report language (e.g. 'en', 'ja')",
+				Pattern:     "^[a-z]{2}$",
 			},
 		},
 	},
 	{
-		Name:        "weather_alert",
+		Name:        PromptWeatherAlertName,
 		Description: "Generate a weather alert message",
 		Arguments: []protocol.PromptArgument{
 			{
@@ -110,33 +232,44 @@ var PromptList = []protocol.Prompt{
 				Name:        "severity",
 				Description: "Alert severity (1-5)",
 				Required:    true,
+				Enum:        []string{"1", "2", "3", "4", "5"},
 			},
 		},
 	},
 }
 
+// Names of the tools.
+const (
+	ToolConvertTemperatureName     = "convert_temperature"
+	ToolCalculateHumidityIndexName = "calculate_humidity_index"
+)
+
 // JSON Schema type definitions generated from inputSchema
 var (
 	ToolConvertTemperatureInputSchema     = json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{"temperature":{"type":"number","description":"Temperature value to convert"},"from_unit":{"type":"string","enum":["celsius","fahrenheit"],"description":"Source temperature unit"},"to_unit":{"type":"string","enum":["celsius","fahrenheit"],"description":"Target temperature unit"}},"additionalProperties":false,"type":"object","required":["temperature","from_unit","to_unit"]}`)
+	ToolConvertTemperatureOutputSchema    = json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{"temperature":{"type":"number","description":"Converted temperature value"},"unit":{"type":"string","enum":["celsius","fahrenheit"],"description":"Unit of the converted temperature"}},"additionalProperties":false,"type":"object","required":["temperature","unit"]}`)
 	ToolCalculateHumidityIndexInputSchema = json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{"temperature":{"type":"number","description":"Temperature in Celsius"},"humidity":{"type":"number","description":"Relative humidity percentage (0-100)"}},"additionalProperties":false,"type":"object","required":["temperature","humidity"]}`)
 )
 
 // ToolList contains all available tools.
 var ToolList = []protocol.Tool{
 	{
-		Name:        "convert_temperature",
-		Description: "Convert temperature between Celsius and Fahrenheit",
-		InputSchema: ToolConvertTemperatureInputSchema,
+		Name:         ToolConvertTemperatureName,
+		Description:  "Convert temperature between Celsius and Fahrenheit",
+		InputSchema:  ToolConvertTemperatureInputSchema,
+		OutputSchema: ToolConvertTemperatureOutputSchema,
+		Annotations:  &protocol.ToolAnnotations{Title: "Convert Temperature", ReadOnlyHint: true},
 	},
 	{
-		Name:        "calculate_humidity_index",
+		Name:        ToolCalculateHumidityIndexName,
 		Description: "Calculate humidity index based on temperature and humidity",
 		InputSchema: ToolCalculateHumidityIndexInputSchema,
 	},
 }
 
 // NewHandler creates a new MCP handler.
-func NewHandler(promptHandler ServerPromptHandler, resourceHandler mcp.ServerResourceHandler, toolHandler ServerToolHandler, completionHandler mcp.ServerCompletionHandler) *mcp.Handler {
+// opts are applied after the generated configuration, so they can override it, e.g. by mcp.WithInstructions.
+func NewHandler(promptHandler ServerPromptHandler, resourceHandler mcp.ServerResourceHandler, toolHandler ServerToolHandler, completionHandler mcp.ServerCompletionHandler, opts ...mcp.HandlerOption) *mcp.Handler {
 	h := &mcp.Handler{}
 	h.Capabilities = protocol.ServerCapabilities{
 		Prompts: &protocol.PromptCapability{},
@@ -151,19 +284,27 @@ func NewHandler(promptHandler ServerPromptHandler, resourceHandler mcp.ServerRes
 	h.Implementation = protocol.Implementation{
 		Name:    "Weather Forecast MCP Server",
 		Version: "1.0.0",
+		Title:   "Weather Forecast",
 	}
+	h.Instructions = "Use the weather://forecast resources for \"current\" forecasts and the tools for unit conversions.\nCity names must be in English."
 	h.Prompts = PromptList
 	h.PromptHandler = protocol.ServerHandlerFunc[protocol.GetPromptRequestParams](func(ctx context.Context, method string, req protocol.GetPromptRequestParams) (any, error) {
 		switch method {
 		case "prompts/get":
 			switch req.Name {
-			case "weather_report":
+			case PromptWeatherReportName:
+				if err := protocol.ValidateRequiredPromptArguments(req.Arguments, "city"); err != nil {
+					return nil, err
+				}
 				var in PromptWeatherReportRequest
 				if err := json.Unmarshal(req.Arguments, &in); err != nil {
 					return nil, err
 				}
 				return promptHandler.HandlePromptWeatherReport(ctx, &in)
-			case "weather_alert":
+			case PromptWeatherAlertName:
+				if err := protocol.ValidateRequiredPromptArguments(req.Arguments, "alert_type", "severity"); err != nil {
+					return nil, err
+				}
 				var in PromptWeatherAlertRequest
 				if err := json.Unmarshal(req.Arguments, &in); err != nil {
 					return nil, err
@@ -189,20 +330,20 @@ func NewHandler(promptHandler ServerPromptHandler, resourceHandler mcp.ServerRes
 		switch method {
 		case "tools/call":
 			switch req.Name {
-			case "convert_temperature":
+			case ToolConvertTemperatureName:
 				var in ToolConvertTemperatureRequest
 				if err := json.Unmarshal(req.Arguments, &in); err != nil {
-					return nil, err
+					return nil, fmt.Errorf("%w: %w", protocol.ErrInvalidToolArguments, err)
 				}
 				inputSchema, _ := ToolList[idx].InputSchema.(json.RawMessage)
 				if err := protocol.ValidateByJSONSchema(string(inputSchema), in); err != nil {
 					return nil, err
 				}
 				return toolHandler.HandleToolConvertTemperature(ctx, &in)
-			case "calculate_humidity_index":
+			case ToolCalculateHumidityIndexName:
 				var in ToolCalculateHumidityIndexRequest
 				if err := json.Unmarshal(req.Arguments, &in); err != nil {
-					return nil, err
+					return nil, fmt.Errorf("%w: %w", protocol.ErrInvalidToolArguments, err)
 				}
 				inputSchema, _ := ToolList[idx].InputSchema.(json.RawMessage)
 				if err := protocol.ValidateByJSONSchema(string(inputSchema), in); err != nil {
@@ -217,5 +358,8 @@ func NewHandler(promptHandler ServerPromptHandler, resourceHandler mcp.ServerRes
 		}
 	})
 	h.CompletionHandler = completionHandler
+	for _, opt := range opts {
+		opt(h)
+	}
 	return h
 }
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	mcp "github.com/ktr0731/go-mcp"
+	"github.com/ktr0731/go-mcp/protocol"
 	"golang.org/x/exp/jsonrpc2"
 )
 
@@ -57,18 +58,8 @@ func (h *promptHandler) HandlePromptWeatherReport(ctx context.Context, req *Prom
 	return &mcp.GetPromptResult{
 		Description: "Weather report for " + city.City,
 		Messages: []mcp.PromptMessage{
-			{
-				Role: mcp.RoleUser,
-				Content: mcp.TextContent{
-					Text: fmt.Sprintf("Please provide a weather report for %s", city.City),
-				},
-			},
-			{
-				Role: mcp.RoleAssistant,
-				Content: mcp.TextContent{
-					Text: reportText,
-				},
-			},
+			mcp.NewTextPromptMessage(mcp.RoleUser, fmt.Sprintf("Please provide a weather report for %s", city.City)),
+			mcp.NewTextPromptMessage(mcp.RoleAssistant, reportText),
 		},
 	}, nil
 }
@@ -80,30 +71,20 @@ func (h *promptHandler) HandlePromptWeatherAlert(ctx context.Context, req *Promp
 	var alertText string
 	switch alertType {
 	case "rain":
-		alertText = fmt.Sprintf("WEATHER ALERT: Heavy rain warning. Severity level: %s/5. Expect heavy rainfall and possible flooding in low-lying areas. Please take necessary precautions.", severity)
+		alertText = fmt.Sprintf("WEATHER ALERT: Heavy rain warning. Severity level: %d/5. Expect heavy rainfall and possible flooding in low-lying areas. Please take necessary precautions.", severity)
 	case "snow":
-		alertText = fmt.Sprintf("WEATHER ALERT: Snow warning. Severity level: %s/5. Expect heavy snowfall and difficult road conditions. Please avoid unnecessary travel.", severity)
+		alertText = fmt.Sprintf("WEATHER ALERT: Snow warning. Severity level: %d/5. Expect heavy snowfall and difficult road conditions. Please avoid unnecessary travel.", severity)
 	case "heat":
-		alertText = fmt.Sprintf("WEATHER ALERT: Heat warning. Severity level: %s/5. Extremely high temperatures expected. Stay hydrated and avoid direct sun exposure.", severity)
+		alertText = fmt.Sprintf("WEATHER ALERT: Heat warning. Severity level: %d/5. Extremely high temperatures expected. Stay hydrated and avoid direct sun exposure.", severity)
 	default:
-		alertText = fmt.Sprintf("WEATHER ALERT: %s warning. Severity level: %s/5. Please stay informed about changing weather conditions.", alertType, severity)
+		alertText = fmt.Sprintf("WEATHER ALERT: %s warning. Severity level: %d/5. Please stay informed about changing weather conditions.", alertType, severity)
 	}
 
 	return &mcp.GetPromptResult{
 		Description: "Weather alert for " + alertType,
 		Messages: []mcp.PromptMessage{
-			{
-				Role: mcp.RoleUser,
-				Content: mcp.TextContent{
-					Text: fmt.Sprintf("Generate a weather alert for %s with severity %s", alertType, severity),
-				},
-			},
-			{
-				Role: mcp.RoleAssistant,
-				Content: mcp.TextContent{
-					Text: alertText,
-				},
-			},
+			mcp.NewTextPromptMessage(mcp.RoleUser, fmt.Sprintf("Generate a weather alert for %s with severity %d", alertType, severity)),
+			mcp.NewTextPromptMessage(mcp.RoleAssistant, alertText),
 		},
 	}, nil
 }
@@ -131,21 +112,24 @@ func (h *toolHandler) HandleToolConvertTemperature(ctx context.Context, req *Too
 	} else if fromUnit == ConvertTemperatureFromUnitTypeFahrenheit && toUnit == ConvertTemperatureToUnitTypeFahrenheit {
 		result = temperature
 	} else {
-		return nil, fmt.Errorf("unsupported conversion: %s to %s", fromUnit, toUnit)
+		return mcp.NewErrorToolResult("unsupported conversion: %s to %s", fromUnit, toUnit), nil
 	}
 
 	// Round to 2 decimal places
 	result = math.Round(result*100) / 100
 
 	resultText := fmt.Sprintf("%.2f %s = %.2f %s", temperature, fromUnit, result, toUnit)
+	structuredContent, err := json.Marshal(map[string]any{
+		"temperature": result,
+		"unit":        toUnit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal structured content: %w", err)
+	}
 
-	return &mcp.CallToolResult{
-		Content: []mcp.CallToolContent{
-			mcp.TextContent{
-				Text: resultText,
-			},
-		},
-	}, nil
+	res := mcp.NewTextToolResult(resultText)
+	res.StructuredContent = structuredContent
+	return res, nil
 }
 
 func (h *toolHandler) HandleToolCalculateHumidityIndex(ctx context.Context, req *ToolCalculateHumidityIndexRequest) (*mcp.CallToolResult, error) {
@@ -177,51 +161,29 @@ func (h *toolHandler) HandleToolCalculateHumidityIndex(ctx context.Context, req
 		comfort = "Very hot"
 	}
 
-	resultText := fmt.Sprintf("Temperature: %.1f°C, Humidity: %.1f%%\nComfort Index: %.1f (%s)", temperature, humidity, index, comfort)
-
-	return &mcp.CallToolResult{
-		Content: []mcp.CallToolContent{
-			mcp.TextContent{
-				Text: resultText,
-			},
-		},
-	}, nil
+	return mcp.NewTextToolResult(fmt.Sprintf("Temperature: %.1f°C, Humidity: %.1f%%\nComfort Index: %.1f (%s)", temperature, humidity, index, comfort)), nil
 }
 
+// resourceHandler lists the resources of the cities by the embedded ResourceListHandler.
 type resourceHandler struct {
+	ResourceListHandler
+
 	cities map[string]*CityWeather
 }
 
 var _ mcp.ServerResourceHandler = (*resourceHandler)(nil)
 
-func (h *resourceHandler) HandleResourcesList(ctx context.Context) (*mcp.ListResourcesResult, error) {
-	resources := []mcp.Resource{}
-
-	// Add resources for each city
-	for id, city := range h.cities {
-		resources = append(resources, mcp.Resource{
-			URI:         fmt.Sprintf("weather://forecast/%s", id),
-			Name:        fmt.Sprintf("%s Weather Forecast", city.City),
-			Description: fmt.Sprintf("Current weather data for %s", city.City),
-			MimeType:    "application/json",
-		})
-	}
-
-	return &mcp.ListResourcesResult{
-		Resources: resources,
-	}, nil
-}
-
 func (h *resourceHandler) HandleResourcesRead(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
-	uri := req.URI
+	return ReadResourceByTemplate(ctx, h, req)
+}
 
-	// Extract path from URI: weather://forecast/tokyo → tokyo
-	path := uri[len("weather://forecast/"):]
+var _ ServerResourceTemplateHandler = (*resourceHandler)(nil)
 
+func (h *resourceHandler) HandleResourceCityWeatherForecast(ctx context.Context, req *ResourceCityWeatherForecastRequest) (*mcp.ReadResourceResult, error) {
 	// Get weather data for the city
-	city, ok := h.cities[path]
+	city, ok := h.cities[req.City]
 	if !ok {
-		return nil, fmt.Errorf("resource not found: %s", uri)
+		return nil, fmt.Errorf("resource not found: %s", req.URI)
 	}
 
 	// Convert to JSON
@@ -233,7 +195,7 @@ func (h *resourceHandler) HandleResourcesRead(ctx context.Context, req *mcp.Read
 	return &mcp.ReadResourceResult{
 		Contents: []mcp.ResourceContent{
 			mcp.TextResourceContent{
-				URI:      uri,
+				URI:      req.URI,
 				MimeType: "application/json",
 				Text:     string(weatherJSON),
 			},
@@ -241,6 +203,14 @@ func (h *resourceHandler) HandleResourcesRead(ctx context.Context, req *mcp.Read
 	}, nil
 }
 
+func (h *resourceHandler) HandleResourceHistoricalWeatherData(ctx context.Context, req *ResourceHistoricalWeatherDataRequest) (*mcp.ReadResourceResult, error) {
+	if _, ok := h.cities[req.City]; !ok {
+		return nil, fmt.Errorf("resource not found: %s", req.URI)
+	}
+	// This example doesn't record historical data.
+	return nil, fmt.Errorf("no historical weather data of %s on %s", req.City, req.Date)
+}
+
 // translateCondition translates weather conditions to the specified language
 func translateCondition(condition, language string) string {
 	if language != "ja" {
@@ -289,6 +259,12 @@ func (h *completionHandler) HandleComplete(ctx context.Context, req *mcp.Complet
 				Values: values,
 			}, nil
 		case "language":
+			// Suggest the local language of the city first if the city is already selected.
+			if req.Context != nil && req.Context.Arguments["city"] == "tokyo" {
+				return &mcp.CompleteResult{
+					Values: []string{"ja", "en"},
+				}, nil
+			}
 			return &mcp.CompleteResult{
 				Values: []string{"en", "ja"},
 			}, nil
@@ -349,6 +325,18 @@ func Start(ctx context.Context) error {
 	resourceHandler := &resourceHandler{cities: cities}
 
 	handler := NewHandler(promptHandler, resourceHandler, toolHandler, completionHandler)
+	// Log the elapsed time of each tool call.
+	handler.Use(func(next mcp.HandleFunc) mcp.HandleFunc {
+		return func(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+			if req.Method != protocol.MethodToolsCall {
+				return next(ctx, req)
+			}
+			start := time.Now()
+			res, err := next(ctx, req)
+			mcp.Logger(ctx, "weather").Debug("tools/call finished", "elapsed", time.Since(start).String())
+			return res, err
+		}
+	})
 
 	ctx, listener, binder := mcp.NewStdioTransport(ctx, handler, nil)
 	srv, err := jsonrpc2.Serve(ctx, listener, binder)
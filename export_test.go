@@ -0,0 +1,6 @@
+package mcp
+
+// SetLastProgressToken sets the last progress token issued by c, so that tests can start the counter high.
+func SetLastProgressToken(c *Client, token int64) {
+	c.s.lastProgressToken.Store(token)
+}
@@ -0,0 +1,91 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/ktr0731/go-mcp/protocol"
+	"golang.org/x/exp/jsonrpc2"
+)
+
+// InMemoryClient is a Client connected to a server by NewInMemoryTransport.
+// Unlike a Client returned by NewInMemoryClient, the session is not initialized, so tests can drive the lifecycle by Initialize.
+type InMemoryClient struct {
+	*Client
+}
+
+// NewInMemoryTransport serves handler in memory and returns a client connected to it.
+// This is useful for testing servers without spawning a process.
+// Log notifications are sent to the client if the Logging capability is set, and discarded by the client.
+// The server is stopped when ctx is canceled or the client is closed.
+func NewInMemoryTransport(ctx context.Context, handler *Handler) (*InMemoryClient, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	listener, err := serveInMemory(ctx, handler)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	c, err := dialClient(ctx, listener.Dialer(), &ClientOptions{})
+	if err != nil {
+		cancel()
+		listener.Close()
+		return nil, err
+	}
+	c.onClose = func() {
+		cancel()
+		listener.Close()
+	}
+	return &InMemoryClient{Client: c}, nil
+}
+
+// NewInMemoryClient serves handler in memory and returns a Client connected and initialized to it.
+// Unlike NewInMemoryTransport, the client receives log notifications and progress notifications as configured by opts.
+// The server is stopped when ctx is canceled or the client is closed.
+func NewInMemoryClient(ctx context.Context, handler *Handler, opts *ClientOptions) (*Client, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	listener, err := serveInMemory(ctx, handler)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	c, err := NewClient(ctx, listener.Dialer(), opts)
+	if err != nil {
+		cancel()
+		listener.Close()
+		return nil, err
+	}
+	c.onClose = func() {
+		cancel()
+		listener.Close()
+	}
+	return c, nil
+}
+
+// serveInMemory serves handler over an in-process pipe until ctx is canceled, and returns the listener to dial it.
+func serveInMemory(ctx context.Context, handler *Handler) (jsonrpc2.Listener, error) {
+	ctx = SetLogWriterToContext(ctx, io.Discard)
+
+	listener, err := jsonrpc2.NetPipe(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a listener: %w", err)
+	}
+
+	binder := &binder{handler: handler, logToConn: handler.Capabilities.Logging != nil}
+	if _, err := jsonrpc2.Serve(ctx, listener, binder); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to serve: %w", err)
+	}
+	return listener, nil
+}
+
+// Initialize initializes the session by an initialize request with params followed by notifications/initialized.
+// The server rejects requests other than initialize and ping until the session is initialized.
+func (c *InMemoryClient) Initialize(ctx context.Context, params protocol.InitializeRequestParams) (*protocol.InitializeResult, error) {
+	if err := c.initialize(ctx, params); err != nil {
+		return nil, err
+	}
+	return c.InitializeResult(), nil
+}
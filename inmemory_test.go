@@ -0,0 +1,51 @@
+package mcp_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	mcp "github.com/ktr0731/go-mcp"
+	"github.com/ktr0731/go-mcp/protocol"
+)
+
+func TestInMemoryTransport(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			return &mcp.CallToolResult{
+				Content: []mcp.CallToolContent{mcp.TextContent{Text: "hello, " + req.Name}},
+			}, nil
+		}),
+	}
+
+	ctx := context.Background()
+	client, err := mcp.NewInMemoryTransport(ctx, h)
+	if err != nil {
+		t.Fatalf("failed to create an in-memory transport: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	if err := client.Notify(ctx, protocol.MethodNotificationsInitialized, struct{}{}); err != nil {
+		t.Fatalf("failed to send a notification: %v", err)
+	}
+
+	res, err := client.Call(ctx, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "greet"})
+	if err != nil {
+		t.Fatalf("tools/call returned an error: %v", err)
+	}
+	var got struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(res, &got); err != nil {
+		t.Fatalf("failed to decode the result: %v", err)
+	}
+	if len(got.Content) != 1 || got.Content[0].Text != "hello, greet" {
+		t.Errorf("unexpected result: %s", res)
+	}
+}
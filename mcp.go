@@ -7,39 +7,309 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"log/slog"
+	"math"
 	"os"
+	"runtime/debug"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/ktr0731/go-mcp/protocol"
 	"golang.org/x/exp/jsonrpc2"
 )
 
-var minimumLogLevel = new(slog.LevelVar)
+// ErrTooManySubscriptions is returned to the client when a subscribe request exceeds Handler.MaxSubscriptions.
+var ErrTooManySubscriptions = fmt.Errorf("%w: too many subscriptions", jsonrpc2.ErrInvalidParams)
+
+// ErrNotInitialized is returned to the client when it sends a request other than initialize and ping
+// before the session is initialized by notifications/initialized.
+//
+// See https://modelcontextprotocol.io/specification/2025-03-26/basic/lifecycle#initialization
+var ErrNotInitialized = fmt.Errorf("%w: the session is not initialized", jsonrpc2.ErrInvalidRequest)
+
+// ErrToolTimeout is returned to the client when a tool call exceeds the timeout set by Handler.SetToolTimeout.
+// It uses -32001, which is in the range reserved for implementation-defined server errors.
+var ErrToolTimeout = jsonrpc2.NewError(-32001, "tool execution timed out")
+
+// ErrRequestTimeout is returned to the client when a request exceeds the RequestTimeout of the transport,
+// e.g. StdioTransportOptions.RequestTimeout. It uses the same code as ErrToolTimeout.
+var ErrRequestTimeout = jsonrpc2.NewError(-32001, "request timed out")
 
 // Verify that Handler implements jsonrpc2.Handler interface
 var _ jsonrpc2.Handler = (*Handler)(nil)
 
 // Handler is the main handler for MCP server implementation.
 // Note that exported fields are exported for accessing by generated code. Do not access/modify them directly.
+// Use RegisteredTools, RegisteredPrompts and RegisteredResourceTemplates to introspect the registered definitions.
 type Handler struct {
 	Capabilities   protocol.ServerCapabilities
 	Implementation protocol.Implementation
+	// Instructions describe how to use the server and its features, which are sent to clients in the initialize response.
+	// Clients can use this to improve the LLM's understanding of the server, e.g. by adding it to the system prompt.
+	Instructions string
 
 	Prompts       []protocol.Prompt
 	PromptHandler serverHandler[protocol.GetPromptRequestParams]
 
 	Tools       []protocol.Tool
 	ToolHandler serverHandler[protocol.CallToolRequestParams]
+	// toolHandlers is a map of tool names to their handlers registered by AddTool.
+	toolHandlers map[string]ToolHandler
+	// toolsMu guards Tools and toolHandlers, which can be replaced by SetTools while serving.
+	toolsMu sync.RWMutex
+	// SensitiveToolArguments is a map of tool names to the names of their sensitive arguments,
+	// which are declared by `mcp:"sensitive"` tags of tool input fields.
+	// See RedactToolArguments.
+	SensitiveToolArguments map[string][]string
+	// ToolArgumentErrorsAsResults returns tool calls whose arguments can't be decoded or don't conform to the input schema
+	// as CallToolResult with IsError set and the reason as text content, so that the LLM can see the mistake and self-correct.
+	// By default, they fail with protocol errors. Errors are recognized by protocol.ErrInvalidToolArguments.
+	ToolArgumentErrorsAsResults bool
 
-	ResourceHandler     ServerResourceHandler
-	ResourceTemplates   []ResourceTemplate
-	subscribedResources sync.Map
+	ResourceHandler   ServerResourceHandler
+	ResourceTemplates []ResourceTemplate
 
 	CompletionHandler ServerCompletionHandler
 
+	// MaxSubscriptions is the maximum number of resources (including glob patterns) that a connection can subscribe to.
+	// Subscribe requests beyond the limit fail with ErrTooManySubscriptions.
+	// If this is zero, the number of subscriptions is unlimited.
+	MaxSubscriptions int
+	// MaxBinaryContentSize is the maximum size in bytes of the binary data of each BlobResourceContent, ImageContent and AudioContent
+	// in results of tools/call, prompts/get and resources/read. Binary data is base64-encoded in memory
+	// because a JSON-RPC message is marshaled as a whole before it is written, so a large blob takes memory of its encoded size.
+	// Results with content exceeding the limit fail with an error wrapping ErrContentTooLarge.
+	// If this is zero, DefaultMaxBinaryContentSize is used. If this is negative, the size is unlimited.
+	// Servers offering large blobs should let clients read them in parts by ByteRange instead.
+	MaxBinaryContentSize int64
+
+	// DisablePanicRecovery disables recovering from panics of handlers, so that a panic crashes the process.
+	// By default, a panic fails only the request with jsonrpc2.ErrInternal. See Handle.
+	DisablePanicRecovery bool
+
+	// Debug includes details of errors which are bugs of the server in error responses,
+	// e.g. why structured content of a tool result doesn't conform to the output schema of the tool.
+	// It helps development, but shouldn't be enabled in production because the details may expose internal data.
+	// The details are always sent as log notifications regardless of this, except stack traces of recovered panics,
+	// which are sent only if this is enabled and written to ErrorLog otherwise.
+	Debug bool
+
+	// LogNotificationFailurePolicy decides what to do when a log notification sent by Logger fails to be sent,
+	// e.g. because the connection is closing. By default, the notification is dropped silently.
+	// Other notifications such as NotifyResourceUpdated and ReportProgress return send errors to the caller.
+	LogNotificationFailurePolicy NotificationFailurePolicy
+	// ErrorLog is the logger for errors of the server itself, e.g. failures of log notifications.
+	// If this is nil, the standard logger of the log package is used.
+	ErrorLog *log.Logger
+
+	// SuppressLogsBeforeSetLevel suppresses log notifications until the client sets the minimum level by logging/setLevel,
+	// so that clients which aren't interested in logs don't receive them.
+	// By default, log notifications at protocol.LevelInfo or higher are sent before logging/setLevel as the spec allows.
+	SuppressLogsBeforeSetLevel bool
+
+	// PageSize is the maximum number of prompts and tools returned by a single prompts/list or tools/list request.
+	// Clients fetch the rest by the returned cursor. See Paginate for how cursors are handled.
+	// If this is zero, all prompts and tools are returned at once.
+	PageSize int
+
+	// OnInitialize is called when the server receives an initialize request, before the response is sent.
+	// ctx is canceled if the connection is closed in the middle of the handshake,
+	// so expensive per-session setup (e.g. opening a DB connection, validating credentials) can be aborted.
+	// If it returns an error, the initialize request fails with the error.
+	OnInitialize func(ctx context.Context, params *protocol.InitializeRequestParams) error
+
+	// StrictProtocolVersion configures how an initialize request with an unsupported protocol version is handled.
+	//
+	// If this is not set, the server responds with its own latest protocol version (protocol.LatestProtocolVersion)
+	// as the spec requires, and never echoes the version requested by the client. The client can then decide to disconnect
+	// if it doesn't support the version. The fallback is also reported to the client as a warning log notification
+	// (notifications/message), not to a server-side logger.
+	//
+	// If this is set, the initialize request fails with an invalid params error instead.
+	//
+	// See https://modelcontextprotocol.io/specification/2025-03-26/basic/lifecycle#version-negotiation
+	StrictProtocolVersion bool
+
+	// middlewares wrap the built-in dispatch of requests. See Use.
+	middlewares []Middleware
+
 	// cancelFuncByRequestID is a map of cancellation functions for in-flight requests.
 	cancelFuncByRequestID sync.Map
+	// toolTimeouts is a map of tool names to their execution timeouts.
+	toolTimeouts sync.Map
+	// toolContentTypes is a map of tool names to the types of content they are allowed to return.
+	toolContentTypes sync.Map
+	// sessions is a set of active sessions, used to send server-initiated notifications.
+	sessions sync.Map
+	// logLevel is the minimum level of log notifications, set by logging/setLevel.
+	logLevel slog.LevelVar
+	// logLevelSet reports whether logLevel is set by logging/setLevel.
+	logLevelSet atomic.Bool
+}
+
+// HandlerOption configures a Handler created by NewHandler.
+type HandlerOption func(*Handler)
+
+// NewHandler creates a Handler configured by opts, which are applied in order.
+// Options of features (e.g. WithTools) enable the corresponding capabilities if they aren't enabled yet,
+// so a handler doesn't need WithCapabilities unless it enables optional features such as ListChanged.
+func NewHandler(opts ...HandlerOption) *Handler {
+	h := &Handler{}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// WithCapabilities sets the capabilities of the server. It replaces capabilities enabled by preceding options.
+func WithCapabilities(capabilities protocol.ServerCapabilities) HandlerOption {
+	return func(h *Handler) { h.Capabilities = capabilities }
+}
+
+// WithImplementation sets the name and version of the server sent in the initialize response.
+func WithImplementation(impl protocol.Implementation) HandlerOption {
+	return func(h *Handler) { h.Implementation = impl }
+}
+
+// WithInstructions sets the instructions sent in the initialize response. See Handler.Instructions.
+func WithInstructions(instructions string) HandlerOption {
+	return func(h *Handler) { h.Instructions = instructions }
+}
+
+// WithPrompts sets the prompts offered by the server and the handler of prompts/get requests, and enables the Prompts capability.
+func WithPrompts(prompts []protocol.Prompt, ph serverHandler[protocol.GetPromptRequestParams]) HandlerOption {
+	return func(h *Handler) {
+		h.Prompts = prompts
+		h.PromptHandler = ph
+		if h.Capabilities.Prompts == nil {
+			h.Capabilities.Prompts = &protocol.PromptCapability{}
+		}
+	}
+}
+
+// WithTools sets the tools offered by the server and the handler of tools/call requests, and enables the Tools capability.
+// Tools handled by their own handlers can be added by AddTool after NewHandler.
+func WithTools(tools []protocol.Tool, th serverHandler[protocol.CallToolRequestParams]) HandlerOption {
+	return func(h *Handler) {
+		h.Tools = tools
+		h.ToolHandler = th
+		if h.Capabilities.Tools == nil {
+			h.Capabilities.Tools = &protocol.ToolCapability{}
+		}
+	}
+}
+
+// WithResourceHandler sets the handler of resources and the resource templates offered by the server,
+// and enables the Resources capability.
+func WithResourceHandler(rh ServerResourceHandler, templates ...ResourceTemplate) HandlerOption {
+	return func(h *Handler) {
+		h.ResourceHandler = rh
+		h.ResourceTemplates = templates
+		if h.Capabilities.Resources == nil {
+			h.Capabilities.Resources = &protocol.ResourceCapability{}
+		}
+	}
+}
+
+// WithCompletionHandler sets the handler of completion/complete requests and enables the Completions capability.
+func WithCompletionHandler(ch ServerCompletionHandler) HandlerOption {
+	return func(h *Handler) {
+		h.CompletionHandler = ch
+		if h.Capabilities.Completions == nil {
+			h.Capabilities.Completions = &protocol.CompletionsCapability{}
+		}
+	}
+}
+
+// SetToolTimeout sets the execution timeout for the tool with the given name.
+// When a call to the tool exceeds d, the context passed to the tool handler is canceled
+// and the request fails with ErrToolTimeout, or results in a CallToolResult with IsError set with ToolTimeoutAsResult.
+// The timeout replaces the RequestTimeout of the transport for calls to the tool, so it can be longer than that.
+// If d is zero or negative, the timeout for the tool is removed.
+func (h *Handler) SetToolTimeout(name string, d time.Duration, opts ...ToolTimeoutOption) {
+	if d <= 0 {
+		h.toolTimeouts.Delete(name)
+		return
+	}
+	timeout := toolTimeout{d: d}
+	for _, opt := range opts {
+		opt(&timeout)
+	}
+	h.toolTimeouts.Store(name, timeout)
+}
+
+// ToolTimeoutOption is an option of Handler.SetToolTimeout.
+type ToolTimeoutOption func(*toolTimeout)
+
+// ToolTimeoutAsResult reports a timeout of the tool as a CallToolResult with IsError set instead of ErrToolTimeout,
+// so that the LLM can see that the tool timed out. The code generated by codegen uses it for tools with Timeout.
+func ToolTimeoutAsResult() ToolTimeoutOption {
+	return func(t *toolTimeout) { t.asResult = true }
+}
+
+// toolTimeout is the execution timeout of a tool set by SetToolTimeout.
+type toolTimeout struct {
+	d        time.Duration
+	asResult bool
+}
+
+// SetToolContentTypes restricts the types of content which the tool with the given name can return, e.g. only ContentTypeText
+// for a tool whose output must not be rendered as images by clients.
+// If the tool returns content of other types, the result is not sent and the request fails with jsonrpc2.ErrInternal
+// like a result which doesn't conform to the output schema of the tool.
+// If no types are given, the restriction for the tool is removed.
+func (h *Handler) SetToolContentTypes(name string, types ...ContentType) {
+	if len(types) == 0 {
+		h.toolContentTypes.Delete(name)
+		return
+	}
+	h.toolContentTypes.Store(name, slices.Clone(types))
+}
+
+// NotificationFailurePolicy is a policy for notifications which fail to be sent.
+type NotificationFailurePolicy int
+
+const (
+	// NotificationFailureDrop drops failed notifications silently.
+	NotificationFailureDrop NotificationFailurePolicy = iota
+	// NotificationFailureLog reports failed notifications to Handler.ErrorLog.
+	NotificationFailureLog
+	// NotificationFailureClose closes the connection when a notification fails to be sent.
+	NotificationFailureClose
+)
+
+// HandleFunc handles a request and returns its result.
+// For notifications, the result is ignored.
+type HandleFunc func(ctx context.Context, req *jsonrpc2.Request) (any, error)
+
+// Middleware wraps a HandleFunc to add cross-cutting behavior around request handling, e.g. authentication, metrics or tracing.
+// A middleware can observe every request including notifications, and can short-circuit a request by not calling next.
+type Middleware func(next HandleFunc) HandleFunc
+
+// Use adds middlewares that wrap the built-in dispatch of requests.
+// Middlewares are called in the order they are added, i.e. the first middleware is the outermost one.
+// ctx passed to middlewares is the same as the one passed to handlers, so it can be used with Logger or ReportProgress.
+// Use must be called before serving.
+//
+// For example, the following middleware logs the elapsed time of tools/call:
+//
+//	h.Use(func(next mcp.HandleFunc) mcp.HandleFunc {
+//		return func(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+//			if req.Method != protocol.MethodToolsCall {
+//				return next(ctx, req)
+//			}
+//			start := time.Now()
+//			res, err := next(ctx, req)
+//			mcp.Logger(ctx, "timing").Debug("tools/call", "elapsed", time.Since(start))
+//			return res, err
+//		}
+//	})
+func (h *Handler) Use(mw ...Middleware) {
+	h.middlewares = append(h.middlewares, mw...)
 }
 
 // serverHandler is a common interface for various handlers.
@@ -48,40 +318,135 @@ type serverHandler[Req any] interface {
 }
 
 // Handle handles an incoming request.
-func (h *Handler) Handle(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+//
+// If a handler panics, the panic is recovered and the request fails with jsonrpc2.ErrInternal,
+// so that a single buggy handler doesn't take down the whole connection.
+// The panic value is included in the error message, and the stack trace is logged.
+// Set DisablePanicRecovery to let panics propagate instead.
+func (h *Handler) Handle(ctx context.Context, req *jsonrpc2.Request) (_ any, err error) {
 	cctx, cancel := context.WithCancel(ctx)
 	id := fmt.Sprintf("%v", req.ID.Raw())
 	h.cancelFuncByRequestID.Store(id, cancel)
 	defer h.cancelFuncByRequestID.Delete(id)
 
+	// Cancel the request if the connection is closed while it is being handled.
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok {
+		stop := context.AfterFunc(s.ctx, cancel)
+		defer stop()
+	}
+
+	cctx = context.WithValue(cctx, handlerKey{}, h)
+	cctx = context.WithValue(cctx, logLevelKey{}, handlerLogLevel{h: h})
+	if token := progressTokenFromRequest(req); token != nil {
+		cctx = context.WithValue(cctx, progressTokenKey{}, token)
+	}
+	if meta := metaFromRequest(req); meta != nil {
+		cctx = context.WithValue(cctx, metaKey{}, meta)
+	}
+
+	defer func() {
+		if h.DisablePanicRecovery {
+			return
+		}
+		if r := recover(); r != nil {
+			// The stack trace is sent to the client only in debug mode because it may expose internal details.
+			stack := debug.Stack()
+			if h.Debug {
+				Logger(cctx, "go-mcp").Error("recovered from a panic", "method", req.Method, "panic", r, "stack", string(stack))
+			} else {
+				Logger(cctx, "go-mcp").Error("recovered from a panic", "method", req.Method, "panic", r)
+				h.errorLog().Printf("mcp: recovered from a panic while handling %s: %v\n%s", req.Method, r, stack)
+			}
+			err = fmt.Errorf("%w: panic while handling %s: %v", jsonrpc2.ErrInternal, req.Method, r)
+		}
+	}()
+
+	handle := HandleFunc(h.handle)
+	for _, mw := range slices.Backward(h.middlewares) {
+		handle = mw(handle)
+	}
+	res, err := handle(cctx, req)
+	if err != nil {
+		return res, err
+	}
+	return limitBinaryContent(res, h.maxBinaryContentSize()), nil
+}
+
+// maxBinaryContentSize returns the limit of binary content by h.MaxBinaryContentSize, or zero if it is unlimited.
+func (h *Handler) maxBinaryContentSize() int64 {
+	switch {
+	case h.MaxBinaryContentSize == 0:
+		return DefaultMaxBinaryContentSize
+	case h.MaxBinaryContentSize < 0:
+		return 0
+	}
+	return h.MaxBinaryContentSize
+}
+
+// handle dispatches a request to the built-in handler of the method.
+func (h *Handler) handle(cctx context.Context, req *jsonrpc2.Request) (any, error) {
 	logger := Logger(cctx, "go-mcp")
 
+	// The lifecycle is enforced per connection. Requests handled without a transport (e.g. by calling Handle directly) have no session.
+	if s, ok := cctx.Value(sessionKey{}).(*session); ok && req.IsCall() && !s.initialized.Load() {
+		if req.Method != protocol.MethodInitialize && req.Method != protocol.MethodPing {
+			return nil, fmt.Errorf("%w: %s", ErrNotInitialized, req.Method)
+		}
+	}
+
 	switch {
 	case req.Method == protocol.MethodPing:
 		return struct{}{}, nil
 	// Lifecycle: https://spec.modelcontextprotocol.io/specification/2025-03-26/basic/lifecycle/
 	case req.Method == protocol.MethodInitialize:
 		var params protocol.InitializeRequestParams
-		if err := json.Unmarshal(req.Params, &params); err != nil {
+		if err := JSONUnmarshal(req.Params, &params); err != nil {
 			return nil, jsonrpc2.ErrInvalidParams
 		}
+		params.Raw = req.Params
 		protocolVersion := params.ProtocolVersion
 		if _, ok := protocol.AvailableProtocolVersions[protocolVersion]; !ok {
+			if h.StrictProtocolVersion {
+				return nil, fmt.Errorf("%w: unsupported protocol version: %q", jsonrpc2.ErrInvalidParams, params.ProtocolVersion)
+			}
 			protocolVersion = protocol.LatestProtocolVersion
+			logger.Warn("unsupported protocol version, falling back to the latest version", "requested", params.ProtocolVersion, "served", protocolVersion)
+		}
+
+		if h.OnInitialize != nil {
+			if err := h.OnInitialize(cctx, &params); err != nil {
+				return nil, fmt.Errorf("failed to handle %s: %w", req.Method, err)
+			}
+		}
+		if s, ok := cctx.Value(sessionKey{}).(*session); ok {
+			s.initializeParams.Store(&params)
+			s.protocolVersion.Store(&protocolVersion)
 		}
 
 		return &protocol.InitializeResult{
 			ProtocolVersion: protocolVersion,
 			Capabilities:    h.Capabilities,
 			ServerInfo:      h.Implementation,
+			Instructions:    h.Instructions,
 		}, nil
 	case req.Method == protocol.MethodNotificationsInitialized:
+		if s, ok := cctx.Value(sessionKey{}).(*session); ok {
+			s.initialized.Store(true)
+		}
 		return nil, nil
 	case req.Method == protocol.MethodPromptsList:
-		return &listPromptsResult{Prompts: h.Prompts}, nil
+		cursor, err := nextCursorFromRequest(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get next cursor: %w", err)
+		}
+		prompts, next, err := Paginate(h.Prompts, cursor, h.PageSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to paginate prompts: %w", err)
+		}
+		return &listPromptsResult{Prompts: prompts, NextCursor: next}, nil
 	case req.Method == protocol.MethodPromptsGet:
 		var params protocol.GetPromptRequestParams
-		if err := json.Unmarshal(req.Params, &params); err != nil {
+		if err := JSONUnmarshal(req.Params, &params); err != nil {
 			logger.Error("failed to unmarshal params", "error", err)
 			return nil, jsonrpc2.ErrInvalidParams
 		}
@@ -102,6 +467,14 @@ func (h *Handler) Handle(ctx context.Context, req *jsonrpc2.Request) (any, error
 		}
 		cctx = context.WithValue(cctx, nextCursorKey{}, cursor)
 
+		if streamer, ok := h.ResourceHandler.(ServerResourceStreamer); ok {
+			resources, next, err := paginateSeq(streamer.HandleResourcesListStream(cctx), cursor, h.PageSize)
+			if err != nil {
+				return nil, fmt.Errorf("failed to handle %s: %w", req.Method, err)
+			}
+			return &ListResourcesResult{Resources: resources, NextCursor: next}, nil
+		}
+
 		res, err := h.ResourceHandler.HandleResourcesList(cctx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to handle %s: %w", req.Method, err)
@@ -113,10 +486,19 @@ func (h *Handler) Handle(ctx context.Context, req *jsonrpc2.Request) (any, error
 			return nil, jsonrpc2.ErrMethodNotFound
 		}
 		var params ReadResourceRequest
-		if err := json.Unmarshal(req.Params, &params); err != nil {
+		if err := JSONUnmarshal(req.Params, &params); err != nil {
 			logger.Error("failed to unmarshal params", "error", err)
 			return nil, jsonrpc2.ErrInvalidParams
 		}
+		byteRange, err := byteRangeFromRequest(req)
+		if err != nil {
+			logger.Error("failed to get byte range", "error", err)
+			return nil, fmt.Errorf("%w: %w", jsonrpc2.ErrInvalidParams, err)
+		}
+		if byteRange != nil {
+			cctx = context.WithValue(cctx, byteRangeKey{}, byteRange)
+		}
+
 		res, err := h.ResourceHandler.HandleResourcesRead(cctx, &params)
 		if err != nil {
 			return nil, fmt.Errorf("failed to handle %s: %w", req.Method, err)
@@ -133,20 +515,31 @@ func (h *Handler) Handle(ctx context.Context, req *jsonrpc2.Request) (any, error
 		}, nil
 	case req.Method == protocol.MethodResourcesSubscribe:
 		var params subscribeResourceRequest
-		if err := json.Unmarshal(req.Params, &params); err != nil {
+		if err := JSONUnmarshal(req.Params, &params); err != nil {
 			logger.Error("failed to unmarshal params", "error", err)
 			return nil, jsonrpc2.ErrInvalidParams
 		}
-		h.subscribedResources.Store(params.URI, struct{}{})
+		// Subscriptions are recorded per connection and released when it is closed.
+		// Requests handled without a transport have no connection to notify, so nothing is recorded.
+		uris := subscriptionURIs(params.URI, params.URIs)
+		if s, ok := cctx.Value(sessionKey{}).(*session); ok {
+			if err := s.subscribe(uris, h.MaxSubscriptions); err != nil {
+				logger.Error("failed to subscribe", "error", err)
+				return nil, err
+			}
+		}
 
 		return struct{}{}, nil
 	case req.Method == protocol.MethodResourcesUnsubscribe:
 		var params unsubscribeResourceRequest
-		if err := json.Unmarshal(req.Params, &params); err != nil {
+		if err := JSONUnmarshal(req.Params, &params); err != nil {
 			logger.Error("failed to unmarshal params", "error", err)
 			return nil, jsonrpc2.ErrInvalidParams
 		}
-		h.subscribedResources.Delete(params.URI)
+		uris := subscriptionURIs(params.URI, params.URIs)
+		if s, ok := cctx.Value(sessionKey{}).(*session); ok {
+			s.unsubscribe(uris)
+		}
 
 		return struct{}{}, nil
 	case req.Method == protocol.MethodToolsList:
@@ -154,32 +547,89 @@ func (h *Handler) Handle(ctx context.Context, req *jsonrpc2.Request) (any, error
 			logger.Error("tools/list is not supported")
 			return nil, jsonrpc2.ErrMethodNotFound
 		}
+		cursor, err := nextCursorFromRequest(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get next cursor: %w", err)
+		}
+		h.toolsMu.RLock()
+		tools := h.Tools
+		h.toolsMu.RUnlock()
+		tools, next, err := Paginate(tools, cursor, h.PageSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to paginate tools: %w", err)
+		}
+		if negotiatedProtocolVersion(cctx) < protocol.ProtocolVersion20250618 {
+			// Output schemas were introduced in 2025-06-18.
+			tools = slices.Clone(tools)
+			for i := range tools {
+				tools[i].OutputSchema = nil
+			}
+		}
 		return &listToolsResult{
-			Tools: h.Tools,
+			Tools:      tools,
+			NextCursor: next,
 		}, nil
 	case req.Method == protocol.MethodToolsCall:
 		var params protocol.CallToolRequestParams
-		if err := json.Unmarshal(req.Params, &params); err != nil {
+		if err := JSONUnmarshal(req.Params, &params); err != nil {
 			logger.Error("failed to unmarshal params", "error", err)
 			return nil, jsonrpc2.ErrInvalidParams
 		}
 
-		res, err := h.ToolHandler.Handle(cctx, req.Method, params)
+		if tool, ok := h.tool(params.Name); ok && tool.Annotations != nil && tool.Annotations.Deprecated != "" {
+			logger.Warn("deprecated tool is called", "name", params.Name, "message", tool.Annotations.Deprecated)
+		}
+
+		tctx := cctx
+		var timeout toolTimeout
+		if v, ok := h.toolTimeouts.Load(params.Name); ok {
+			timeout = v.(toolTimeout)
+			var cancel context.CancelFunc
+			tctx, cancel = context.WithTimeout(cctx, timeout.d)
+			defer cancel()
+		}
+
+		res, err := h.callTool(tctx, req.Method, params)
+		// Report the timeout only if the request itself is still alive (i.e. not canceled by the client).
+		if errors.Is(tctx.Err(), context.DeadlineExceeded) && cctx.Err() == nil {
+			if !timeout.asResult {
+				return nil, fmt.Errorf("%w: %s exceeded %s", ErrToolTimeout, params.Name, timeout.d)
+			}
+			res, err = NewErrorToolResult("tool execution timed out after %s", timeout.d), nil
+		}
+		if err != nil && h.ToolArgumentErrorsAsResults && errors.Is(err, protocol.ErrInvalidToolArguments) {
+			res, err = NewErrorToolResult("%s", err), nil
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to handle %s: %w", req.Method, err)
 		}
+		err = h.validateStructuredContent(params.Name, res)
+		if err == nil {
+			err = h.validateContentTypes(params.Name, res)
+		}
+		if err != nil {
+			logger.Error("invalid tool result", "name", params.Name, "error", err)
+			if h.Debug {
+				return nil, fmt.Errorf("%w: %w", jsonrpc2.ErrInternal, err)
+			}
+			return nil, fmt.Errorf("%w: tool %s returned an invalid result", jsonrpc2.ErrInternal, params.Name)
+		}
+		if negotiatedProtocolVersion(cctx) < protocol.ProtocolVersion20250618 {
+			res = toolResultForLegacyClient(res)
+		}
 		return res, nil
 	case req.Method == protocol.MethodLoggingSetLevel:
 		var params protocol.LoggingSetLevelRequestParams
-		if err := json.Unmarshal(req.Params, &params); err != nil {
+		if err := JSONUnmarshal(req.Params, &params); err != nil {
 			logger.Error("failed to unmarshal params", "error", err)
 			return nil, jsonrpc2.ErrInvalidParams
 		}
-		minimumLogLevel.Set(slog.Level(params.Level))
+		h.logLevel.Set(slog.Level(params.Level))
+		h.logLevelSet.Store(true)
 		return struct{}{}, nil
 	case req.Method == protocol.MethodNotificationsCancelled:
 		var params protocol.NotificationsCancelledRequestParams
-		if err := json.Unmarshal(req.Params, &params); err != nil {
+		if err := JSONUnmarshal(req.Params, &params); err != nil {
 			logger.Error("failed to unmarshal params", "error", err)
 			return nil, jsonrpc2.ErrInvalidParams
 		}
@@ -192,7 +642,7 @@ func (h *Handler) Handle(ctx context.Context, req *jsonrpc2.Request) (any, error
 		return nil, nil
 	case req.Method == protocol.MethodCompletionComplete:
 		var params CompleteRequestParams
-		if err := json.Unmarshal(req.Params, &params); err != nil {
+		if err := JSONUnmarshal(req.Params, &params); err != nil {
 			logger.Error("failed to unmarshal params", "error", err)
 			return nil, jsonrpc2.ErrInvalidParams
 		}
@@ -200,14 +650,44 @@ func (h *Handler) Handle(ctx context.Context, req *jsonrpc2.Request) (any, error
 			logger.Error("completion/complete is not supported")
 			return nil, jsonrpc2.ErrMethodNotFound
 		}
-		res, err := h.CompletionHandler.HandleComplete(cctx, &params)
-		if err != nil {
-			return nil, fmt.Errorf("failed to handle %s: %w", req.Method, err)
+		res := &CompleteResult{Values: []string{}}
+		if h.isCompletable(params.Ref, params.Argument.Name) {
+			var err error
+			res, err = h.CompletionHandler.HandleComplete(cctx, &params)
+			if err != nil {
+				return nil, fmt.Errorf("failed to handle %s: %w", req.Method, err)
+			}
+			// A nil result means no completion values.
+			if res == nil {
+				res = &CompleteResult{Values: []string{}}
+			}
+		}
+		items := res.Items
+		if items == nil {
+			items = make([]CompletionItem, len(res.Values))
+			for i, v := range res.Values {
+				items[i] = CompletionItem{Value: v}
+			}
+		}
+		completion := completeResult{Total: res.Total, HasMore: res.HasMore}
+		if len(items) > maxCompletionValues {
+			completion.Total = max(res.Total, len(items))
+			completion.HasMore = true
+			items = items[:maxCompletionValues]
+		}
+		if res.Items != nil && supportsCompletionItems(cctx) {
+			completion.Values = items
+		} else {
+			values := make([]string, len(items))
+			for i, item := range items {
+				values[i] = item.Value
+			}
+			completion.Values = values
 		}
 		return struct {
-			Completion *CompleteResult `json:"completion"`
+			Completion completeResult `json:"completion"`
 		}{
-			Completion: res,
+			Completion: completion,
 		}, nil
 	default:
 		logger.Error("unknown method", "method", req.Method)
@@ -215,12 +695,345 @@ func (h *Handler) Handle(ctx context.Context, req *jsonrpc2.Request) (any, error
 	}
 }
 
-// IsSubscribed checks if the given resource is subscribed.
-func (h *Handler) IsSubscribed(uri string) bool {
-	_, ok := h.subscribedResources.Load(uri)
+// callTool calls the handler of the tool registered by AddTool, or ToolHandler if there is no such handler.
+func (h *Handler) callTool(ctx context.Context, method string, params protocol.CallToolRequestParams) (any, error) {
+	h.toolsMu.RLock()
+	th, ok := h.toolHandlers[params.Name]
+	h.toolsMu.RUnlock()
+	if ok {
+		return th.HandleTool(ctx, params)
+	}
+	if h.ToolHandler == nil {
+		return nil, fmt.Errorf("%w: tool not found: %s", jsonrpc2.ErrInvalidParams, params.Name)
+	}
+	return h.ToolHandler.Handle(ctx, method, params)
+}
+
+// tool returns the tool with the given name.
+func (h *Handler) tool(name string) (protocol.Tool, bool) {
+	h.toolsMu.RLock()
+	defer h.toolsMu.RUnlock()
+
+	idx := slices.IndexFunc(h.Tools, func(t protocol.Tool) bool { return t.Name == name })
+	if idx == -1 {
+		return protocol.Tool{}, false
+	}
+	return h.Tools[idx], true
+}
+
+// validateStructuredContent validates the structured content of the result of the tool against its output schema.
+// Results of tools without an output schema and error results are not validated.
+func (h *Handler) validateStructuredContent(name string, res any) error {
+	result := toolResult(res)
+	if result == nil || result.IsError {
+		return nil
+	}
+
+	tool, ok := h.tool(name)
+	if !ok || tool.OutputSchema == nil {
+		return nil
+	}
+	outputSchema := tool.OutputSchema
+
+	if len(result.StructuredContent) == 0 {
+		return fmt.Errorf("tool %s has an output schema, but the result has no structured content", name)
+	}
+	schema, ok := outputSchema.(json.RawMessage)
+	if !ok {
+		b, err := JSONMarshal(outputSchema)
+		if err != nil {
+			return fmt.Errorf("failed to marshal the output schema of tool %s: %w", name, err)
+		}
+		schema = b
+	}
+	return protocol.ValidateStructuredContent(string(schema), result.StructuredContent)
+}
+
+// validateContentTypes validates that the content of a tool result is of the types which the tool is allowed to return.
+// See SetToolContentTypes.
+func (h *Handler) validateContentTypes(name string, res any) error {
+	result := toolResult(res)
+	if result == nil {
+		return nil
+	}
+	v, ok := h.toolContentTypes.Load(name)
+	if !ok {
+		return nil
+	}
+	types := v.([]ContentType)
+	for i, c := range result.Content {
+		if typ := contentType(c); !slices.Contains(types, typ) {
+			return fmt.Errorf("content %d of tool %s is %s, which is not one of the allowed types %v", i, name, typ, types)
+		}
+	}
+	return nil
+}
+
+// toolResultForLegacyClient converts a tool result for clients which don't support protocol version 2025-06-18.
+// As the spec recommends, structured content is sent as text content instead if the result has no content.
+// Resource links are sent as text content of their URIs.
+func toolResultForLegacyClient(res any) any {
+	result := toolResult(res)
+	if result == nil {
+		return res
+	}
+	hasLink := slices.ContainsFunc(result.Content, func(c CallToolContent) bool { return contentType(c) == ContentTypeResourceLink })
+	if len(result.StructuredContent) == 0 && !hasLink {
+		return res
+	}
+
+	r := *result
+	r.Content = make([]CallToolContent, len(result.Content))
+	for i, c := range result.Content {
+		switch l := c.(type) {
+		case ResourceLink:
+			c = TextContent{Text: l.URI, Annotations: l.Annotations}
+		case *ResourceLink:
+			c = TextContent{Text: l.URI, Annotations: l.Annotations}
+		}
+		r.Content[i] = c
+	}
+	if len(r.Content) == 0 && len(r.StructuredContent) != 0 {
+		r.Content = []CallToolContent{TextContent{Text: string(r.StructuredContent)}}
+	}
+	r.StructuredContent = nil
+	return &r
+}
+
+// toolResult returns the result of a tool handler as a *CallToolResult, or nil if it isn't one.
+func toolResult(res any) *CallToolResult {
+	switch r := res.(type) {
+	case *CallToolResult:
+		return r
+	case CallToolResult:
+		return &r
+	default:
+		return nil
+	}
+}
+
+// completeResult is CompleteResult sent to clients, whose values are either strings or CompletionItems.
+type completeResult struct {
+	Values  any  `json:"values"`
+	Total   int  `json:"total,omitzero"`
+	HasMore bool `json:"hasMore,omitzero"`
+}
+
+// supportsCompletionItems reports whether the client of ctx declared the experimental capability CompletionItemsCapability.
+func supportsCompletionItems(ctx context.Context) bool {
+	params, ok := InitializeParams(ctx)
+	if !ok {
+		return false
+	}
+	_, ok = params.Capabilities.Experimental[CompletionItemsCapability]
 	return ok
 }
 
+// maxCompletionValues is the maximum number of values of a completion result allowed by the spec.
+const maxCompletionValues = 100
+
+// isCompletable reports whether the argument of the reference can be completed.
+// Only resource templates which declare CompletableVariables restrict completable arguments.
+func (h *Handler) isCompletable(ref Reference, argName string) bool {
+	if ref.Type != CompletionReferenceTypeResource {
+		return true
+	}
+	uri := ref.URI
+	if uri == "" {
+		uri = ref.Name
+	}
+	for _, tmpl := range h.ResourceTemplates {
+		if tmpl.URITemplate == uri && tmpl.CompletableVariables != nil {
+			return slices.Contains(tmpl.CompletableVariables, argName)
+		}
+	}
+	return true
+}
+
+// IsSubscribed checks if the given resource is subscribed by any active connection.
+// Subscriptions of a connection are released when it is closed.
+//
+// In addition to the spec, resources/subscribe and resources/unsubscribe accept the following extensions,
+// which servers can advertise by the Experimental capability:
+//   - "uris": a list of additional URIs to (un)subscribe at once.
+//   - Glob patterns, e.g. "weather://forecast/*", which subscribe to all matching resources.
+//     "*" matches any sequence of characters except "/". A pattern is unsubscribed by the same pattern.
+func (h *Handler) IsSubscribed(uri string) bool {
+	var subscribed bool
+	h.sessions.Range(func(k, _ any) bool {
+		subscribed = k.(*session).isSubscribed(uri)
+		return !subscribed
+	})
+	return subscribed
+}
+
+// subscriptionURIs returns uri and uris of a resources/(un)subscribe request, skipping empty ones
+// so that a request with only "uris" doesn't (un)subscribe to "".
+func subscriptionURIs(uri string, uris []string) []string {
+	return slices.DeleteFunc(append([]string{uri}, uris...), func(uri string) bool { return uri == "" })
+}
+
+// matchURIPattern reports whether uri matches pattern, where "*" matches any sequence of characters except "/".
+// Unlike path.Match, other characters such as "?" are matched literally because they are common in URIs.
+func matchURIPattern(pattern, uri string) bool {
+	prefix, rest, ok := strings.Cut(pattern, "*")
+	if !ok {
+		return pattern == uri
+	}
+	if !strings.HasPrefix(uri, prefix) {
+		return false
+	}
+	uri = uri[len(prefix):]
+	// Try every possible length of the wildcard, which can't contain "/".
+	for i := 0; i <= len(uri); i++ {
+		if matchURIPattern(rest, uri[i:]) {
+			return true
+		}
+		if i < len(uri) && uri[i] == '/' {
+			break
+		}
+	}
+	return false
+}
+
+// NotifyResourceUpdated notifies clients that the resource with the given URI has been updated.
+// Only connections which subscribe to the resource (directly or by a glob pattern) are notified.
+//
+// See https://modelcontextprotocol.io/specification/2025-03-26/server/resources#subscriptions
+func (h *Handler) NotifyResourceUpdated(ctx context.Context, uri string) error {
+	method := protocol.MethodNotificationsResourcesUpdated
+	var errs []error
+	h.sessions.Range(func(k, _ any) bool {
+		s := k.(*session)
+		if !s.isSubscribed(uri) {
+			return true
+		}
+		if err := s.conn.Notify(ctx, method, &resourceUpdatedNotificationParams{URI: uri}); err != nil {
+			errs = append(errs, fmt.Errorf("failed to send %s: %w", method, err))
+		}
+		return true
+	})
+	return errors.Join(errs...)
+}
+
+// RedactToolArguments returns the arguments of a tool call with sensitive arguments replaced by "[REDACTED]".
+// Use this instead of the raw arguments when logging tool calls, so that secrets such as API keys are not logged.
+// If the arguments are not a valid JSON object, it returns nil.
+func (h *Handler) RedactToolArguments(params protocol.CallToolRequestParams) json.RawMessage {
+	sensitive := h.SensitiveToolArguments[params.Name]
+	if len(params.Arguments) == 0 || len(sensitive) == 0 {
+		return params.Arguments
+	}
+
+	var args map[string]json.RawMessage
+	if err := JSONUnmarshal(params.Arguments, &args); err != nil {
+		return nil
+	}
+	for _, name := range sensitive {
+		if _, ok := args[name]; ok {
+			args[name] = json.RawMessage(`"[REDACTED]"`)
+		}
+	}
+	b, err := JSONMarshal(args)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// RegisteredTools returns a copy of the list of tools offered by the server.
+func (h *Handler) RegisteredTools() []protocol.Tool {
+	h.toolsMu.RLock()
+	defer h.toolsMu.RUnlock()
+	return slices.Clone(h.Tools)
+}
+
+// RegisteredPrompts returns a copy of the list of prompts offered by the server.
+func (h *Handler) RegisteredPrompts() []protocol.Prompt {
+	return slices.Clone(h.Prompts)
+}
+
+// RegisteredResourceTemplates returns a copy of the list of resource templates offered by the server.
+func (h *Handler) RegisteredResourceTemplates() []ResourceTemplate {
+	return slices.Clone(h.ResourceTemplates)
+}
+
+// SetTools replaces the list of tools offered by the server.
+// If the Tools capability enables ListChanged, notifications/tools/list_changed is sent to clients.
+// Note that ToolHandler must be able to handle calls to the new tools.
+//
+// See https://modelcontextprotocol.io/specification/2025-03-26/server/tools#list-changed-notification
+func (h *Handler) SetTools(ctx context.Context, tools []protocol.Tool) error {
+	h.toolsMu.Lock()
+	h.Tools = tools
+	h.toolsMu.Unlock()
+
+	return h.notifyToolsListChanged(ctx)
+}
+
+// notifyToolsListChanged sends notifications/tools/list_changed if the Tools capability enables ListChanged.
+func (h *Handler) notifyToolsListChanged(ctx context.Context) error {
+	if h.Capabilities.Tools == nil || !h.Capabilities.Tools.ListChanged {
+		return nil
+	}
+	return h.notify(ctx, protocol.MethodNotificationsToolsListChanged, struct{}{})
+}
+
+// NotifyPromptsListChanged notifies clients that the list of prompts has changed.
+// It does nothing if the Prompts capability doesn't enable ListChanged.
+//
+// See https://modelcontextprotocol.io/specification/2025-03-26/server/prompts#list-changed-notification
+func (h *Handler) NotifyPromptsListChanged(ctx context.Context) error {
+	if h.Capabilities.Prompts == nil || !h.Capabilities.Prompts.ListChanged {
+		return nil
+	}
+	return h.notify(ctx, protocol.MethodNotificationsPromptsListChanged, struct{}{})
+}
+
+// NotifyResourcesListChanged notifies clients that the list of resources has changed.
+// It does nothing if the Resources capability doesn't enable ListChanged.
+//
+// See https://modelcontextprotocol.io/specification/2025-03-26/server/resources#list-changed-notification
+func (h *Handler) NotifyResourcesListChanged(ctx context.Context) error {
+	if h.Capabilities.Resources == nil || !h.Capabilities.Resources.ListChanged {
+		return nil
+	}
+	return h.notify(ctx, protocol.MethodNotificationsResourcesListChanged, struct{}{})
+}
+
+// errorLog returns h.ErrorLog, or the standard logger if it is nil.
+func (h *Handler) errorLog() *log.Logger {
+	if h.ErrorLog == nil {
+		return log.Default()
+	}
+	return h.ErrorLog
+}
+
+// handleLogNotificationFailure handles err of sending a log notification according to h.LogNotificationFailurePolicy.
+func (h *Handler) handleLogNotificationFailure(ctx context.Context, err error) {
+	switch h.LogNotificationFailurePolicy {
+	case NotificationFailureLog:
+		h.errorLog().Printf("mcp: failed to send a log notification: %v", err)
+	case NotificationFailureClose:
+		if s, ok := ctx.Value(sessionKey{}).(*session); ok {
+			// Close waits for in-flight requests including the one logging, so it must not block here.
+			go s.conn.Close()
+		}
+	}
+}
+
+// notify sends a notification to all active connections.
+func (h *Handler) notify(ctx context.Context, method string, params any) error {
+	var errs []error
+	h.sessions.Range(func(k, _ any) bool {
+		if err := k.(*session).conn.Notify(ctx, method, params); err != nil {
+			errs = append(errs, fmt.Errorf("failed to send %s: %w", method, err))
+		}
+		return true
+	})
+	return errors.Join(errs...)
+}
+
 type stdio struct {
 	in  io.ReadCloser
 	out io.WriteCloser
@@ -245,6 +1058,8 @@ type stdioCloser struct {
 	close func() error
 }
 
+func (c *stdioCloser) Close() error { return c.close() }
+
 func (l *stdioListener) Accept(ctx context.Context) (io.ReadWriteCloser, error) {
 	select {
 	case <-ctx.Done():
@@ -262,19 +1077,68 @@ func (l *stdioListener) Dialer() jsonrpc2.Dialer { return &stdioDialer{stdio: l.
 
 type framer struct {
 	jsonrpc2.Framer
+
+	// onClose is called when the underlying stream can no longer be read.
+	onClose func()
+	// onOpen is called with the underlying stream before the connection starts reading.
+	onOpen func(rwc io.Reader)
+	// onRead is called with each message in the order of arrival. If it returns true, the message is consumed
+	// and not delivered to the connection.
+	onRead func(msg jsonrpc2.Message) bool
+	// contentLength makes the framer delimit messages by Content-Length headers instead of newlines.
+	// The embedded Framer must be jsonrpc2.HeaderFramer to read such messages.
+	contentLength bool
+}
+
+func (f *framer) Reader(r io.Reader) jsonrpc2.Reader {
+	if f.onOpen != nil {
+		f.onOpen(r)
+	}
+	reader := f.Framer.Reader(r)
+	return &framerReader{Reader: reader, onClose: f.onClose, onRead: f.onRead}
+}
+
+type framerReader struct {
+	jsonrpc2.Reader
+	onClose func()
+	onRead  func(msg jsonrpc2.Message) bool
+}
+
+func (r *framerReader) Read(ctx context.Context) (jsonrpc2.Message, int64, error) {
+	for {
+		msg, n, err := r.Reader.Read(ctx)
+		if err != nil && r.onClose != nil {
+			// jsonrpc2.Connection stops reading on any read error, so the connection is closed.
+			r.onClose()
+		}
+		if err == nil && r.onRead != nil && r.onRead(msg) {
+			continue
+		}
+		return msg, n, err
+	}
 }
 
 func (f *framer) Writer(rw io.Writer) jsonrpc2.Writer {
 	writer := f.Framer.Writer(rw)
-	return &framerWriter{Writer: writer, rw: rw}
+	return &framerWriter{Writer: writer, rw: rw, contentLength: f.contentLength}
 }
 
 type framerWriter struct {
 	jsonrpc2.Writer
-	rw io.Writer
+	rw            io.Writer
+	contentLength bool
 }
 
 func (w *framerWriter) Write(ctx context.Context, msg jsonrpc2.Message) (int64, error) {
+	if w.contentLength {
+		data, err := jsonrpc2.EncodeMessage(msg)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode message: %w", err)
+		}
+		n, err := writeWithContentLength(w.rw, data)
+		return int64(n), err
+	}
+
 	n, err := w.Writer.Write(ctx, msg)
 	if err != nil {
 		return 0, err
@@ -286,20 +1150,196 @@ func (w *framerWriter) Write(ctx context.Context, msg jsonrpc2.Message) (int64,
 	return n, nil
 }
 
+// writeWithContentLength writes data with a Content-Length header in a single Write,
+// so that it isn't interleaved with other messages written concurrently (e.g. log notifications).
+func writeWithContentLength(w io.Writer, data []byte) (int, error) {
+	msg := fmt.Appendf(nil, "Content-Length: %d\r\n\r\n", len(data))
+	return w.Write(append(msg, data...))
+}
+
+// contentLengthWriter is an io.Writer that writes each message with a Content-Length header.
+// Each call of Write must contain a whole message.
+type contentLengthWriter struct {
+	w io.Writer
+}
+
+func (c *contentLengthWriter) Write(p []byte) (int, error) {
+	if _, err := writeWithContentLength(c.w, bytes.TrimSuffix(p, []byte("\n"))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
 // binder is an implementation of jsonrpc2.Binder
 type binder struct {
-	handler   jsonrpc2.Handler
+	handler   *Handler
 	preempter jsonrpc2.Preempter
+
+	// logToConn sends log notifications to the connection itself instead of the log writer of the transport.
+	// This is used by transports that have a stream per connection (e.g. SSE).
+	logToConn bool
+	// contentLength makes connections delimit messages by Content-Length headers instead of newlines.
+	contentLength bool
+	// requestTimeout is the deadline of each request. If this is zero, requests have no deadline.
+	requestTimeout time.Duration
 }
 
 func (b *binder) Bind(ctx context.Context, conn *jsonrpc2.Connection) (jsonrpc2.ConnectionOptions, error) {
+	sctx, cancel := context.WithCancel(ctx)
+	s := &session{ctx: sctx, conn: conn}
+	b.handler.sessions.Store(s, struct{}{})
+
+	f := &framer{
+		Framer: jsonrpc2.RawFramer(),
+		onClose: func() {
+			cancel()
+			b.handler.sessions.Delete(s)
+			s.unsubscribeAll()
+		},
+		onRead:        s.handleProgress,
+		contentLength: b.contentLength,
+	}
+	if b.contentLength {
+		f.Framer = jsonrpc2.HeaderFramer()
+	}
+	if b.logToConn {
+		f.onOpen = func(rwc io.Reader) {
+			if w, ok := rwc.(io.Writer); ok {
+				s.logWriter = w
+			}
+		}
+	}
+
 	return jsonrpc2.ConnectionOptions{
-		Framer:    &framer{Framer: jsonrpc2.RawFramer()},
+		Framer:    f,
 		Preempter: b.preempter,
-		Handler:   b.handler,
+		Handler: jsonrpc2.HandlerFunc(func(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+			ctx = context.WithValue(ctx, sessionKey{}, s)
+			if s.logWriter != nil {
+				ctx = SetLogWriterToContext(ctx, s.logWriter)
+			}
+			if b.requestTimeout > 0 && req.IsCall() && !b.handler.hasToolTimeout(req) {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, b.requestTimeout)
+				defer cancel()
+			}
+			res, err := b.handler.Handle(ctx, req)
+			// The context of the handler is derived from ctx, so it is canceled by the deadline as well.
+			// Report the timeout only if the connection is still alive.
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) && s.ctx.Err() == nil {
+				return nil, fmt.Errorf("%w: %s exceeded %s", ErrRequestTimeout, req.Method, b.requestTimeout)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return marshalResult(res)
+		}),
 	}, nil
 }
 
+// hasToolTimeout reports whether req is a tools/call request of a tool with its own timeout set by SetToolTimeout,
+// which replaces the RequestTimeout of the transport.
+func (h *Handler) hasToolTimeout(req *jsonrpc2.Request) bool {
+	if req.Method != protocol.MethodToolsCall {
+		return false
+	}
+	var params struct {
+		Name string `json:"name"`
+	}
+	// Invalid params are reported by the handler of the method, so the error is ignored here.
+	if err := JSONUnmarshal(req.Params, &params); err != nil {
+		return false
+	}
+	_, ok := h.toolTimeouts.Load(params.Name)
+	return ok
+}
+
+// session holds the state of a single connection.
+type session struct {
+	// ctx is canceled when the connection is closed.
+	ctx context.Context
+	// conn is the connection of the session, used to send server-initiated messages.
+	conn *jsonrpc2.Connection
+	// progressHandlers is a map of progress tokens of server-initiated requests to their progress handlers.
+	progressHandlers sync.Map
+	// lastProgressToken is the last progress token issued for server-initiated requests.
+	lastProgressToken atomic.Int64
+	// initializeParams is the params of the initialize request of the session. It is nil until the session is initialized.
+	initializeParams atomic.Pointer[protocol.InitializeRequestParams]
+	// protocolVersion is the protocol version negotiated by the initialize request. It is nil until the session is initialized.
+	protocolVersion atomic.Pointer[string]
+	// initialized reports whether the client sent notifications/initialized.
+	// Until then, requests other than initialize and ping are rejected with ErrNotInitialized.
+	initialized atomic.Bool
+	// subscriptions is a set of resources subscribed by the connection, which is limited by Handler.MaxSubscriptions.
+	subscriptions   map[string]struct{}
+	subscriptionsMu sync.Mutex
+	// logWriter is the writer for log notifications of the connection.
+	// If this is nil, the log writer of the transport is used.
+	logWriter io.Writer
+}
+
+// subscribe records resources subscribed by the session.
+// If max is positive and the number of subscriptions would exceed it, it returns ErrTooManySubscriptions without recording any of them.
+func (s *session) subscribe(uris []string, max int) error {
+	s.subscriptionsMu.Lock()
+	defer s.subscriptionsMu.Unlock()
+
+	if s.subscriptions == nil {
+		s.subscriptions = map[string]struct{}{}
+	}
+	var added []string
+	for _, uri := range uris {
+		if _, ok := s.subscriptions[uri]; !ok && !slices.Contains(added, uri) {
+			added = append(added, uri)
+		}
+	}
+	if max > 0 && len(s.subscriptions)+len(added) > max {
+		return fmt.Errorf("%w: the limit is %d", ErrTooManySubscriptions, max)
+	}
+	for _, uri := range added {
+		s.subscriptions[uri] = struct{}{}
+	}
+	return nil
+}
+
+// isSubscribed reports whether the session subscribes to uri directly or by a glob pattern.
+func (s *session) isSubscribed(uri string) bool {
+	s.subscriptionsMu.Lock()
+	defer s.subscriptionsMu.Unlock()
+
+	if _, ok := s.subscriptions[uri]; ok {
+		return true
+	}
+	for sub := range s.subscriptions {
+		if strings.Contains(sub, "*") && matchURIPattern(sub, uri) {
+			return true
+		}
+	}
+	return false
+}
+
+// unsubscribe removes resources from the subscriptions of the session.
+func (s *session) unsubscribe(uris []string) {
+	s.subscriptionsMu.Lock()
+	defer s.subscriptionsMu.Unlock()
+
+	for _, uri := range uris {
+		delete(s.subscriptions, uri)
+	}
+}
+
+// unsubscribeAll removes all subscriptions of the session. It is called when the connection is closed.
+func (s *session) unsubscribeAll() {
+	s.subscriptionsMu.Lock()
+	defer s.subscriptionsMu.Unlock()
+
+	s.subscriptions = nil
+}
+
+// sessionKey is a key for retrieving the session from the context
+type sessionKey struct{}
+
 type StdioTransportOptions struct {
 	// MaxConns is the maximum number of connections that can be handled by the transport.
 	// If this is not set, 5 connections are allowed.
@@ -307,6 +1347,17 @@ type StdioTransportOptions struct {
 	// Preempter is the preempter for the transport.
 	// If this is not set, no preemption is done.
 	Preempter jsonrpc2.Preempter
+	// ContentLengthFraming makes the transport delimit messages by LSP-style Content-Length headers
+	// (e.g. "Content-Length: 42\r\n\r\n{...}") instead of newlines.
+	// Set this for clients that share code with LSP tooling.
+	ContentLengthFraming bool
+	// RequestTimeout is the maximum duration of handling a single request.
+	// When a request exceeds it, the context passed to the handler is canceled and the request fails with ErrRequestTimeout.
+	// Handlers must respect the cancellation of the context, because the response is sent only after they return.
+	// If this is zero, requests have no timeout.
+	// Calls to tools with their own timeout set by Handler.SetToolTimeout are limited by that timeout instead,
+	// whether it is shorter or longer than this.
+	RequestTimeout time.Duration
 }
 
 // NewStdioTransport creates a new stdio transport.
@@ -317,15 +1368,18 @@ func NewStdioTransport(
 	handler *Handler,
 	opts *StdioTransportOptions,
 ) (context.Context, jsonrpc2.Listener, jsonrpc2.Binder) {
-	w := io.Discard
+	if opts == nil {
+		opts = &StdioTransportOptions{}
+	}
+
+	var w io.Writer = io.Discard
 	if handler.Capabilities.Logging != nil {
 		w = os.Stdout
+		if opts.ContentLengthFraming {
+			w = &contentLengthWriter{w: w}
+		}
 	}
 	ctx = SetLogWriterToContext(ctx, w)
-
-	if opts == nil {
-		opts = &StdioTransportOptions{}
-	}
 	if opts.MaxConns == 0 {
 		opts.MaxConns = 5
 	}
@@ -334,7 +1388,12 @@ func NewStdioTransport(
 		stdio:  stdio{in: os.Stdin, out: os.Stdout},
 		tokens: make(chan struct{}, opts.MaxConns),
 	}
-	binder := &binder{handler: handler, preempter: opts.Preempter}
+	binder := &binder{
+		handler:        handler,
+		preempter:      opts.Preempter,
+		contentLength:  opts.ContentLengthFraming,
+		requestTimeout: opts.RequestTimeout,
+	}
 
 	return ctx, listener, binder
 }
@@ -353,8 +1412,12 @@ type logHandler struct {
 	name string
 
 	mu      *sync.Mutex
+	w       io.Writer
 	encoder *json.Encoder
 	buf     *bytes.Buffer
+
+	// onError is called when a notification fails to be sent.
+	onError func(error)
 }
 
 func (s *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
@@ -379,15 +1442,51 @@ func (s *logHandler) Handle(ctx context.Context, r slog.Record) error {
 	data := s.buf.String()
 	s.buf.Reset()
 
-	return s.encoder.Encode(logRecord{
+	err := s.encoder.Encode(logRecord{
 		JSONRPC: "2.0",
 		Method:  "notifications/message",
 		Params: map[string]any{
-			"level":  levelNameForLogging(r.Level),
+			"level":  protocol.LogLevel(r.Level),
 			"logger": s.name,
 			"data":   json.RawMessage(data),
 		},
 	})
+	if err == nil {
+		// Flush each notification immediately so that logs emitted during a long-running handler
+		// reach the client in real time even over buffered transports.
+		err = flush(s.w)
+	}
+	if err != nil && s.onError != nil {
+		s.onError(err)
+	}
+	return err
+}
+
+// flush flushes w if it buffers data (e.g. *bufio.Writer or http.ResponseWriter).
+func flush(w io.Writer) error {
+	switch f := w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
+	}
+	return nil
+}
+
+// logLevelKey is a key for retrieving the minimum log level of the handler from the context
+type logLevelKey struct{}
+
+// handlerLogLevel is the minimum level of log notifications of a Handler.
+// protocol.LogLevel has the same values as slog.Level, so a record is sent if its level is greater than or equal to this.
+type handlerLogLevel struct {
+	h *Handler
+}
+
+func (l handlerLogLevel) Level() slog.Level {
+	if l.h.SuppressLogsBeforeSetLevel && !l.h.logLevelSet.Load() {
+		return slog.Level(math.MaxInt)
+	}
+	return l.h.logLevel.Level()
 }
 
 // logWriterKey is a key for retrieving the log writer from the context
@@ -401,46 +1500,38 @@ func SetLogWriterToContext(ctx context.Context, w io.Writer) context.Context {
 // Logger creates a new logger with the given name.
 // Note that this logger is for communication with the client, not for internal logging.
 // The logged messages are sent as notifications to the client.
+// Messages below the level set by logging/setLevel on the Handler handling ctx are dropped.
+// If ctx is not passed from a Handler, the minimum level is info.
+// If ctx has no log writer set by SetLogWriterToContext (e.g. in unit tests of handlers), messages are discarded.
 //
 // See https://modelcontextprotocol.io/specification/2025-03-26/server/utilities/logging#logging
 func Logger(ctx context.Context, name string) *slog.Logger {
-	writer := ctx.Value(logWriterKey{}).(io.Writer)
-	handler := newLogHandler(name, writer)
-	return slog.New(handler)
-}
-
-// levelNameForLogging maps a slog level to a MCP logging level name.
-func levelNameForLogging(level slog.Level) string {
-	switch {
-	case level <= slog.LevelDebug:
-		return "debug"
-	case level <= slog.LevelInfo:
-		return "info"
-	case level <= slog.Level(1): // Notice
-		return "notice"
-	case level <= slog.LevelWarn:
-		return "warning"
-	case level <= slog.LevelError:
-		return "error"
-	case level <= slog.Level(9): // Critical
-		return "critical"
-	case level <= slog.Level(10): // Alert
-		return "alert"
-	default:
-		return "emergency"
+	writer, ok := ctx.Value(logWriterKey{}).(io.Writer)
+	if !ok {
+		writer = io.Discard
 	}
+	var level slog.Leveler = slog.LevelInfo
+	if l, ok := ctx.Value(logLevelKey{}).(slog.Leveler); ok {
+		level = l
+	}
+	handler := newLogHandler(name, writer, level)
+	if h, ok := ctx.Value(handlerKey{}).(*Handler); ok {
+		handler.onError = func(err error) { h.handleLogNotificationFailure(ctx, err) }
+	}
+	return slog.New(handler)
 }
 
 // newLogHandler creates a new log handler.
-func newLogHandler(name string, w io.Writer) *logHandler {
+func newLogHandler(name string, w io.Writer, level slog.Leveler) *logHandler {
 	buf := &bytes.Buffer{}
 	handler := &logHandler{
 		name:    name,
+		w:       w,
 		encoder: json.NewEncoder(w),
 		buf:     buf,
 		mu:      &sync.Mutex{},
 		Handler: slog.NewJSONHandler(buf, &slog.HandlerOptions{
-			Level: minimumLogLevel,
+			Level: level,
 			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
 				if len(groups) != 0 {
 					return a
@@ -462,9 +1553,13 @@ func newLogHandler(name string, w io.Writer) *logHandler {
 type nextCursorKey struct{}
 
 // nextCursorFromRequest retrieves the cursor value from the request
+// Params are optional, so empty or null params are treated as an empty cursor.
 func nextCursorFromRequest(req *jsonrpc2.Request) (string, error) {
+	if params := bytes.TrimSpace(req.Params); len(params) == 0 || bytes.Equal(params, []byte("null")) {
+		return "", nil
+	}
 	var p protocol.PaginationParams
-	if err := json.Unmarshal(req.Params, &p); err != nil {
+	if err := JSONUnmarshal(req.Params, &p); err != nil {
 		return "", fmt.Errorf("failed to unmarshal pagination params: %w", err)
 	}
 	return p.Cursor, nil
@@ -479,3 +1574,111 @@ func NextCursor(ctx context.Context) (string, bool) {
 	}
 	return v.(string), true
 }
+
+// metaKey is a key for retrieving _meta of the request from the context
+type metaKey struct{}
+
+// metaFromRequest retrieves _meta of the request. It returns nil if the request doesn't have _meta.
+func metaFromRequest(req *jsonrpc2.Request) map[string]any {
+	var p struct {
+		Meta map[string]any `json:"_meta"`
+	}
+	// Invalid params are reported by the handler of the method, so the error is ignored here.
+	_ = JSONUnmarshal(req.Params, &p)
+	return p.Meta
+}
+
+// Meta returns _meta of the request being handled from the context, e.g. {"progressToken": 1, "traceparent": "..."}.
+// It contains all the metadata sent by the client including the ones handled by this package, such as progressToken.
+// The returned map must not be modified. If the request doesn't have _meta, it returns nil.
+func Meta(ctx context.Context) map[string]any {
+	meta, _ := ctx.Value(metaKey{}).(map[string]any)
+	return meta
+}
+
+// negotiatedProtocolVersion returns the protocol version negotiated with the client of ctx.
+// If ctx isn't bound to an initialized connection, it returns protocol.LatestProtocolVersion.
+func negotiatedProtocolVersion(ctx context.Context) string {
+	if v, ok := ProtocolVersion(ctx); ok {
+		return v
+	}
+	return protocol.LatestProtocolVersion
+}
+
+// ProtocolVersion returns the protocol version negotiated with the client from the context,
+// which may differ from the version requested by the client. See Handler.StrictProtocolVersion.
+// If the client isn't initialized yet or ctx isn't bound to a connection, it returns false.
+func ProtocolVersion(ctx context.Context) (string, bool) {
+	s, ok := ctx.Value(sessionKey{}).(*session)
+	if !ok {
+		return "", false
+	}
+	v := s.protocolVersion.Load()
+	if v == nil {
+		return "", false
+	}
+	return *v, true
+}
+
+// ClientInfo returns the name and version of the client from the context, e.g. for logging which client is connected.
+// If the client isn't initialized yet or ctx isn't bound to a connection, it returns false.
+func ClientInfo(ctx context.Context) (protocol.Implementation, bool) {
+	params, ok := InitializeParams(ctx)
+	return params.ClientInfo, ok
+}
+
+// ClientCapabilities returns the capabilities declared by the client from the context,
+// e.g. for using sampling only if the client supports it.
+// If the client isn't initialized yet or ctx isn't bound to a connection, it returns false.
+func ClientCapabilities(ctx context.Context) (protocol.ClientCapabilities, bool) {
+	params, ok := InitializeParams(ctx)
+	return params.Capabilities, ok
+}
+
+// InitializeParams returns the params of the initialize request of the client from the context.
+// Params.Raw holds the raw JSON of the params, so handlers can detect features the client declared
+// by fields not defined in protocol.InitializeRequestParams.
+// If the client isn't initialized yet or ctx isn't bound to a connection, it returns false.
+func InitializeParams(ctx context.Context) (protocol.InitializeRequestParams, bool) {
+	s, ok := ctx.Value(sessionKey{}).(*session)
+	if !ok {
+		return protocol.InitializeRequestParams{}, false
+	}
+	params := s.initializeParams.Load()
+	if params == nil {
+		return protocol.InitializeRequestParams{}, false
+	}
+	return *params, true
+}
+
+// byteRangeKey is a key for retrieving the requested byte range from the context
+type byteRangeKey struct{}
+
+// byteRangeFromRequest retrieves the byte range from _meta of the request.
+// It returns nil if the request doesn't have a byte range.
+func byteRangeFromRequest(req *jsonrpc2.Request) (*ByteRange, error) {
+	var p struct {
+		Meta struct {
+			Range *ByteRange `json:"range"`
+		} `json:"_meta"`
+	}
+	if err := JSONUnmarshal(req.Params, &p); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal byte range: %w", err)
+	}
+	r := p.Meta.Range
+	if r != nil && (r.Offset < 0 || r.Length < 0) {
+		return nil, fmt.Errorf("invalid byte range: offset %d, length %d", r.Offset, r.Length)
+	}
+	return r, nil
+}
+
+// RequestedByteRange returns the byte range requested by the client from the context.
+// If the client doesn't request a byte range or the API doesn't support byte ranges, it returns false.
+// Only resources/read supports byte ranges.
+func RequestedByteRange(ctx context.Context) (ByteRange, bool) {
+	v := ctx.Value(byteRangeKey{})
+	if v == nil {
+		return ByteRange{}, false
+	}
+	return *v.(*ByteRange), true
+}
@@ -0,0 +1,2049 @@
+package mcp_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mcp "github.com/ktr0731/go-mcp"
+	"github.com/ktr0731/go-mcp/protocol"
+	"golang.org/x/exp/jsonrpc2"
+)
+
+func TestHandler_Handle_InitializeUnsupportedProtocolVersion(t *testing.T) {
+	t.Parallel()
+
+	params := protocol.InitializeRequestParams{
+		ProtocolVersion: "2025-3-26",
+		ClientInfo:      protocol.Implementation{Name: "test-client", Version: "1.0.0"},
+	}
+
+	t.Run("fallback", func(t *testing.T) {
+		t.Parallel()
+
+		var logs bytes.Buffer
+		ctx := mcp.SetLogWriterToContext(context.Background(), &logs)
+		h := &mcp.Handler{}
+
+		res, err := h.Handle(ctx, newCall(t, 1, protocol.MethodInitialize, params))
+		if err != nil {
+			t.Fatalf("Handle returned an error: %v", err)
+		}
+		result, ok := res.(*protocol.InitializeResult)
+		if !ok {
+			t.Fatalf("unexpected result type: %T", res)
+		}
+		if result.ProtocolVersion != protocol.LatestProtocolVersion {
+			t.Errorf("want protocol version %s, got %s", protocol.LatestProtocolVersion, result.ProtocolVersion)
+		}
+
+		var notification struct {
+			Method string `json:"method"`
+			Params struct {
+				Level string `json:"level"`
+				Data  struct {
+					Requested string `json:"requested"`
+					Served    string `json:"served"`
+				} `json:"data"`
+			} `json:"params"`
+		}
+		if err := json.Unmarshal(logs.Bytes(), &notification); err != nil {
+			t.Fatalf("failed to unmarshal log notification %q: %v", logs.String(), err)
+		}
+		if notification.Method != protocol.MethodNotificationsMessage {
+			t.Errorf("want method %s, got %s", protocol.MethodNotificationsMessage, notification.Method)
+		}
+		if notification.Params.Level != "warning" {
+			t.Errorf("want level warning, got %s", notification.Params.Level)
+		}
+		if notification.Params.Data.Requested != params.ProtocolVersion {
+			t.Errorf("want requested %s, got %s", params.ProtocolVersion, notification.Params.Data.Requested)
+		}
+		if notification.Params.Data.Served != protocol.LatestProtocolVersion {
+			t.Errorf("want served %s, got %s", protocol.LatestProtocolVersion, notification.Params.Data.Served)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := mcp.SetLogWriterToContext(context.Background(), &bytes.Buffer{})
+		h := &mcp.Handler{StrictProtocolVersion: true}
+
+		_, err := h.Handle(ctx, newCall(t, 1, protocol.MethodInitialize, params))
+		if !errors.Is(err, jsonrpc2.ErrInvalidParams) {
+			t.Errorf("want %v, got %v", jsonrpc2.ErrInvalidParams, err)
+		}
+	})
+}
+
+func TestHandler_Handle_InitializeInstructions(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{Instructions: "Use the forecast resources."}
+	ctx := mcp.SetLogWriterToContext(context.Background(), &bytes.Buffer{})
+
+	res, err := h.Handle(ctx, newCall(t, 1, protocol.MethodInitialize, protocol.InitializeRequestParams{
+		ProtocolVersion: protocol.LatestProtocolVersion,
+	}))
+	if err != nil {
+		t.Fatalf("Handle returned an error: %v", err)
+	}
+	result, ok := res.(*protocol.InitializeResult)
+	if !ok {
+		t.Fatalf("unexpected result type: %T", res)
+	}
+	if result.Instructions != h.Instructions {
+		t.Errorf("want instructions %q, got %q", h.Instructions, result.Instructions)
+	}
+}
+
+func TestNewHandler(t *testing.T) {
+	t.Parallel()
+
+	tools := []protocol.Tool{{Name: "echo", InputSchema: json.RawMessage(`{"type":"object"}`)}}
+	h := mcp.NewHandler(
+		mcp.WithImplementation(protocol.Implementation{Name: "test-server", Version: "1.0.0"}),
+		mcp.WithInstructions("Use echo."),
+		mcp.WithTools(tools, protocol.ServerHandlerFunc[protocol.CallToolRequestParams](
+			func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+				return &mcp.CallToolResult{Content: []mcp.CallToolContent{mcp.TextContent{Text: "echo"}}}, nil
+			},
+		)),
+	)
+	ctx := mcp.SetLogWriterToContext(context.Background(), &bytes.Buffer{})
+
+	res, err := h.Handle(ctx, newCall(t, 1, protocol.MethodInitialize, protocol.InitializeRequestParams{
+		ProtocolVersion: protocol.LatestProtocolVersion,
+	}))
+	if err != nil {
+		t.Fatalf("Handle returned an error: %v", err)
+	}
+	result, ok := res.(*protocol.InitializeResult)
+	if !ok {
+		t.Fatalf("unexpected result type: %T", res)
+	}
+	if result.ServerInfo.Name != "test-server" {
+		t.Errorf("want server name test-server, got %s", result.ServerInfo.Name)
+	}
+	if result.Instructions != "Use echo." {
+		t.Errorf("want instructions %q, got %q", "Use echo.", result.Instructions)
+	}
+	if result.Capabilities.Tools == nil {
+		t.Error("want the Tools capability to be enabled by WithTools")
+	}
+	if result.Capabilities.Prompts != nil || result.Capabilities.Resources != nil || result.Capabilities.Completions != nil {
+		t.Errorf("want only the Tools capability, got %+v", result.Capabilities)
+	}
+
+	res, err = h.Handle(ctx, newCall(t, 2, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "echo"}))
+	if err != nil {
+		t.Fatalf("Handle returned an error: %v", err)
+	}
+	if _, ok := res.(*mcp.CallToolResult); !ok {
+		t.Errorf("unexpected result type: %T", res)
+	}
+}
+
+func TestInitializeParams(t *testing.T) {
+	t.Parallel()
+
+	var (
+		got   protocol.InitializeRequestParams
+		gotOK bool
+	)
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			got, gotOK = mcp.InitializeParams(ctx)
+			return &mcp.CallToolResult{}, nil
+		}),
+	}
+	conn := serveUninitialized(t, h, jsonrpc2.HandlerFunc(func(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+		return nil, nil
+	}))
+
+	ctx := context.Background()
+	if _, ok := mcp.InitializeParams(ctx); ok {
+		t.Error("want no initialize params outside of a session")
+	}
+
+	params := json.RawMessage(`{"protocolVersion":"2025-03-26","capabilities":{"experimental":{"foo":{}}},"clientInfo":{"name":"client","version":"1.0.0"},"x-extension":true}`)
+	if err := conn.Call(ctx, protocol.MethodInitialize, params).Await(ctx, nil); err != nil {
+		t.Fatalf("initialize returned an error: %v", err)
+	}
+	if err := conn.Notify(ctx, protocol.MethodNotificationsInitialized, struct{}{}); err != nil {
+		t.Fatalf("failed to send %s: %v", protocol.MethodNotificationsInitialized, err)
+	}
+	if err := conn.Call(ctx, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "detect"}).Await(ctx, nil); err != nil {
+		t.Fatalf("tools/call returned an error: %v", err)
+	}
+	if !gotOK {
+		t.Fatal("want initialize params after initialization")
+	}
+	if got.ClientInfo.Name != "client" {
+		t.Errorf("want client name %q, got %q", "client", got.ClientInfo.Name)
+	}
+	if _, ok := got.Capabilities.Experimental["foo"]; !ok {
+		t.Errorf("want the experimental capability foo, got %v", got.Capabilities.Experimental)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(got.Raw, &raw); err != nil {
+		t.Fatalf("failed to unmarshal raw params: %v", err)
+	}
+	if raw["x-extension"] != true {
+		t.Errorf("want the raw params to contain x-extension, got %s", got.Raw)
+	}
+}
+
+func TestClientInfo(t *testing.T) {
+	t.Parallel()
+
+	var (
+		version      string
+		info         protocol.Implementation
+		capabilities protocol.ClientCapabilities
+		ok           [3]bool
+	)
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			version, ok[0] = mcp.ProtocolVersion(ctx)
+			info, ok[1] = mcp.ClientInfo(ctx)
+			capabilities, ok[2] = mcp.ClientCapabilities(ctx)
+			return &mcp.CallToolResult{}, nil
+		}),
+	}
+	conn := serve(t, h)
+
+	ctx := context.Background()
+	if _, ok := mcp.ProtocolVersion(ctx); ok {
+		t.Error("want no protocol version outside of a session")
+	}
+
+	params := protocol.InitializeRequestParams{
+		ProtocolVersion: protocol.ProtocolVersion20250326,
+		Capabilities:    protocol.ClientCapabilities{Roots: &protocol.RootsCapability{}},
+		ClientInfo:      protocol.Implementation{Name: "client", Version: "1.0.0"},
+	}
+	if err := conn.Call(ctx, protocol.MethodInitialize, params).Await(ctx, nil); err != nil {
+		t.Fatalf("initialize returned an error: %v", err)
+	}
+	if err := conn.Call(ctx, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "detect"}).Await(ctx, nil); err != nil {
+		t.Fatalf("tools/call returned an error: %v", err)
+	}
+	if ok != [3]bool{true, true, true} {
+		t.Fatalf("want all accessors to succeed, got %v", ok)
+	}
+	if version != protocol.ProtocolVersion20250326 {
+		t.Errorf("want protocol version %s, got %s", protocol.ProtocolVersion20250326, version)
+	}
+	if info.Name != "client" || info.Version != "1.0.0" {
+		t.Errorf("want client info client 1.0.0, got %+v", info)
+	}
+	if capabilities.Roots == nil {
+		t.Errorf("want the roots capability, got %+v", capabilities)
+	}
+}
+
+func TestHandler_Handle_Lifecycle(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			return &mcp.CallToolResult{}, nil
+		}),
+	}
+	first := serveUninitialized(t, h, nil)
+	second := serveUninitialized(t, h, nil)
+
+	ctx := context.Background()
+	callTool := func(conn *jsonrpc2.Connection) error {
+		return conn.Call(ctx, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "echo"}).Await(ctx, nil)
+	}
+
+	if err := callTool(first); err == nil || !strings.Contains(err.Error(), "not initialized") {
+		t.Errorf("want an error for tools/call before initialization, got %v", err)
+	}
+	if err := first.Call(ctx, protocol.MethodPing, struct{}{}).Await(ctx, nil); err != nil {
+		t.Errorf("want ping to be allowed before initialization, got %v", err)
+	}
+
+	initialize(t, first)
+	if err := callTool(first); err != nil {
+		t.Errorf("tools/call returned an error after initialization: %v", err)
+	}
+	// The state is per connection.
+	if err := callTool(second); err == nil {
+		t.Error("want an error for tools/call on another connection which isn't initialized")
+	}
+}
+
+func TestHandler_Handle_ProtocolVersionGating(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		Tools: []protocol.Tool{{
+			Name:         "convert_temperature",
+			InputSchema:  json.RawMessage(`{"type":"object"}`),
+			OutputSchema: json.RawMessage(`{"type":"object"}`),
+		}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			return &mcp.CallToolResult{StructuredContent: json.RawMessage(`{"temperature":77}`)}, nil
+		}),
+	}
+
+	cases := map[string]struct {
+		version    string
+		wantList   string
+		wantResult string
+	}{
+		"2025-06-18": {
+			version:    protocol.ProtocolVersion20250618,
+			wantList:   `{"tools":[{"name":"convert_temperature","inputSchema":{"type":"object"},"outputSchema":{"type":"object"}}]}`,
+			wantResult: `{"content":[],"structuredContent":{"temperature":77}}`,
+		},
+		"2025-03-26": {
+			version:    protocol.ProtocolVersion20250326,
+			wantList:   `{"tools":[{"name":"convert_temperature","inputSchema":{"type":"object"}}]}`,
+			wantResult: `{"content":[{"type":"text","text":"{\"temperature\":77}"}]}`,
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			conn := serveWithClientHandler(t, h, jsonrpc2.HandlerFunc(func(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+				return nil, nil
+			}))
+			ctx := context.Background()
+			var initRes protocol.InitializeResult
+			if err := conn.Call(ctx, protocol.MethodInitialize, protocol.InitializeRequestParams{ProtocolVersion: c.version}).Await(ctx, &initRes); err != nil {
+				t.Fatalf("initialize returned an error: %v", err)
+			}
+			if initRes.ProtocolVersion != c.version {
+				t.Errorf("want protocol version %s, got %s", c.version, initRes.ProtocolVersion)
+			}
+
+			var list json.RawMessage
+			if err := conn.Call(ctx, protocol.MethodToolsList, struct{}{}).Await(ctx, &list); err != nil {
+				t.Fatalf("tools/list returned an error: %v", err)
+			}
+			if string(list) != c.wantList {
+				t.Errorf("want %s, got %s", c.wantList, list)
+			}
+
+			var res json.RawMessage
+			if err := conn.Call(ctx, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "convert_temperature"}).Await(ctx, &res); err != nil {
+				t.Fatalf("tools/call returned an error: %v", err)
+			}
+			if string(res) != c.wantResult {
+				t.Errorf("want %s, got %s", c.wantResult, res)
+			}
+		})
+	}
+}
+
+func TestHandler_Handle_ResourceLink(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			return &mcp.CallToolResult{
+				Content: []mcp.CallToolContent{mcp.ResourceLink{
+					URI:      "weather://historical/tokyo/2025-01-01",
+					Name:     "Tokyo on 2025-01-01",
+					MimeType: "application/json",
+				}},
+			}, nil
+		}),
+	}
+
+	cases := map[string]struct {
+		version string
+		want    string
+	}{
+		"2025-06-18": {
+			version: protocol.ProtocolVersion20250618,
+			want:    `{"content":[{"type":"resource_link","uri":"weather://historical/tokyo/2025-01-01","name":"Tokyo on 2025-01-01","mimeType":"application/json"}]}`,
+		},
+		"2025-03-26": {
+			version: protocol.ProtocolVersion20250326,
+			want:    `{"content":[{"type":"text","text":"weather://historical/tokyo/2025-01-01"}]}`,
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			conn := serveWithClientHandler(t, h, jsonrpc2.HandlerFunc(func(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+				return nil, nil
+			}))
+			ctx := context.Background()
+			if err := conn.Call(ctx, protocol.MethodInitialize, protocol.InitializeRequestParams{ProtocolVersion: c.version}).Await(ctx, nil); err != nil {
+				t.Fatalf("initialize returned an error: %v", err)
+			}
+
+			var res json.RawMessage
+			if err := conn.Call(ctx, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "get_historical_link"}).Await(ctx, &res); err != nil {
+				t.Fatalf("tools/call returned an error: %v", err)
+			}
+			if string(res) != c.want {
+				t.Errorf("want %s, got %s", c.want, res)
+			}
+		})
+	}
+}
+
+func TestMeta(t *testing.T) {
+	t.Parallel()
+
+	var got map[string]any
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			got = mcp.Meta(ctx)
+			return &mcp.CallToolResult{}, nil
+		}),
+	}
+	ctx := mcp.SetLogWriterToContext(context.Background(), &bytes.Buffer{})
+
+	params := json.RawMessage(`{"name":"trace","_meta":{"progressToken":1,"traceparent":"00-abc-def-01"}}`)
+	if _, err := h.Handle(ctx, newCall(t, 1, protocol.MethodToolsCall, params)); err != nil {
+		t.Fatalf("tools/call returned an error: %v", err)
+	}
+	if got["traceparent"] != "00-abc-def-01" || got["progressToken"] != float64(1) {
+		t.Errorf("want _meta of the request, got %v", got)
+	}
+
+	if _, err := h.Handle(ctx, newCall(t, 2, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "trace"})); err != nil {
+		t.Fatalf("tools/call returned an error: %v", err)
+	}
+	if got != nil {
+		t.Errorf("want nil for a request without _meta, got %v", got)
+	}
+}
+
+func TestHandler_OnInitialize_ConnectionClosed(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	canceled := make(chan error, 1)
+	h := &mcp.Handler{
+		OnInitialize: func(ctx context.Context, params *protocol.InitializeRequestParams) error {
+			close(started)
+			<-ctx.Done()
+			canceled <- ctx.Err()
+			return ctx.Err()
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	listener, err := jsonrpc2.NetPipe(ctx)
+	if err != nil {
+		t.Fatalf("failed to create a listener: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	sctx, _, binder := mcp.NewStdioTransport(ctx, h, nil)
+	if _, err := jsonrpc2.Serve(sctx, listener, binder); err != nil {
+		t.Fatalf("failed to serve: %v", err)
+	}
+
+	dialer := &recordingDialer{Dialer: listener.Dialer()}
+	client, err := jsonrpc2.Dial(ctx, dialer, jsonrpc2.ConnectionOptions{Framer: jsonrpc2.RawFramer()})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	client.Call(ctx, protocol.MethodInitialize, protocol.InitializeRequestParams{
+		ProtocolVersion: protocol.LatestProtocolVersion,
+	})
+	<-started
+
+	// Close the underlying stream rather than the client connection,
+	// because Connection.Close waits for the outgoing initialize call, which never finishes by itself.
+	if err := dialer.rwc.Close(); err != nil {
+		t.Fatalf("failed to close the connection: %v", err)
+	}
+
+	select {
+	case err := <-canceled:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("want the session context to be canceled with %v, got %v", context.Canceled, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnInitialize context was not canceled after the connection was closed")
+	}
+}
+
+// recordingDialer records the stream it dials so that tests can close it directly.
+type recordingDialer struct {
+	jsonrpc2.Dialer
+	rwc io.ReadWriteCloser
+}
+
+func (d *recordingDialer) Dial(ctx context.Context) (io.ReadWriteCloser, error) {
+	rwc, err := d.Dialer.Dial(ctx)
+	d.rwc = rwc
+	return rwc, err
+}
+
+func TestLogger_Flush(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	w := bufio.NewWriter(&out)
+	ctx := mcp.SetLogWriterToContext(context.Background(), w)
+
+	mcp.Logger(ctx, "test").Info("hello")
+
+	if out.Len() == 0 {
+		t.Error("log notification was not flushed to the underlying writer")
+	}
+}
+
+func TestLogger_NoLogWriter(t *testing.T) {
+	t.Parallel()
+
+	// Logger must not panic even if ctx doesn't have a log writer.
+	mcp.Logger(context.Background(), "test").Info("discarded")
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, io.ErrClosedPipe }
+
+func TestHandler_LogNotificationFailurePolicy(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		policy  mcp.NotificationFailurePolicy
+		wantLog bool
+	}{
+		"drop": {policy: mcp.NotificationFailureDrop},
+		"log":  {policy: mcp.NotificationFailureLog, wantLog: true},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var errLog bytes.Buffer
+			h := &mcp.Handler{
+				Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+				ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+					mcp.Logger(ctx, "test").Info("hello")
+					return &mcp.CallToolResult{}, nil
+				}),
+				LogNotificationFailurePolicy: c.policy,
+				ErrorLog:                     log.New(&errLog, "", 0),
+			}
+			ctx := mcp.SetLogWriterToContext(context.Background(), failingWriter{})
+
+			// A failed log notification doesn't fail the request.
+			if _, err := h.Handle(ctx, newCall(t, 1, protocol.MethodToolsCall, map[string]any{"name": "log"})); err != nil {
+				t.Fatalf("tools/call returned an error: %v", err)
+			}
+			if got := strings.Contains(errLog.String(), io.ErrClosedPipe.Error()); got != c.wantLog {
+				t.Errorf("want the failure to be logged: %t, got error log %q", c.wantLog, errLog.String())
+			}
+		})
+	}
+}
+
+func TestHandler_Use(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	record := func(name string) mcp.Middleware {
+		return func(next mcp.HandleFunc) mcp.HandleFunc {
+			return func(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+				calls = append(calls, name+" "+req.Method)
+				return next(ctx, req)
+			}
+		}
+	}
+	errUnauthorized := errors.New("unauthorized")
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			calls = append(calls, "tool "+req.Name)
+			return &mcp.CallToolResult{}, nil
+		}),
+	}
+	h.Use(record("first"), record("second"))
+	h.Use(func(next mcp.HandleFunc) mcp.HandleFunc {
+		return func(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+			if req.Method == protocol.MethodToolsCall {
+				var params protocol.CallToolRequestParams
+				if err := json.Unmarshal(req.Params, &params); err != nil {
+					return nil, err
+				}
+				if params.Name == "admin" {
+					return nil, errUnauthorized
+				}
+			}
+			return next(ctx, req)
+		}
+	})
+	ctx := mcp.SetLogWriterToContext(context.Background(), &bytes.Buffer{})
+
+	notification, err := jsonrpc2.NewNotification(protocol.MethodNotificationsInitialized, struct{}{})
+	if err != nil {
+		t.Fatalf("failed to create a notification: %v", err)
+	}
+	if _, err := h.Handle(ctx, notification); err != nil {
+		t.Fatalf("notifications/initialized returned an error: %v", err)
+	}
+	if _, err := h.Handle(ctx, newCall(t, 1, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "search"})); err != nil {
+		t.Fatalf("tools/call returned an error: %v", err)
+	}
+	if _, err := h.Handle(ctx, newCall(t, 2, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "admin"})); !errors.Is(err, errUnauthorized) {
+		t.Errorf("want %v, got %v", errUnauthorized, err)
+	}
+
+	want := []string{
+		"first notifications/initialized",
+		"second notifications/initialized",
+		"first tools/call",
+		"second tools/call",
+		"tool search",
+		"first tools/call",
+		"second tools/call",
+	}
+	if !slices.Equal(want, calls) {
+		t.Errorf("want %v, got %v", want, calls)
+	}
+}
+
+func TestHandler_SetToolTimeout(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			if req.Name == "slow" {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			return &mcp.CallToolResult{}, nil
+		}),
+	}
+	h.SetToolTimeout("slow", 10*time.Millisecond)
+	ctx := mcp.SetLogWriterToContext(context.Background(), &bytes.Buffer{})
+
+	_, err := h.Handle(ctx, newCall(t, 1, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "slow"}))
+	if !errors.Is(err, mcp.ErrToolTimeout) {
+		t.Errorf("want %v, got %v", mcp.ErrToolTimeout, err)
+	}
+
+	if _, err := h.Handle(ctx, newCall(t, 2, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "fast"})); err != nil {
+		t.Errorf("tools/call returned an error: %v", err)
+	}
+}
+
+func TestHandler_SetToolTimeout_AsResult(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}),
+	}
+	h.SetToolTimeout("slow", 10*time.Millisecond, mcp.ToolTimeoutAsResult())
+	ctx := mcp.SetLogWriterToContext(context.Background(), &bytes.Buffer{})
+
+	res, err := h.Handle(ctx, newCall(t, 1, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "slow"}))
+	if err != nil {
+		t.Fatalf("tools/call returned an error: %v", err)
+	}
+	b, err := json.Marshal(res)
+	if err != nil {
+		t.Fatalf("failed to marshal the result: %v", err)
+	}
+	want := `{"content":[{"type":"text","text":"tool execution timed out after 10ms"}],"isError":true}`
+	if string(b) != want {
+		t.Errorf("want %s, got %s", want, b)
+	}
+
+	// The result is reported only for timeouts, not for cancellation by the client.
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := h.Handle(cctx, newCall(t, 2, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "slow"})); !errors.Is(err, context.Canceled) {
+		t.Errorf("want %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestHandler_Handle_RecoverPanic(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			panic("boom")
+		}),
+	}
+	client := serve(t, h)
+	ctx := context.Background()
+
+	err := client.Call(ctx, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "panic"}).Await(ctx, nil)
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("want an error containing the panic value, got %v", err)
+	}
+
+	// The connection is still alive after the panic.
+	if err := client.Call(ctx, protocol.MethodPing, struct{}{}).Await(ctx, nil); err != nil {
+		t.Errorf("ping returned an error: %v", err)
+	}
+}
+
+func TestHandler_Handle_RecoverPanicStack(t *testing.T) {
+	t.Parallel()
+
+	for _, debug := range []bool{false, true} {
+		t.Run(fmt.Sprintf("debug=%t", debug), func(t *testing.T) {
+			t.Parallel()
+
+			var errorLog bytes.Buffer
+			h := &mcp.Handler{
+				Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+				ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+					panic("boom")
+				}),
+				Debug:    debug,
+				ErrorLog: log.New(&errorLog, "", 0),
+			}
+			var logs bytes.Buffer
+			ctx := mcp.SetLogWriterToContext(context.Background(), &logs)
+			if _, err := h.Handle(ctx, newCall(t, 1, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "panic"})); err == nil {
+				t.Fatal("want an error, got nil")
+			}
+
+			// The stack trace is sent to the client only in debug mode, and written to ErrorLog otherwise.
+			if got := strings.Contains(logs.String(), "goroutine"); got != debug {
+				t.Errorf("want the log notification to contain the stack trace: %t, got:\n%s", debug, logs.String())
+			}
+			if got := strings.Contains(errorLog.String(), "goroutine"); got == debug {
+				t.Errorf("want ErrorLog to contain the stack trace: %t, got:\n%s", !debug, errorLog.String())
+			}
+		})
+	}
+}
+
+func TestHandler_Handle_ToolsCallImageContent(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			return &mcp.CallToolResult{
+				Content: []mcp.CallToolContent{
+					mcp.ImageContent{Data: strings.NewReader("chart"), MimeType: "image/png"},
+					mcp.AudioContent{Data: strings.NewReader("beep"), MimeType: "audio/wav"},
+				},
+			}, nil
+		}),
+	}
+	client := newInMemoryClient(t, h)
+
+	res, err := client.Call(context.Background(), protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "render_chart"})
+	if err != nil {
+		t.Fatalf("tools/call returned an error: %v", err)
+	}
+	// The content has the same shape as contents of prompt messages.
+	want := `{"content":[{"type":"image","mimeType":"image/png","data":"Y2hhcnQ="},{"type":"audio","mimeType":"audio/wav","data":"YmVlcA=="}]}`
+	if string(res) != want {
+		t.Errorf("want %s, got %s", want, res)
+	}
+}
+
+func TestHandler_Handle_ToolsCallDeprecated(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}, Logging: &protocol.LoggingCapability{}},
+		Tools: []protocol.Tool{
+			{Name: "search", Annotations: &protocol.ToolAnnotations{Deprecated: "Use search_v2 instead"}},
+			{Name: "search_v2"},
+		},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			return &mcp.CallToolResult{}, nil
+		}),
+	}
+
+	for _, name := range []string{"search", "search_v2"} {
+		var logs bytes.Buffer
+		ctx := mcp.SetLogWriterToContext(context.Background(), &logs)
+		if _, err := h.Handle(ctx, newCall(t, 1, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: name})); err != nil {
+			t.Fatalf("tools/call returned an error: %v", err)
+		}
+
+		deprecated := name == "search"
+		if got := strings.Contains(logs.String(), "Use search_v2 instead"); got != deprecated {
+			t.Errorf("%s: want the deprecation warning to be logged: %t, got logs %q", name, deprecated, logs.String())
+		}
+	}
+}
+
+func TestHandler_Handle_ToolsCallStructuredContent(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		result  *mcp.CallToolResult
+		wantErr bool
+	}{
+		"valid": {
+			result: &mcp.CallToolResult{StructuredContent: json.RawMessage(`{"temperature":77}`)},
+		},
+		"invalid": {
+			result:  &mcp.CallToolResult{StructuredContent: json.RawMessage(`{"temperature":"hot"}`)},
+			wantErr: true,
+		},
+		"missing": {
+			result:  &mcp.CallToolResult{},
+			wantErr: true,
+		},
+		"error result": {
+			result: &mcp.CallToolResult{IsError: true},
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			h := &mcp.Handler{
+				Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+				Tools: []protocol.Tool{{
+					Name:         "convert_temperature",
+					InputSchema:  json.RawMessage(`{"type":"object"}`),
+					OutputSchema: json.RawMessage(`{"type":"object","properties":{"temperature":{"type":"number"}},"required":["temperature"]}`),
+				}},
+				ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+					return c.result, nil
+				}),
+			}
+			ctx := mcp.SetLogWriterToContext(context.Background(), &bytes.Buffer{})
+
+			_, err := h.Handle(ctx, newCall(t, 1, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "convert_temperature"}))
+			if c.wantErr && !errors.Is(err, jsonrpc2.ErrInternal) {
+				t.Errorf("want %v, got %v", jsonrpc2.ErrInternal, err)
+			}
+			if !c.wantErr && err != nil {
+				t.Errorf("tools/call returned an error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHandler_ToolArgumentErrorsAsResults(t *testing.T) {
+	t.Parallel()
+
+	for _, asResults := range []bool{false, true} {
+		t.Run(fmt.Sprintf("asResults=%t", asResults), func(t *testing.T) {
+			t.Parallel()
+
+			h := &mcp.Handler{
+				Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+				ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+					return nil, protocol.ValidateByJSONSchema(`{"type":"object","required":["city"]}`, map[string]any{})
+				}),
+				ToolArgumentErrorsAsResults: asResults,
+			}
+			ctx := mcp.SetLogWriterToContext(context.Background(), &bytes.Buffer{})
+
+			res, err := h.Handle(ctx, newCall(t, 1, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "get_weather"}))
+			if !asResults {
+				if !errors.Is(err, protocol.ErrInvalidToolArguments) {
+					t.Errorf("want %v, got %v", protocol.ErrInvalidToolArguments, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("tools/call returned an error: %v", err)
+			}
+			result, ok := res.(*mcp.CallToolResult)
+			if !ok || !result.IsError || len(result.Content) != 1 {
+				t.Fatalf("want an error result, got %#v", res)
+			}
+			if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "city") {
+				t.Errorf("want the reason in the result, got %q", text)
+			}
+		})
+	}
+}
+
+func TestHandler_Handle_Debug(t *testing.T) {
+	t.Parallel()
+
+	for _, debug := range []bool{false, true} {
+		t.Run(fmt.Sprintf("debug=%t", debug), func(t *testing.T) {
+			t.Parallel()
+
+			h := &mcp.Handler{
+				Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+				Tools: []protocol.Tool{{
+					Name:         "convert_temperature",
+					InputSchema:  json.RawMessage(`{"type":"object"}`),
+					OutputSchema: json.RawMessage(`{"type":"object","properties":{"temperature":{"type":"number"}}}`),
+				}},
+				ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+					return &mcp.CallToolResult{StructuredContent: json.RawMessage(`{"temperature":"hot"}`)}, nil
+				}),
+				Debug: debug,
+			}
+			ctx := mcp.SetLogWriterToContext(context.Background(), &bytes.Buffer{})
+
+			_, err := h.Handle(ctx, newCall(t, 1, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "convert_temperature"}))
+			if !errors.Is(err, jsonrpc2.ErrInternal) {
+				t.Fatalf("want %v, got %v", jsonrpc2.ErrInternal, err)
+			}
+			// Validation details mention the expected type only in debug mode.
+			if got := strings.Contains(err.Error(), "number"); got != debug {
+				t.Errorf("want details in the error: %t, got %q", debug, err.Error())
+			}
+		})
+	}
+}
+
+func TestHandler_SetToolContentTypes(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		Tools:        []protocol.Tool{{Name: "describe", InputSchema: json.RawMessage(`{"type":"object"}`)}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			return &mcp.CallToolResult{
+				Content: []mcp.CallToolContent{
+					mcp.TextContent{Text: "a chart"},
+					mcp.ImageContent{Data: strings.NewReader("png"), MimeType: "image/png"},
+				},
+			}, nil
+		}),
+	}
+	ctx := mcp.SetLogWriterToContext(context.Background(), &bytes.Buffer{})
+	call := func() error {
+		_, err := h.Handle(ctx, newCall(t, 1, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "describe"}))
+		return err
+	}
+
+	h.SetToolContentTypes("describe", mcp.ContentTypeText)
+	if err := call(); !errors.Is(err, jsonrpc2.ErrInternal) {
+		t.Errorf("want %v for a disallowed image, got %v", jsonrpc2.ErrInternal, err)
+	}
+
+	h.SetToolContentTypes("describe", mcp.ContentTypeText, mcp.ContentTypeImage)
+	if err := call(); err != nil {
+		t.Errorf("want no error for allowed content, got %v", err)
+	}
+
+	h.SetToolContentTypes("describe")
+	if err := call(); err != nil {
+		t.Errorf("want no error after removing the restriction, got %v", err)
+	}
+}
+
+func TestHandler_Handle_DisablePanicRecovery(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			panic("boom")
+		}),
+		DisablePanicRecovery: true,
+	}
+	ctx := mcp.SetLogWriterToContext(context.Background(), &bytes.Buffer{})
+
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("want the panic to propagate, got %v", r)
+		}
+	}()
+	h.Handle(ctx, newCall(t, 1, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "panic"}))
+}
+
+func TestHandler_Handle_ResourcesListWithoutParams(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities:    protocol.ServerCapabilities{Resources: &protocol.ResourceCapability{}},
+		ResourceHandler: &blobResourceHandler{},
+	}
+	ctx := mcp.SetLogWriterToContext(context.Background(), &bytes.Buffer{})
+
+	cases := map[string]json.RawMessage{
+		"no params":   nil,
+		"null params": json.RawMessage("null"),
+	}
+	for name, params := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := &jsonrpc2.Request{ID: jsonrpc2.Int64ID(1), Method: protocol.MethodResourcesList, Params: params}
+			if _, err := h.Handle(ctx, req); err != nil {
+				t.Errorf("resources/list returned an error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHandler_Handle_ResourcesReadByteRange(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities:    protocol.ServerCapabilities{Resources: &protocol.ResourceCapability{}},
+		ResourceHandler: &blobResourceHandler{data: []byte("0123456789")},
+	}
+	ctx := mcp.SetLogWriterToContext(context.Background(), &bytes.Buffer{})
+
+	cases := map[string]struct {
+		params any
+		want   string
+	}{
+		"no range": {
+			params: map[string]any{"uri": "file:///blob"},
+			want:   "0123456789",
+		},
+		"range": {
+			params: map[string]any{"uri": "file:///blob", "_meta": map[string]any{"range": map[string]any{"offset": 2, "length": 3}}},
+			want:   "234",
+		},
+		"range to the end": {
+			params: map[string]any{"uri": "file:///blob", "_meta": map[string]any{"range": map[string]any{"offset": 7}}},
+			want:   "789",
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := h.Handle(ctx, newCall(t, 1, protocol.MethodResourcesRead, c.params))
+			if err != nil {
+				t.Fatalf("resources/read returned an error: %v", err)
+			}
+			b, err := json.Marshal(res)
+			if err != nil {
+				t.Fatalf("failed to marshal the result: %v", err)
+			}
+			var got struct {
+				Contents []struct {
+					Data []byte `json:"data"`
+				} `json:"contents"`
+			}
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatalf("failed to unmarshal the result: %v", err)
+			}
+			if len(got.Contents) != 1 || string(got.Contents[0].Data) != c.want {
+				t.Errorf("want %s, got %s", c.want, b)
+			}
+		})
+	}
+
+	t.Run("invalid range", func(t *testing.T) {
+		t.Parallel()
+
+		params := map[string]any{"uri": "file:///blob", "_meta": map[string]any{"range": map[string]any{"offset": -1}}}
+		_, err := h.Handle(ctx, newCall(t, 1, protocol.MethodResourcesRead, params))
+		if !errors.Is(err, jsonrpc2.ErrInvalidParams) {
+			t.Errorf("want %v, got %v", jsonrpc2.ErrInvalidParams, err)
+		}
+	})
+}
+
+func TestHandler_NotifyResourceUpdated(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Resources: &protocol.ResourceCapability{Subscribe: true}},
+	}
+	notified := make(chan string, 1)
+	conn := serveWithClientHandler(t, h, jsonrpc2.HandlerFunc(func(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+		if req.Method == protocol.MethodNotificationsResourcesUpdated {
+			var params struct {
+				URI string `json:"uri"`
+			}
+			if err := json.Unmarshal(req.Params, &params); err != nil {
+				t.Errorf("failed to unmarshal params: %v", err)
+			}
+			notified <- params.URI
+		}
+		return nil, nil
+	}))
+
+	otherNotified := make(chan struct{}, 1)
+	other := serveWithClientHandler(t, h, jsonrpc2.HandlerFunc(func(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+		if req.Method == protocol.MethodNotificationsResourcesUpdated {
+			otherNotified <- struct{}{}
+		}
+		return nil, nil
+	}))
+
+	ctx := context.Background()
+	if err := conn.Call(ctx, protocol.MethodResourcesSubscribe, map[string]any{"uri": "file:///a"}).Await(ctx, nil); err != nil {
+		t.Fatalf("resources/subscribe returned an error: %v", err)
+	}
+	if err := other.Call(ctx, protocol.MethodResourcesSubscribe, map[string]any{"uri": "weather://forecast/*"}).Await(ctx, nil); err != nil {
+		t.Fatalf("resources/subscribe returned an error: %v", err)
+	}
+
+	// Unsubscribed resources are not notified.
+	if err := h.NotifyResourceUpdated(ctx, "file:///b"); err != nil {
+		t.Fatalf("NotifyResourceUpdated returned an error: %v", err)
+	}
+	if err := h.NotifyResourceUpdated(ctx, "file:///a"); err != nil {
+		t.Fatalf("NotifyResourceUpdated returned an error: %v", err)
+	}
+
+	select {
+	case uri := <-notified:
+		if uri != "file:///a" {
+			t.Errorf("want file:///a, got %s", uri)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification was not received")
+	}
+
+	// Only connections subscribing to the resource are notified.
+	select {
+	case <-otherNotified:
+		t.Error("want no notification for the connection which doesn't subscribe to the resource")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// A resource subscribed by a glob pattern is notified.
+	if err := h.NotifyResourceUpdated(ctx, "weather://forecast/tokyo"); err != nil {
+		t.Fatalf("NotifyResourceUpdated returned an error: %v", err)
+	}
+	select {
+	case <-otherNotified:
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification was not received")
+	}
+	select {
+	case uri := <-notified:
+		t.Errorf("want no notification for the connection which doesn't subscribe to the resource, got %s", uri)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestHandler_PageSize(t *testing.T) {
+	t.Parallel()
+
+	h := toolsHandler(5)
+	h.PageSize = 2
+	client := newInMemoryClient(t, h)
+	ctx := context.Background()
+
+	var (
+		pages  int
+		names  []string
+		cursor string
+	)
+	for {
+		res, err := client.Call(ctx, protocol.MethodToolsList, protocol.PaginationParams{Cursor: cursor})
+		if err != nil {
+			t.Fatalf("tools/list returned an error: %v", err)
+		}
+		var got struct {
+			Tools      []protocol.Tool `json:"tools"`
+			NextCursor string          `json:"nextCursor"`
+		}
+		if err := json.Unmarshal(res, &got); err != nil {
+			t.Fatalf("failed to unmarshal the result: %v", err)
+		}
+		pages++
+		for _, tool := range got.Tools {
+			names = append(names, tool.Name)
+		}
+		if got.NextCursor == "" {
+			break
+		}
+		cursor = got.NextCursor
+	}
+
+	want := []string{"tool_0", "tool_1", "tool_2", "tool_3", "tool_4"}
+	if !slices.Equal(want, names) {
+		t.Errorf("want %v, got %v", want, names)
+	}
+	if pages != 3 {
+		t.Errorf("want 3 pages, got %d", pages)
+	}
+}
+
+type streamResourceHandler struct {
+	mcp.ServerResourceHandler
+	names      []string
+	enumerated atomic.Int64
+}
+
+func (h *streamResourceHandler) HandleResourcesListStream(ctx context.Context) iter.Seq2[mcp.Resource, error] {
+	return func(yield func(mcp.Resource, error) bool) {
+		for _, name := range h.names {
+			h.enumerated.Add(1)
+			if !yield(mcp.Resource{URI: "file:///" + name, Name: name}, nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestHandler_ResourcesListStream(t *testing.T) {
+	t.Parallel()
+
+	rh := &streamResourceHandler{names: []string{"a", "b", "c", "d", "e"}}
+	h := &mcp.Handler{
+		Capabilities:    protocol.ServerCapabilities{Resources: &protocol.ResourceCapability{}},
+		ResourceHandler: rh,
+		PageSize:        2,
+	}
+	ctx := context.Background()
+
+	list := func(cursor string) *mcp.ListResourcesResult {
+		t.Helper()
+		res, err := h.Handle(ctx, newCall(t, 1, protocol.MethodResourcesList, protocol.PaginationParams{Cursor: cursor}))
+		if err != nil {
+			t.Fatalf("resources/list returned an error: %v", err)
+		}
+		return res.(*mcp.ListResourcesResult)
+	}
+
+	res := list("")
+	// The first page is returned without enumerating the rest of the resources.
+	if n := rh.enumerated.Load(); n != 3 {
+		t.Errorf("want 3 resources to be enumerated, got %d", n)
+	}
+
+	var names []string
+	for {
+		for _, r := range res.Resources {
+			names = append(names, r.Name)
+		}
+		if res.NextCursor == "" {
+			break
+		}
+		res = list(res.NextCursor)
+	}
+	if want := rh.names; !slices.Equal(want, names) {
+		t.Errorf("want %v, got %v", want, names)
+	}
+
+	t.Run("stale cursor", func(t *testing.T) {
+		t.Parallel()
+
+		rh := &streamResourceHandler{names: []string{"a", "b", "c"}}
+		h := &mcp.Handler{ResourceHandler: rh, PageSize: 2}
+		res, err := h.Handle(ctx, newCall(t, 1, protocol.MethodResourcesList, struct{}{}))
+		if err != nil {
+			t.Fatalf("resources/list returned an error: %v", err)
+		}
+		cursor := res.(*mcp.ListResourcesResult).NextCursor
+
+		rh.names = []string{"x", "b", "c"}
+		_, err = h.Handle(ctx, newCall(t, 2, protocol.MethodResourcesList, protocol.PaginationParams{Cursor: cursor}))
+		if !errors.Is(err, mcp.ErrStaleCursor) {
+			t.Errorf("want %v, got %v", mcp.ErrStaleCursor, err)
+		}
+	})
+}
+
+func TestHandler_SetTools(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{ListChanged: true}},
+		Tools:        []protocol.Tool{{Name: "a"}},
+	}
+	notified := make(chan struct{}, 1)
+	conn := serveWithClientHandler(t, h, jsonrpc2.HandlerFunc(func(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+		if req.Method == protocol.MethodNotificationsToolsListChanged {
+			notified <- struct{}{}
+		}
+		return nil, nil
+	}))
+
+	ctx := context.Background()
+	// Wait for the connection to be bound before sending notifications.
+	if err := conn.Call(ctx, protocol.MethodPing, struct{}{}).Await(ctx, nil); err != nil {
+		t.Fatalf("ping returned an error: %v", err)
+	}
+	if err := h.SetTools(ctx, []protocol.Tool{{Name: "b"}}); err != nil {
+		t.Fatalf("SetTools returned an error: %v", err)
+	}
+
+	select {
+	case <-notified:
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification was not received")
+	}
+
+	var res struct {
+		Tools []protocol.Tool `json:"tools"`
+	}
+	if err := conn.Call(ctx, protocol.MethodToolsList, struct{}{}).Await(ctx, &res); err != nil {
+		t.Fatalf("tools/list returned an error: %v", err)
+	}
+	if len(res.Tools) != 1 || res.Tools[0].Name != "b" {
+		t.Errorf("want tool b, got %+v", res.Tools)
+	}
+}
+
+func TestHandler_Registered(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Prompts:           []protocol.Prompt{{Name: "weather_report"}},
+		Tools:             []protocol.Tool{{Name: "convert_temperature"}},
+		ResourceTemplates: []mcp.ResourceTemplate{{URITemplate: "weather://forecast/{city}"}},
+	}
+
+	// The returned lists are copies, so modifying them doesn't affect the handler.
+	tools := h.RegisteredTools()
+	tools[0].Name = "modified"
+	if got := h.RegisteredTools()[0].Name; got != "convert_temperature" {
+		t.Errorf("want convert_temperature, got %s", got)
+	}
+	prompts := h.RegisteredPrompts()
+	prompts[0].Name = "modified"
+	if got := h.RegisteredPrompts()[0].Name; got != "weather_report" {
+		t.Errorf("want weather_report, got %s", got)
+	}
+	templates := h.RegisteredResourceTemplates()
+	templates[0].URITemplate = "modified"
+	if got := h.RegisteredResourceTemplates()[0].URITemplate; got != "weather://forecast/{city}" {
+		t.Errorf("want weather://forecast/{city}, got %s", got)
+	}
+}
+
+func TestHandler_RedactToolArguments(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		SensitiveToolArguments: map[string][]string{"search": {"api_key"}},
+	}
+
+	cases := map[string]struct {
+		params protocol.CallToolRequestParams
+		want   string
+	}{
+		"sensitive argument": {
+			params: protocol.CallToolRequestParams{Name: "search", Arguments: json.RawMessage(`{"api_key":"secret","query":"go"}`)},
+			want:   `{"api_key":"[REDACTED]","query":"go"}`,
+		},
+		"no sensitive arguments": {
+			params: protocol.CallToolRequestParams{Name: "echo", Arguments: json.RawMessage(`{"api_key":"secret"}`)},
+			want:   `{"api_key":"secret"}`,
+		},
+		"invalid arguments": {
+			params: protocol.CallToolRequestParams{Name: "search", Arguments: json.RawMessage(`"secret"`)},
+			want:   ``,
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := h.RedactToolArguments(c.params)
+			if string(got) != c.want {
+				t.Errorf("want %s, got %s", c.want, got)
+			}
+		})
+	}
+}
+
+func TestHandler_NotifyListChanged(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{
+			Prompts:   &protocol.PromptCapability{ListChanged: true},
+			Resources: &protocol.ResourceCapability{},
+		},
+	}
+	notified := make(chan string, 2)
+	conn := serveWithClientHandler(t, h, jsonrpc2.HandlerFunc(func(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+		notified <- req.Method
+		return nil, nil
+	}))
+
+	ctx := context.Background()
+	// Wait for the connection to be bound before sending notifications.
+	if err := conn.Call(ctx, protocol.MethodPing, struct{}{}).Await(ctx, nil); err != nil {
+		t.Fatalf("ping returned an error: %v", err)
+	}
+
+	// The Resources capability doesn't enable ListChanged, so nothing is sent.
+	if err := h.NotifyResourcesListChanged(ctx); err != nil {
+		t.Fatalf("NotifyResourcesListChanged returned an error: %v", err)
+	}
+	if err := h.NotifyPromptsListChanged(ctx); err != nil {
+		t.Fatalf("NotifyPromptsListChanged returned an error: %v", err)
+	}
+
+	select {
+	case method := <-notified:
+		if method != protocol.MethodNotificationsPromptsListChanged {
+			t.Errorf("want %s, got %s", protocol.MethodNotificationsPromptsListChanged, method)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification was not received")
+	}
+}
+
+func TestHandler_MaxSubscriptions(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities:     protocol.ServerCapabilities{Resources: &protocol.ResourceCapability{Subscribe: true}},
+		MaxSubscriptions: 2,
+	}
+	client := newInMemoryClient(t, h)
+	ctx := context.Background()
+
+	call := func(method, uri string) error {
+		_, err := client.Call(ctx, method, map[string]any{"uri": uri})
+		return err
+	}
+
+	if err := call(protocol.MethodResourcesSubscribe, "weather://forecast/tokyo"); err != nil {
+		t.Fatalf("resources/subscribe returned an error: %v", err)
+	}
+	if err := call(protocol.MethodResourcesSubscribe, "weather://forecast/*"); err != nil {
+		t.Fatalf("resources/subscribe returned an error: %v", err)
+	}
+	// Subscribing to an already subscribed resource doesn't count.
+	if err := call(protocol.MethodResourcesSubscribe, "weather://forecast/tokyo"); err != nil {
+		t.Fatalf("resources/subscribe returned an error: %v", err)
+	}
+
+	err := call(protocol.MethodResourcesSubscribe, "weather://forecast/london")
+	if err == nil || !strings.Contains(err.Error(), "too many subscriptions") {
+		t.Fatalf("want a too many subscriptions error, got %v", err)
+	}
+
+	if err := call(protocol.MethodResourcesUnsubscribe, "weather://forecast/tokyo"); err != nil {
+		t.Fatalf("resources/unsubscribe returned an error: %v", err)
+	}
+	if err := call(protocol.MethodResourcesSubscribe, "weather://forecast/london"); err != nil {
+		t.Errorf("resources/subscribe after unsubscribing returned an error: %v", err)
+	}
+
+	// Only "uris" can be specified, and the missing "uri" doesn't count.
+	if err := call(protocol.MethodResourcesUnsubscribe, "weather://forecast/london"); err != nil {
+		t.Fatalf("resources/unsubscribe returned an error: %v", err)
+	}
+	if _, err := client.Call(ctx, protocol.MethodResourcesSubscribe, map[string]any{"uris": []string{"weather://forecast/london"}}); err != nil {
+		t.Errorf("resources/subscribe with only uris returned an error: %v", err)
+	}
+
+	// The limit is per connection.
+	other := newInMemoryClient(t, h)
+	if _, err := other.Call(ctx, protocol.MethodResourcesSubscribe, map[string]any{"uri": "weather://forecast/paris"}); err != nil {
+		t.Errorf("resources/subscribe from another connection returned an error: %v", err)
+	}
+}
+
+func TestHandler_IsSubscribed(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Resources: &protocol.ResourceCapability{Subscribe: true}},
+	}
+	client, err := mcp.NewInMemoryTransport(context.Background(), h)
+	if err != nil {
+		t.Fatalf("failed to create an in-memory transport: %v", err)
+	}
+	// client is closed by the test to release its subscriptions.
+	ctx := context.Background()
+	if _, err := client.Initialize(ctx, protocol.InitializeRequestParams{ProtocolVersion: protocol.LatestProtocolVersion}); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	other := newInMemoryClient(t, h)
+
+	params := map[string]any{
+		"uri":  "file:///a",
+		"uris": []string{"file:///b?x=1", "weather://forecast/*"},
+	}
+	if _, err := client.Call(ctx, protocol.MethodResourcesSubscribe, params); err != nil {
+		t.Fatalf("resources/subscribe returned an error: %v", err)
+	}
+	if _, err := other.Call(ctx, protocol.MethodResourcesSubscribe, map[string]any{"uri": "file:///c"}); err != nil {
+		t.Fatalf("resources/subscribe returned an error: %v", err)
+	}
+
+	cases := map[string]bool{
+		"file:///a":                  true,
+		"file:///b?x=1":              true,
+		"file:///b?x=2":              false,
+		"file:///c":                  true,
+		"weather://forecast/tokyo":   true,
+		"weather://forecast/":        true,
+		"weather://forecast/tokyo/1": false,
+		"weather://current/tokyo":    false,
+	}
+	for uri, want := range cases {
+		if got := h.IsSubscribed(uri); got != want {
+			t.Errorf("IsSubscribed(%q): want %t, got %t", uri, want, got)
+		}
+	}
+
+	// Unsubscribing from another connection doesn't affect the subscriptions of the connection.
+	if _, err := other.Call(ctx, protocol.MethodResourcesUnsubscribe, map[string]any{"uri": "file:///a"}); err != nil {
+		t.Fatalf("resources/unsubscribe returned an error: %v", err)
+	}
+	if !h.IsSubscribed("file:///a") {
+		t.Error("want file:///a to be subscribed")
+	}
+
+	if _, err := client.Call(ctx, protocol.MethodResourcesUnsubscribe, map[string]any{"uri": "weather://forecast/*"}); err != nil {
+		t.Fatalf("resources/unsubscribe returned an error: %v", err)
+	}
+	if h.IsSubscribed("weather://forecast/tokyo") {
+		t.Error("want weather://forecast/tokyo to be unsubscribed")
+	}
+
+	// Subscriptions are released when the connection is closed.
+	client.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for h.IsSubscribed("file:///a") {
+		if time.Now().After(deadline) {
+			t.Fatal("want file:///a to be unsubscribed after the connection is closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !h.IsSubscribed("file:///c") {
+		t.Error("want file:///c to be subscribed by the other connection")
+	}
+}
+
+func TestHandler_LoggingSetLevel(t *testing.T) {
+	t.Parallel()
+
+	newHandler := func() *mcp.Handler {
+		return &mcp.Handler{
+			Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}, Logging: &protocol.LoggingCapability{}},
+			ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+				mcp.Logger(ctx, "test").Debug("debug message")
+				return &mcp.CallToolResult{}, nil
+			}),
+		}
+	}
+	h1, h2 := newHandler(), newHandler()
+
+	var buf1, buf2 bytes.Buffer
+	ctx1 := mcp.SetLogWriterToContext(context.Background(), &buf1)
+	ctx2 := mcp.SetLogWriterToContext(context.Background(), &buf2)
+
+	// Only h1 receives logging/setLevel.
+	if _, err := h1.Handle(ctx1, newCall(t, 1, protocol.MethodLoggingSetLevel, map[string]any{"level": "debug"})); err != nil {
+		t.Fatalf("logging/setLevel returned an error: %v", err)
+	}
+	for _, c := range []struct {
+		h   *mcp.Handler
+		ctx context.Context
+	}{{h1, ctx1}, {h2, ctx2}} {
+		if _, err := c.h.Handle(c.ctx, newCall(t, 2, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "log"})); err != nil {
+			t.Fatalf("tools/call returned an error: %v", err)
+		}
+	}
+
+	if !bytes.Contains(buf1.Bytes(), []byte("debug message")) {
+		t.Errorf("want the debug message to be logged by h1, got %q", buf1.String())
+	}
+	if buf2.Len() != 0 {
+		t.Errorf("want no logs from h2, got %q", buf2.String())
+	}
+}
+
+func TestHandler_SuppressLogsBeforeSetLevel(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}, Logging: &protocol.LoggingCapability{}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			mcp.Logger(ctx, "test").Error("error message")
+			return &mcp.CallToolResult{}, nil
+		}),
+		SuppressLogsBeforeSetLevel: true,
+	}
+	var buf bytes.Buffer
+	ctx := mcp.SetLogWriterToContext(context.Background(), &buf)
+	callTool := func() {
+		t.Helper()
+		if _, err := h.Handle(ctx, newCall(t, 1, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "log"})); err != nil {
+			t.Fatalf("tools/call returned an error: %v", err)
+		}
+	}
+
+	callTool()
+	if buf.Len() != 0 {
+		t.Errorf("want no logs before logging/setLevel, got %q", buf.String())
+	}
+
+	if _, err := h.Handle(ctx, newCall(t, 2, protocol.MethodLoggingSetLevel, map[string]any{"level": "error"})); err != nil {
+		t.Fatalf("logging/setLevel returned an error: %v", err)
+	}
+	callTool()
+	if !bytes.Contains(buf.Bytes(), []byte(`"level":"error"`)) {
+		t.Errorf("want the error message to be logged after logging/setLevel, got %q", buf.String())
+	}
+}
+
+func TestHandler_CompletableVariables(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Completions: &protocol.CompletionsCapability{}},
+		ResourceTemplates: []mcp.ResourceTemplate{
+			{URITemplate: "weather://historical/{city}/{date}", CompletableVariables: []string{"city"}},
+		},
+		CompletionHandler: completionHandlerFunc(func(ctx context.Context, req *mcp.CompleteRequestParams) (*mcp.CompleteResult, error) {
+			return &mcp.CompleteResult{Values: []string{"Tokyo"}}, nil
+		}),
+	}
+	ctx := mcp.SetLogWriterToContext(context.Background(), &bytes.Buffer{})
+
+	cases := map[string]struct {
+		argument string
+		want     string
+	}{
+		"completable":     {argument: "city", want: `{"completion":{"values":["Tokyo"]}}`},
+		"not completable": {argument: "date", want: `{"completion":{"values":[]}}`},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := h.Handle(ctx, newCall(t, 1, protocol.MethodCompletionComplete, mcp.CompleteRequestParams{
+				Ref:      mcp.Reference{Type: mcp.CompletionReferenceTypeResource, URI: "weather://historical/{city}/{date}"},
+				Argument: mcp.CompletionArgument{Name: c.argument, Value: "T"},
+			}))
+			if err != nil {
+				t.Fatalf("completion/complete returned an error: %v", err)
+			}
+			b, err := json.Marshal(res)
+			if err != nil {
+				t.Fatalf("failed to marshal the result: %v", err)
+			}
+			if string(b) != c.want {
+				t.Errorf("want %s, got %s", c.want, b)
+			}
+		})
+	}
+}
+
+func TestHandler_Handle_CompletionContext(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Completions: &protocol.CompletionsCapability{}},
+		CompletionHandler: completionHandlerFunc(func(ctx context.Context, req *mcp.CompleteRequestParams) (*mcp.CompleteResult, error) {
+			if req.Context == nil {
+				return &mcp.CompleteResult{Values: []string{"Tokyo", "New York"}}, nil
+			}
+			cities := map[string][]string{"jp": {"Tokyo"}, "us": {"New York"}}
+			return &mcp.CompleteResult{Values: cities[req.Context.Arguments["country"]]}, nil
+		}),
+	}
+	ctx := mcp.SetLogWriterToContext(context.Background(), &bytes.Buffer{})
+
+	cases := map[string]struct {
+		params string
+		want   string
+	}{
+		"context":    {params: `,"context":{"arguments":{"country":"jp"}}`, want: `{"completion":{"values":["Tokyo"]}}`},
+		"no context": {want: `{"completion":{"values":["Tokyo","New York"]}}`},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			params := json.RawMessage(`{"ref":{"type":"ref/prompt","name":"weather_report"},"argument":{"name":"city","value":""}` + c.params + `}`)
+			res, err := h.Handle(ctx, newCall(t, 1, protocol.MethodCompletionComplete, params))
+			if err != nil {
+				t.Fatalf("completion/complete returned an error: %v", err)
+			}
+			b, err := json.Marshal(res)
+			if err != nil {
+				t.Fatalf("failed to marshal the result: %v", err)
+			}
+			if string(b) != c.want {
+				t.Errorf("want %s, got %s", c.want, b)
+			}
+		})
+	}
+}
+
+func TestHandler_Handle_CompletionValuesLimit(t *testing.T) {
+	t.Parallel()
+
+	values := make([]string, 150)
+	for i := range values {
+		values[i] = fmt.Sprintf("value_%d", i)
+	}
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Completions: &protocol.CompletionsCapability{}},
+		CompletionHandler: completionHandlerFunc(func(ctx context.Context, req *mcp.CompleteRequestParams) (*mcp.CompleteResult, error) {
+			return &mcp.CompleteResult{Values: values}, nil
+		}),
+	}
+	client := newInMemoryClient(t, h)
+
+	res, err := client.Call(context.Background(), protocol.MethodCompletionComplete, mcp.CompleteRequestParams{
+		Ref:      mcp.Reference{Type: mcp.CompletionReferenceTypePrompt, Name: "prompt"},
+		Argument: mcp.CompletionArgument{Name: "arg", Value: "v"},
+	})
+	if err != nil {
+		t.Fatalf("completion/complete returned an error: %v", err)
+	}
+	var got struct {
+		Completion mcp.CompleteResult `json:"completion"`
+	}
+	if err := json.Unmarshal(res, &got); err != nil {
+		t.Fatalf("failed to unmarshal the result: %v", err)
+	}
+	if len(got.Completion.Values) != 100 {
+		t.Errorf("want 100 values, got %d", len(got.Completion.Values))
+	}
+	if !got.Completion.HasMore {
+		t.Error("want hasMore to be true")
+	}
+	if got.Completion.Total != 150 {
+		t.Errorf("want total 150, got %d", got.Completion.Total)
+	}
+}
+
+func TestHandler_Handle_CompletionNilResult(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Completions: &protocol.CompletionsCapability{}},
+		CompletionHandler: completionHandlerFunc(func(ctx context.Context, req *mcp.CompleteRequestParams) (*mcp.CompleteResult, error) {
+			return nil, nil
+		}),
+		DisablePanicRecovery: true,
+	}
+	client := newInMemoryClient(t, h)
+
+	res, err := client.Call(context.Background(), protocol.MethodCompletionComplete, mcp.CompleteRequestParams{
+		Ref:      mcp.Reference{Type: mcp.CompletionReferenceTypePrompt, Name: "prompt"},
+		Argument: mcp.CompletionArgument{Name: "arg", Value: "v"},
+	})
+	if err != nil {
+		t.Fatalf("completion/complete returned an error: %v", err)
+	}
+	if want := `{"completion":{"values":[]}}`; string(res) != want {
+		t.Errorf("want %s, got %s", want, res)
+	}
+}
+
+func TestHandler_Handle_CompletionItems(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Completions: &protocol.CompletionsCapability{}},
+		CompletionHandler: completionHandlerFunc(func(ctx context.Context, req *mcp.CompleteRequestParams) (*mcp.CompleteResult, error) {
+			return &mcp.CompleteResult{Items: []mcp.CompletionItem{{Value: "130010", Label: "Tokyo", Description: "Tokyo, Japan"}}}, nil
+		}),
+	}
+
+	cases := map[string]struct {
+		capabilities protocol.ClientCapabilities
+		want         string
+	}{
+		"labeled": {
+			capabilities: protocol.ClientCapabilities{Experimental: map[string]any{mcp.CompletionItemsCapability: map[string]any{}}},
+			want:         `{"completion":{"values":[{"value":"130010","label":"Tokyo","description":"Tokyo, Japan"}]}}`,
+		},
+		"fallback": {
+			want: `{"completion":{"values":["130010"]}}`,
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			conn := serveWithClientHandler(t, h, jsonrpc2.HandlerFunc(func(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+				return nil, nil
+			}))
+			ctx := context.Background()
+			params := protocol.InitializeRequestParams{ProtocolVersion: protocol.LatestProtocolVersion, Capabilities: c.capabilities}
+			if err := conn.Call(ctx, protocol.MethodInitialize, params).Await(ctx, nil); err != nil {
+				t.Fatalf("initialize returned an error: %v", err)
+			}
+
+			var res json.RawMessage
+			err := conn.Call(ctx, protocol.MethodCompletionComplete, mcp.CompleteRequestParams{
+				Ref:      mcp.Reference{Type: mcp.CompletionReferenceTypePrompt, Name: "weather_report"},
+				Argument: mcp.CompletionArgument{Name: "city", Value: "T"},
+			}).Await(ctx, &res)
+			if err != nil {
+				t.Fatalf("completion/complete returned an error: %v", err)
+			}
+			if string(res) != c.want {
+				t.Errorf("want %s, got %s", c.want, res)
+			}
+		})
+	}
+}
+
+func TestStdioTransport_ContentLengthFraming(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			return &mcp.CallToolResult{Content: []mcp.CallToolContent{mcp.TextContent{Text: "hello, " + req.Name}}}, nil
+		}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	listener, err := jsonrpc2.NetPipe(ctx)
+	if err != nil {
+		t.Fatalf("failed to create a listener: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	ctx, _, binder := mcp.NewStdioTransport(ctx, h, &mcp.StdioTransportOptions{ContentLengthFraming: true})
+	if _, err := jsonrpc2.Serve(ctx, listener, binder); err != nil {
+		t.Fatalf("failed to serve: %v", err)
+	}
+	client, err := jsonrpc2.Dial(ctx, listener.Dialer(), jsonrpc2.ConnectionOptions{Framer: jsonrpc2.HeaderFramer()})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	initialize(t, client)
+
+	var res json.RawMessage
+	if err := client.Call(ctx, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "greet"}).Await(ctx, &res); err != nil {
+		t.Fatalf("tools/call returned an error: %v", err)
+	}
+	want := `{"content":[{"type":"text","text":"hello, greet"}]}`
+	if string(res) != want {
+		t.Errorf("want %s, got %s", want, res)
+	}
+
+	// The connection keeps working for subsequent messages.
+	if err := client.Call(ctx, protocol.MethodPing, struct{}{}).Await(ctx, nil); err != nil {
+		t.Errorf("ping returned an error: %v", err)
+	}
+}
+
+func TestStdioTransport_RequestTimeout(t *testing.T) {
+	t.Parallel()
+
+	canceled := make(chan struct{})
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			<-ctx.Done()
+			close(canceled)
+			return nil, ctx.Err()
+		}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	listener, err := jsonrpc2.NetPipe(ctx)
+	if err != nil {
+		t.Fatalf("failed to create a listener: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	ctx, _, binder := mcp.NewStdioTransport(ctx, h, &mcp.StdioTransportOptions{RequestTimeout: 50 * time.Millisecond})
+	if _, err := jsonrpc2.Serve(ctx, listener, binder); err != nil {
+		t.Fatalf("failed to serve: %v", err)
+	}
+	client, err := jsonrpc2.Dial(ctx, listener.Dialer(), jsonrpc2.ConnectionOptions{Framer: jsonrpc2.RawFramer()})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	initialize(t, client)
+
+	err = client.Call(ctx, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "slow"}).Await(ctx, nil)
+	if err == nil || !strings.Contains(err.Error(), mcp.ErrRequestTimeout.Error()) {
+		t.Fatalf("want an error containing %q, got %v", mcp.ErrRequestTimeout, err)
+	}
+	select {
+	case <-canceled:
+	default:
+		t.Error("want the context of the handler to be canceled")
+	}
+
+	// Requests within the timeout succeed.
+	if err := client.Call(ctx, protocol.MethodPing, struct{}{}).Await(ctx, nil); err != nil {
+		t.Errorf("ping returned an error: %v", err)
+	}
+}
+
+func TestStdioTransport_RequestTimeout_ToolTimeout(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			select {
+			case <-time.After(200 * time.Millisecond):
+				return mcp.NewTextToolResult("done"), nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}),
+	}
+	// The tool timeout is longer than the request timeout of the transport, and replaces it.
+	h.SetToolTimeout("slow", 2*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	listener, err := jsonrpc2.NetPipe(ctx)
+	if err != nil {
+		t.Fatalf("failed to create a listener: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	ctx, _, binder := mcp.NewStdioTransport(ctx, h, &mcp.StdioTransportOptions{RequestTimeout: 50 * time.Millisecond})
+	if _, err := jsonrpc2.Serve(ctx, listener, binder); err != nil {
+		t.Fatalf("failed to serve: %v", err)
+	}
+	client, err := jsonrpc2.Dial(ctx, listener.Dialer(), jsonrpc2.ConnectionOptions{Framer: jsonrpc2.RawFramer()})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	initialize(t, client)
+
+	if err := client.Call(ctx, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "slow"}).Await(ctx, nil); err != nil {
+		t.Errorf("tools/call of the tool with its own timeout returned an error: %v", err)
+	}
+
+	// Other tools are still limited by the request timeout.
+	err = client.Call(ctx, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "other"}).Await(ctx, nil)
+	if err == nil || !strings.Contains(err.Error(), mcp.ErrRequestTimeout.Error()) {
+		t.Errorf("want an error containing %q, got %v", mcp.ErrRequestTimeout, err)
+	}
+}
+
+// serve serves h over an in-process pipe and returns an initialized client connection to it.
+func serve(t *testing.T, h *mcp.Handler) *jsonrpc2.Connection {
+	t.Helper()
+
+	return serveWithClientHandler(t, h, nil)
+}
+
+// serveWithClientHandler is like serve, but the client handles server-initiated messages with clientHandler.
+func serveWithClientHandler(t *testing.T, h *mcp.Handler, clientHandler jsonrpc2.Handler) *jsonrpc2.Connection {
+	t.Helper()
+
+	conn := serveUninitialized(t, h, clientHandler)
+	initialize(t, conn)
+	return conn
+}
+
+// serveUninitialized is like serveWithClientHandler, but the session of the returned connection isn't initialized.
+func serveUninitialized(t *testing.T, h *mcp.Handler, clientHandler jsonrpc2.Handler) *jsonrpc2.Connection {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	listener, err := jsonrpc2.NetPipe(ctx)
+	if err != nil {
+		t.Fatalf("failed to create a listener: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	ctx, _, binder := mcp.NewStdioTransport(ctx, h, nil)
+	if _, err := jsonrpc2.Serve(ctx, listener, binder); err != nil {
+		t.Fatalf("failed to serve: %v", err)
+	}
+
+	conn, err := jsonrpc2.Dial(ctx, listener.Dialer(), jsonrpc2.ConnectionOptions{Framer: jsonrpc2.RawFramer(), Handler: clientHandler})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	return conn
+}
+
+// initialize initializes the session of conn with the latest protocol version.
+// Tests which depend on the params of the initialize request may initialize the session again.
+func initialize(t *testing.T, conn *jsonrpc2.Connection) {
+	t.Helper()
+
+	ctx := context.Background()
+	params := protocol.InitializeRequestParams{ProtocolVersion: protocol.LatestProtocolVersion}
+	if err := conn.Call(ctx, protocol.MethodInitialize, params).Await(ctx, nil); err != nil {
+		t.Fatalf("initialize returned an error: %v", err)
+	}
+	if err := conn.Notify(ctx, protocol.MethodNotificationsInitialized, struct{}{}); err != nil {
+		t.Fatalf("failed to send %s: %v", protocol.MethodNotificationsInitialized, err)
+	}
+}
+
+func newCall(t *testing.T, id int64, method string, params any) *jsonrpc2.Request {
+	t.Helper()
+
+	req, err := jsonrpc2.NewCall(jsonrpc2.Int64ID(id), method, params)
+	if err != nil {
+		t.Fatalf("failed to create a request: %v", err)
+	}
+	return req
+}
+
+type toolHandlerFunc func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error)
+
+func (f toolHandlerFunc) Handle(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+	return f(ctx, method, req)
+}
+
+type promptHandlerFunc func(ctx context.Context, method string, req protocol.GetPromptRequestParams) (any, error)
+
+func (f promptHandlerFunc) Handle(ctx context.Context, method string, req protocol.GetPromptRequestParams) (any, error) {
+	return f(ctx, method, req)
+}
+
+type completionHandlerFunc func(ctx context.Context, req *mcp.CompleteRequestParams) (*mcp.CompleteResult, error)
+
+func (f completionHandlerFunc) HandleComplete(ctx context.Context, req *mcp.CompleteRequestParams) (*mcp.CompleteResult, error) {
+	return f(ctx, req)
+}
+
+type blobResourceHandler struct {
+	data []byte
+}
+
+func (h *blobResourceHandler) HandleResourcesList(ctx context.Context) (*mcp.ListResourcesResult, error) {
+	return &mcp.ListResourcesResult{}, nil
+}
+
+func (h *blobResourceHandler) HandleResourcesRead(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	var blob io.Reader = bytes.NewReader(h.data)
+	if r, ok := mcp.RequestedByteRange(ctx); ok {
+		blob = r.Section(bytes.NewReader(h.data))
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []mcp.ResourceContent{mcp.BlobResourceContent{URI: req.URI, Blob: blob}},
+	}, nil
+}
@@ -0,0 +1,77 @@
+// Package mcptest provides utilities for testing MCP servers.
+package mcptest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	mcp "github.com/ktr0731/go-mcp"
+	"github.com/ktr0731/go-mcp/protocol"
+)
+
+// Request is a request sent to a handler.
+type Request struct {
+	Method string
+	Params any
+}
+
+// ToolCall returns a tools/call request of the tool.
+func ToolCall(name string, args any) Request {
+	return Request{Method: protocol.MethodToolsCall, Params: map[string]any{"name": name, "arguments": args}}
+}
+
+// Subscribe returns a resources/subscribe request of the resource.
+func Subscribe(uri string) Request {
+	return Request{Method: protocol.MethodResourcesSubscribe, Params: map[string]any{"uri": uri}}
+}
+
+// Unsubscribe returns a resources/unsubscribe request of the resource.
+func Unsubscribe(uri string) Request {
+	return Request{Method: protocol.MethodResourcesUnsubscribe, Params: map[string]any{"uri": uri}}
+}
+
+// SetLevel returns a logging/setLevel request of the level, e.g. "debug".
+func SetLevel(level string) Request {
+	return Request{Method: protocol.MethodLoggingSetLevel, Params: map[string]any{"level": level}}
+}
+
+// RunConcurrently drives h with requests concurrently to surface data races in shared state of the handler.
+// It opens conns connections to h by mcp.NewInMemoryClient, and sends all requests concurrently over each connection.
+// Each connection is initialized before sending requests.
+// It fails t if a request returns an error.
+//
+// RunConcurrently is meant to be used with the race detector, e.g. go test -race.
+func RunConcurrently(t testing.TB, h *mcp.Handler, conns int, requests ...Request) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clients := make([]*mcp.Client, conns)
+	for i := range clients {
+		client, err := mcp.NewInMemoryClient(ctx, h, &mcp.ClientOptions{
+			ClientInfo: protocol.Implementation{Name: "mcptest", Version: "1.0.0"},
+		})
+		if err != nil {
+			t.Fatalf("failed to create an in-memory client: %v", err)
+		}
+		defer client.Close()
+		clients[i] = client
+	}
+
+	var wg sync.WaitGroup
+	for _, client := range clients {
+		for _, req := range requests {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				if _, err := client.Call(ctx, req.Method, req.Params); err != nil {
+					t.Errorf("%s returned an error: %v", req.Method, err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
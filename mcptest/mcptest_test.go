@@ -0,0 +1,35 @@
+package mcptest_test
+
+import (
+	"context"
+	"testing"
+
+	mcp "github.com/ktr0731/go-mcp"
+	"github.com/ktr0731/go-mcp/mcptest"
+	"github.com/ktr0731/go-mcp/protocol"
+)
+
+func TestRunConcurrently(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{
+			Tools:     &protocol.ToolCapability{},
+			Resources: &protocol.ResourceCapability{Subscribe: true},
+			Logging:   &protocol.LoggingCapability{},
+		},
+		ToolHandler: protocol.ServerHandlerFunc[protocol.CallToolRequestParams](func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			mcp.Logger(ctx, "test").Debug("called", "name", req.Name)
+			return &mcp.CallToolResult{Content: []mcp.CallToolContent{mcp.TextContent{Text: "ok"}}}, nil
+		}),
+	}
+
+	mcptest.RunConcurrently(t, h, 4,
+		mcptest.ToolCall("echo", map[string]any{"text": "hello"}),
+		mcptest.Subscribe("weather://forecast/tokyo"),
+		mcptest.Unsubscribe("weather://forecast/tokyo"),
+		mcptest.Subscribe("weather://forecast/*"),
+		mcptest.SetLevel("debug"),
+		mcptest.SetLevel("info"),
+	)
+}
@@ -0,0 +1,171 @@
+package mcp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"iter"
+
+	"golang.org/x/exp/jsonrpc2"
+)
+
+// ErrStaleCursor is returned by Paginate when the list has changed since the cursor was issued.
+// Clients should restart pagination from the beginning.
+var ErrStaleCursor = fmt.Errorf("%w: cursor is stale, restart pagination", jsonrpc2.ErrInvalidParams)
+
+// ErrCursorExpired is returned by Paginate when the cursor was issued with an incompatible cursor format,
+// e.g. by a previous version of the server. Clients should restart pagination from the beginning.
+var ErrCursorExpired = fmt.Errorf("%w: cursor expired, restart pagination", jsonrpc2.ErrInvalidParams)
+
+// paginationCursorVersion is the version of the cursor format.
+// Increment this when the format of paginationCursor changes.
+const paginationCursorVersion = 1
+
+// paginationCursor is the decoded form of an opaque pagination cursor.
+type paginationCursor struct {
+	// Version is the version of the cursor format.
+	Version int `json:"v"`
+	// Offset is the index of the first item of the next page.
+	Offset int `json:"o"`
+	// Digest is the digest of the list at the time the cursor was issued.
+	Digest uint64 `json:"d"`
+}
+
+// Paginate returns the page of items that starts at cursor, and the cursor for the next page.
+// cursor is typically the value returned by NextCursor. The returned next cursor is empty if the page is the last one.
+// If pageSize is zero or negative, all items are returned.
+//
+// The cursor records a digest of items, so if items changed between paginated requests
+// (e.g. after a list_changed notification), Paginate returns ErrStaleCursor instead of skipping or duplicating entries.
+func Paginate[T any](items []T, cursor string, pageSize int) ([]T, string, error) {
+	if pageSize <= 0 {
+		return items, "", nil
+	}
+
+	digest, err := digestItems(items)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var offset int
+	if cursor != "" {
+		c, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		if c.Digest != digest || c.Offset > len(items) {
+			return nil, "", ErrStaleCursor
+		}
+		offset = c.Offset
+	}
+
+	end := min(offset+pageSize, len(items))
+	if end == len(items) {
+		return items[offset:end], "", nil
+	}
+
+	next, err := encodeCursor(paginationCursor{Version: paginationCursorVersion, Offset: end, Digest: digest})
+	if err != nil {
+		return nil, "", err
+	}
+	return items[offset:end], next, nil
+}
+
+// paginateSeq is like Paginate, but it enumerates seq only up to the end of the page.
+// Because the items after the page are unknown, the cursor records the digest of the items before the next page,
+// so changes of the items after the page aren't detected.
+func paginateSeq[T any](seq iter.Seq2[T, error], cursor string, pageSize int) ([]T, string, error) {
+	var offset int
+	var digest uint64
+	if cursor != "" && pageSize > 0 {
+		c, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		offset, digest = c.Offset, c.Digest
+	}
+
+	h := fnv.New64a()
+	encoder := json.NewEncoder(h)
+	items := []T{}
+	var i int
+	var hasMore bool
+	for item, err := range seq {
+		if err != nil {
+			return nil, "", err
+		}
+		if pageSize > 0 && i == offset+pageSize {
+			hasMore = true
+			break
+		}
+		if i == offset && offset > 0 && h.Sum64() != digest {
+			return nil, "", ErrStaleCursor
+		}
+		if err := encoder.Encode(item); err != nil {
+			return nil, "", fmt.Errorf("failed to encode item: %w", err)
+		}
+		if i >= offset {
+			items = append(items, item)
+		}
+		i++
+	}
+	if i < offset || (i == offset && offset > 0 && h.Sum64() != digest) {
+		return nil, "", ErrStaleCursor
+	}
+	if !hasMore {
+		return items, "", nil
+	}
+
+	next, err := encodeCursor(paginationCursor{Version: paginationCursorVersion, Offset: i, Digest: h.Sum64()})
+	if err != nil {
+		return nil, "", err
+	}
+	return items, next, nil
+}
+
+// digestItems computes the digest of the JSON representation of items.
+func digestItems[T any](items []T) (uint64, error) {
+	h := fnv.New64a()
+	encoder := json.NewEncoder(h)
+	for _, item := range items {
+		if err := encoder.Encode(item); err != nil {
+			return 0, fmt.Errorf("failed to encode item: %w", err)
+		}
+	}
+	return h.Sum64(), nil
+}
+
+func encodeCursor(c paginationCursor) (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeCursor(s string) (*paginationCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid cursor", jsonrpc2.ErrInvalidParams)
+	}
+	var c struct {
+		Version int `json:"v"`
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("%w: invalid cursor", jsonrpc2.ErrInvalidParams)
+	}
+	// Check the version first because the rest of an old cursor may not be decodable.
+	if c.Version != paginationCursorVersion {
+		return nil, ErrCursorExpired
+	}
+
+	var cursor paginationCursor
+	if err := json.Unmarshal(b, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: invalid cursor", jsonrpc2.ErrInvalidParams)
+	}
+	if cursor.Offset < 0 {
+		return nil, fmt.Errorf("%w: invalid cursor", jsonrpc2.ErrInvalidParams)
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,99 @@
+package mcp_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	mcp "github.com/ktr0731/go-mcp"
+	"golang.org/x/exp/jsonrpc2"
+)
+
+func TestPaginate(t *testing.T) {
+	t.Parallel()
+
+	items := []string{"a", "b", "c", "d", "e"}
+
+	var (
+		got    []string
+		cursor string
+	)
+	for {
+		page, next, err := mcp.Paginate(items, cursor, 2)
+		if err != nil {
+			t.Fatalf("Paginate returned an error: %v", err)
+		}
+		got = append(got, page...)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	if !slices.Equal(items, got) {
+		t.Errorf("want %v, got %v", items, got)
+	}
+}
+
+func TestPaginate_StaleCursor(t *testing.T) {
+	t.Parallel()
+
+	items := []string{"a", "b", "c", "d", "e"}
+	_, cursor, err := mcp.Paginate(items, "", 2)
+	if err != nil {
+		t.Fatalf("Paginate returned an error: %v", err)
+	}
+
+	// An item is removed between paginated requests.
+	items = slices.Delete(items, 0, 1)
+
+	_, _, err = mcp.Paginate(items, cursor, 2)
+	if !errors.Is(err, mcp.ErrStaleCursor) {
+		t.Errorf("want %v, got %v", mcp.ErrStaleCursor, err)
+	}
+}
+
+func TestPaginate_ExpiredCursor(t *testing.T) {
+	t.Parallel()
+
+	items := []string{"a", "b", "c", "d", "e"}
+	// A cursor issued with an old cursor format that has no version.
+	cursor := base64.RawURLEncoding.EncodeToString([]byte(`{"o":2,"d":1}`))
+
+	_, _, err := mcp.Paginate(items, cursor, 2)
+	if !errors.Is(err, mcp.ErrCursorExpired) {
+		t.Errorf("want %v, got %v", mcp.ErrCursorExpired, err)
+	}
+}
+
+func TestPaginate_NegativeOffset(t *testing.T) {
+	t.Parallel()
+
+	items := []string{"a", "b", "c", "d", "e"}
+	_, cursor, err := mcp.Paginate(items, "", 2)
+	if err != nil {
+		t.Fatalf("Paginate returned an error: %v", err)
+	}
+
+	// Tamper with the offset of a cursor which has a valid digest.
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		t.Fatalf("failed to decode the cursor: %v", err)
+	}
+	var c map[string]any
+	if err := json.Unmarshal(b, &c); err != nil {
+		t.Fatalf("failed to unmarshal the cursor: %v", err)
+	}
+	c["o"] = -1
+	b, err = json.Marshal(c)
+	if err != nil {
+		t.Fatalf("failed to marshal the cursor: %v", err)
+	}
+
+	_, _, err = mcp.Paginate(items, base64.RawURLEncoding.EncodeToString(b), 2)
+	if !errors.Is(err, jsonrpc2.ErrInvalidParams) {
+		t.Errorf("want %v, got %v", jsonrpc2.ErrInvalidParams, err)
+	}
+}
@@ -0,0 +1,129 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/ktr0731/go-mcp/protocol"
+	"golang.org/x/exp/jsonrpc2"
+)
+
+// progressTokenKey is a key for retrieving the progress token of the request from the context
+type progressTokenKey struct{}
+
+// progressTokenFromRequest retrieves the progress token from _meta of the request.
+// It returns nil if the request doesn't have a progress token.
+func progressTokenFromRequest(req *jsonrpc2.Request) any {
+	var p struct {
+		Meta struct {
+			ProgressToken any `json:"progressToken"`
+		} `json:"_meta"`
+	}
+	// Invalid params are reported by the handler of the method, so the error is ignored here.
+	_ = JSONUnmarshal(req.Params, &p)
+	return p.Meta.ProgressToken
+}
+
+// ReportProgress sends a progress notification for the request being handled.
+// total is the total progress required if known, otherwise zero. message is an optional message describing the current progress.
+// It does nothing if the client didn't request progress notifications by a progress token.
+//
+// See https://modelcontextprotocol.io/specification/2025-03-26/basic/utilities/progress
+func ReportProgress(ctx context.Context, progress, total float64, message string) error {
+	token := ctx.Value(progressTokenKey{})
+	if token == nil {
+		return nil
+	}
+	s, ok := ctx.Value(sessionKey{}).(*session)
+	if !ok {
+		return errors.New("no connection to send progress notifications")
+	}
+	return s.conn.Notify(ctx, protocol.MethodNotificationsProgress, &protocol.ProgressNotificationParams{
+		ProgressToken: token,
+		Progress:      progress,
+		Total:         total,
+		Message:       message,
+	})
+}
+
+// progressHandlerKey is a key for retrieving the progress handler from the context
+type progressHandlerKey struct{}
+
+// WithProgressHandler returns a context that receives progress notifications of server-initiated requests.
+// Requests sent to the client with the returned context (e.g. sampling/createMessage) carry a progress token,
+// and f is called with each progress notification the client sends back for the request.
+// f is called by the reader of the connection, so it must not block.
+//
+// See https://modelcontextprotocol.io/specification/2025-03-26/basic/utilities/progress
+func WithProgressHandler(ctx context.Context, f func(*protocol.ProgressNotificationParams)) context.Context {
+	return context.WithValue(ctx, progressHandlerKey{}, f)
+}
+
+// call sends a server-initiated request to the client of the session and waits for the response.
+// If ctx has a progress handler, the request carries a progress token in _meta.
+func (s *session) call(ctx context.Context, method string, params, result any) error {
+	if f, ok := ctx.Value(progressHandlerKey{}).(func(*protocol.ProgressNotificationParams)); ok && f != nil {
+		token := s.lastProgressToken.Add(1)
+		key := strconv.FormatInt(token, 10)
+		s.progressHandlers.Store(key, f)
+		defer s.progressHandlers.Delete(key)
+
+		p, err := withProgressToken(params, token)
+		if err != nil {
+			return err
+		}
+		params = p
+	}
+
+	return s.conn.Call(ctx, method, params).Await(ctx, result)
+}
+
+// withProgressToken returns params with _meta.progressToken set to token.
+func withProgressToken(params any, token int64) (map[string]any, error) {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal params: %w", err)
+	}
+	m := map[string]any{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("params must be an object: %w", err)
+	}
+	meta, _ := m["_meta"].(map[string]any)
+	if meta == nil {
+		meta = map[string]any{}
+	}
+	meta["progressToken"] = token
+	m["_meta"] = meta
+	return m, nil
+}
+
+// handleProgress delivers a progress notification of a server-initiated request to its progress handler.
+// It is called by the reader of the connection, so that progress notifications are delivered in order with the response
+// of the request, even while the handler is awaiting the response. It reports whether msg is a progress notification.
+func (s *session) handleProgress(msg jsonrpc2.Message) bool {
+	req, ok := msg.(*jsonrpc2.Request)
+	if !ok || req.Method != protocol.MethodNotificationsProgress {
+		return false
+	}
+
+	var params protocol.ProgressNotificationParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		// Notifications have no response, so malformed ones are ignored.
+		return true
+	}
+	// Progress handlers are keyed by the JSON encoding of the token, because the token is sent as an integer
+	// but decoded as a float64, which fmt formats in exponent notation from 1e+06.
+	var token struct {
+		ProgressToken json.RawMessage `json:"progressToken"`
+	}
+	if err := json.Unmarshal(req.Params, &token); err != nil {
+		return true
+	}
+	if v, ok := s.progressHandlers.Load(string(token.ProgressToken)); ok {
+		v.(func(*protocol.ProgressNotificationParams))(&params)
+	}
+	return true
+}
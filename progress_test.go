@@ -0,0 +1,97 @@
+package mcp_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	mcp "github.com/ktr0731/go-mcp"
+	"github.com/ktr0731/go-mcp/protocol"
+	"golang.org/x/exp/jsonrpc2"
+)
+
+func TestReportProgress(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			if err := mcp.ReportProgress(ctx, 50, 100, "halfway"); err != nil {
+				return nil, err
+			}
+			return &mcp.CallToolResult{}, nil
+		}),
+	}
+	progress := make(chan protocol.ProgressNotificationParams, 1)
+	conn := serveWithClientHandler(t, h, jsonrpc2.HandlerFunc(func(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+		if req.Method == protocol.MethodNotificationsProgress {
+			var params protocol.ProgressNotificationParams
+			if err := json.Unmarshal(req.Params, &params); err != nil {
+				t.Errorf("failed to unmarshal params: %v", err)
+			}
+			progress <- params
+		}
+		return nil, nil
+	}))
+
+	ctx := context.Background()
+	t.Run("with progress token", func(t *testing.T) {
+		params := map[string]any{"name": "slow", "_meta": map[string]any{"progressToken": "token"}}
+		if err := conn.Call(ctx, protocol.MethodToolsCall, params).Await(ctx, nil); err != nil {
+			t.Fatalf("tools/call returned an error: %v", err)
+		}
+
+		select {
+		case p := <-progress:
+			want := protocol.ProgressNotificationParams{ProgressToken: "token", Progress: 50, Total: 100, Message: "halfway"}
+			if p != want {
+				t.Errorf("want %+v, got %+v", want, p)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("progress notification was not received")
+		}
+	})
+
+	t.Run("without progress token", func(t *testing.T) {
+		params := map[string]any{"name": "slow"}
+		if err := conn.Call(ctx, protocol.MethodToolsCall, params).Await(ctx, nil); err != nil {
+			t.Fatalf("tools/call returned an error: %v", err)
+		}
+		select {
+		case p := <-progress:
+			t.Errorf("want no progress notification, got %+v", p)
+		default:
+		}
+	})
+}
+
+func TestReportProgress_LargeToken(t *testing.T) {
+	t.Parallel()
+
+	h := toolsHandler(1)
+	h.ToolHandler = toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+		if err := mcp.ReportProgress(ctx, 1, 2, "halfway"); err != nil {
+			return nil, err
+		}
+		return mcp.NewTextToolResult("done"), nil
+	})
+	ctx := context.Background()
+	client, err := mcp.NewInMemoryClient(ctx, h, nil)
+	if err != nil {
+		t.Fatalf("failed to create a client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	// The next token is 1000000, which is formatted as 1e+06 if it is handled as a float64.
+	mcp.SetLastProgressToken(client, 999999)
+	var progress []float64
+	if _, err := client.CallTool(mcp.WithProgressHandler(ctx, func(p *protocol.ProgressNotificationParams) {
+		progress = append(progress, p.Progress)
+	}), "tool_0", map[string]any{}); err != nil {
+		t.Fatalf("CallTool returned an error: %v", err)
+	}
+	if len(progress) != 1 || progress[0] != 1 {
+		t.Errorf("want a progress notification of 1, got %v", progress)
+	}
+}
@@ -5,15 +5,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/xeipuuv/gojsonschema"
 )
 
 const (
+	ProtocolVersion20250618 = "2025-06-18"
 	ProtocolVersion20250326 = "2025-03-26"
 	ProtocolVersion20241105 = "2024-11-05"
 
-	LatestProtocolVersion = ProtocolVersion20250326
+	LatestProtocolVersion = ProtocolVersion20250618
 )
 
 const (
@@ -34,14 +36,21 @@ const (
 	MethodResourcesUnsubscribe  = "resources/unsubscribe"
 
 	MethodNotificationsInitialized          = "notifications/initialized"
+	MethodNotificationsPromptsListChanged   = "notifications/prompts/list_changed"
 	MethodNotificationsResourcesListChanged = "notifications/resources/list_changed"
 	MethodNotificationsResourcesUpdated     = "notifications/resources/updated"
+	MethodNotificationsToolsListChanged     = "notifications/tools/list_changed"
 	MethodNotificationsMessage              = "notifications/message"
 	MethodNotificationsCancelled            = "notifications/cancelled"
+	MethodNotificationsProgress             = "notifications/progress"
 
 	MethodCompletionComplete = "completion/complete"
 
 	MethodLoggingSetLevel = "logging/setLevel"
+
+	MethodSamplingCreateMessage = "sampling/createMessage"
+
+	MethodRootsList = "roots/list"
 )
 
 const (
@@ -56,6 +65,7 @@ const (
 )
 
 var AvailableProtocolVersions = map[string]struct{}{
+	ProtocolVersion20250618: {},
 	ProtocolVersion20250326: {},
 	ProtocolVersion20241105: {},
 }
@@ -64,6 +74,22 @@ var AvailableProtocolVersions = map[string]struct{}{
 type Implementation struct {
 	Name    string `json:"name"`
 	Version string `json:"version"`
+	// Title is a human-readable name of the implementation, which clients display instead of Name if set.
+	Title string `json:"title,omitzero"`
+	// WebsiteURL is the URL of the website of the implementation.
+	WebsiteURL string `json:"websiteUrl,omitzero"`
+	// Icons is a list of icons of the implementation, which clients may display in their user interfaces.
+	Icons []Icon `json:"icons,omitzero"`
+}
+
+// Icon represents an icon which clients may display in their user interfaces.
+type Icon struct {
+	// Src is a URL of the icon. It may also be a data URI containing a base64-encoded image.
+	Src string `json:"src"`
+	// MimeType is an optional MIME type of the icon, e.g. "image/png".
+	MimeType string `json:"mimeType,omitzero"`
+	// Sizes is an optional list of sizes of the icon, e.g. "48x48" or "any" for scalable formats.
+	Sizes []string `json:"sizes,omitzero"`
 }
 
 // PaginationParams represents pagination parameters.
@@ -80,24 +106,90 @@ func (f ServerHandlerFunc[Req]) Handle(ctx context.Context, method string, req R
 	return f(ctx, method, req)
 }
 
+// ErrInvalidToolArguments is wrapped by errors of tool arguments which can't be decoded or don't conform to the input schema.
+var ErrInvalidToolArguments = errors.New("invalid tool arguments")
+
 // ValidateByJSONSchema validates a document against a JSON schema.
+// The returned error wraps ErrInvalidToolArguments.
 func ValidateByJSONSchema(schema string, document any) error {
+	if err := validateByJSONSchema(schema, gojsonschema.NewGoLoader(document)); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidToolArguments, err)
+	}
+	return nil
+}
+
+// ValidateRequiredPromptArguments validates that the prompt arguments contain all the required arguments of names.
+// An argument which is null or an empty string is considered missing.
+func ValidateRequiredPromptArguments(arguments json.RawMessage, names ...string) error {
+	var args map[string]json.RawMessage
+	if len(arguments) != 0 {
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return fmt.Errorf("invalid prompt arguments: %w", err)
+		}
+	}
+	var errs []error
+	for _, name := range names {
+		switch string(args[name]) {
+		case "", "null", `""`:
+			errs = append(errs, fmt.Errorf("missing required prompt argument: %s", name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateStructuredContent validates structured content of a tool result against the output schema of the tool.
+func ValidateStructuredContent(schema string, content json.RawMessage) error {
+	if err := validateByJSONSchema(schema, gojsonschema.NewBytesLoader(content)); err != nil {
+		return fmt.Errorf("invalid structured content: %w", err)
+	}
+	return nil
+}
+
+func validateByJSONSchema(schema string, documentLoader gojsonschema.JSONLoader) error {
 	schemaLoader := gojsonschema.NewStringLoader(schema)
-	documentLoader := gojsonschema.NewGoLoader(document)
 	result, err := gojsonschema.Validate(schemaLoader, documentLoader)
 	if err != nil {
 		return fmt.Errorf("failed to validate by JSON schema: %w", err)
 	}
 	if !result.Valid() {
-		errs := make([]error, len(result.Errors()))
-		for i := range result.Errors() {
-			errs[i] = errors.New(result.Errors()[i].String())
+		verr := &ValidationError{Failures: make([]ValidationFailure, len(result.Errors()))}
+		for i, e := range result.Errors() {
+			verr.Failures[i] = ValidationFailure{
+				Field:      e.Field(),
+				Constraint: e.Type(),
+				Message:    e.Description(),
+			}
 		}
-		return fmt.Errorf("invalid tool arguments: %w", errors.Join(errs...))
+		return verr
 	}
 	return nil
 }
 
+// ValidationError is an error of validation by a JSON schema, which holds the failures of each field.
+// Use errors.As to retrieve it from errors returned by ValidateByJSONSchema and ValidateStructuredContent,
+// e.g. to tell an LLM which arguments to correct.
+type ValidationError struct {
+	Failures []ValidationFailure
+}
+
+// ValidationFailure is a failure of a field to conform to a JSON schema.
+type ValidationFailure struct {
+	// Field is the path of the field, e.g. "location.city". It is "(root)" for the document itself.
+	Field string
+	// Constraint is the type of the violated constraint, e.g. "required", "enum" or "invalid_type".
+	Constraint string
+	// Message is a human-readable description of the failure, e.g. "Must be greater than or equal to 0".
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return strings.Join(msgs, "\n")
+}
+
 //
 // Client-related Types
 //
@@ -109,6 +201,10 @@ type InitializeRequestParams struct {
 	ProtocolVersion string             `json:"protocolVersion"`
 	Capabilities    ClientCapabilities `json:"capabilities"`
 	ClientInfo      Implementation     `json:"clientInfo"`
+
+	// Raw is the raw JSON of the params, which contains fields not defined in this struct.
+	// It is set by servers receiving the params and never marshaled.
+	Raw json.RawMessage `json:"-"`
 }
 
 // ClientCapabilities is a set of capabilities a client may support. Known capabilities are defined here, in this schema,
@@ -118,6 +214,10 @@ type ClientCapabilities struct {
 	Experimental map[string]any `json:"experimental,omitzero"`
 	// Roots is present if the client supports listing roots.
 	Roots *RootsCapability `json:"roots,omitzero"`
+	// Sampling is present if the client supports sampling from an LLM.
+	Sampling *SamplingCapability `json:"sampling,omitzero"`
+	// Elicitation is present if the client supports elicitation from the server.
+	Elicitation *ElicitationCapability `json:"elicitation,omitzero"`
 }
 
 // RootsCapability represents the client's capability to support roots features.
@@ -126,6 +226,12 @@ type RootsCapability struct {
 	ListChanged bool `json:"listChanged,omitzero"`
 }
 
+// SamplingCapability represents the client's capability to support sampling (sampling/createMessage).
+type SamplingCapability struct{}
+
+// ElicitationCapability represents the client's capability to support elicitation (elicitation/create).
+type ElicitationCapability struct{}
+
 // CallToolRequestParams is used by the client to invoke a tool provided by the server.
 type CallToolRequestParams struct {
 	// Name is the name of the tool.
@@ -151,6 +257,18 @@ type NotificationsCancelledRequestParams struct {
 	Reason string `json:"reason"`
 }
 
+// ProgressNotificationParams is sent by either side to inform the receiver about the progress of a long-running request.
+type ProgressNotificationParams struct {
+	// ProgressToken is the token given in the request's _meta, used to associate this notification with the request.
+	ProgressToken any `json:"progressToken"`
+	// Progress is the progress thus far. This should increase every time progress is made, even if the total is unknown.
+	Progress float64 `json:"progress"`
+	// Total is the total number of items to process (or total progress required), if known.
+	Total float64 `json:"total,omitzero"`
+	// Message is an optional message describing the current progress.
+	Message string `json:"message,omitzero"`
+}
+
 // LoggingSetLevelRequestParams is a request from the client to the server, to enable or adjust logging.
 type LoggingSetLevelRequestParams struct {
 	// Level is the level of logging that the client wants to receive from the server.
@@ -228,6 +346,34 @@ type CompletionsCapability struct{}
 // These map to syslog message severities, as specified in RFC-5424.
 type LogLevel int
 
+// String returns the name of the level, e.g. "info".
+// A level between the defined levels is named after the next higher one, e.g. slog.LevelWarn-1 is "warning".
+func (l LogLevel) String() string {
+	switch {
+	case l <= LevelDebug:
+		return "debug"
+	case l <= LevelInfo:
+		return "info"
+	case l <= LevelNotice:
+		return "notice"
+	case l <= LevelWarning:
+		return "warning"
+	case l <= LevelError:
+		return "error"
+	case l <= LevelCritical:
+		return "critical"
+	case l <= LevelAlert:
+		return "alert"
+	default:
+		return "emergency"
+	}
+}
+
+// MarshalJSON implements json.Marshaler for LogLevel. It marshals the level as its name. See String.
+func (l LogLevel) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.String())
+}
+
 // UnmarshalJSON implements json.Unmarshaler for LogLevel.
 func (l *LogLevel) UnmarshalJSON(b []byte) error {
 	switch string(b) {
@@ -263,6 +409,9 @@ type Tool struct {
 	Description string `json:"description,omitzero"`
 	// InputSchema is a JSON Schema object defining the expected parameters for the tool.
 	InputSchema any `json:"inputSchema"`
+	// OutputSchema is an optional JSON Schema object defining the structure of the structured content of the tool result.
+	// If this is set, tool results must have structured content conforming to the schema.
+	OutputSchema any `json:"outputSchema,omitzero"`
 
 	// Annotations contains optional additional tool information.
 	Annotations *ToolAnnotations `json:"annotations,omitzero"`
@@ -294,6 +443,10 @@ type ToolAnnotations struct {
 	// of a memory tool is not.
 	// Default: true
 	OpenWorldHint bool `json:"openWorldHint,omitzero"`
+	// Deprecated is a message that describes why the tool is deprecated and what to use instead, e.g. "Use get_forecast instead".
+	// If this is set, the tool is deprecated and a warning log notification is sent when it is called.
+	// This is an extension to the spec, so clients that don't know it just ignore it.
+	Deprecated string `json:"deprecated,omitzero"`
 }
 
 // Prompt Types
@@ -316,4 +469,104 @@ type PromptArgument struct {
 	Description string `json:"description,omitzero"`
 	// Required indicates whether this argument must be provided.
 	Required bool `json:"required,omitzero"`
+	// Pattern is an optional regular expression which values of the argument must match.
+	// Pattern and Enum aren't defined by the MCP specification, so clients which don't know them ignore them.
+	Pattern string `json:"pattern,omitzero"`
+	// Enum is an optional list of the possible values of the argument.
+	Enum []string `json:"enum,omitzero"`
+}
+
+// Sampling Types
+
+// CreateMessageParams is a request from the server to sample an LLM via the client.
+// The client has full discretion over which model to select. The client should also inform the user before beginning sampling,
+// to allow them to inspect the request (human in the loop) and decide whether to approve it.
+type CreateMessageParams struct {
+	// Messages is the conversation to sample from.
+	Messages []SamplingMessage `json:"messages"`
+	// ModelPreferences is the server's preferences for which model to select. The client MAY ignore these preferences.
+	ModelPreferences *ModelPreferences `json:"modelPreferences,omitzero"`
+	// SystemPrompt is an optional system prompt the server wants to use for sampling.
+	// The client MAY modify or omit this prompt.
+	SystemPrompt string `json:"systemPrompt,omitzero"`
+	// IncludeContext is a request to include context from one or more MCP servers (including the caller), to be attached to the prompt.
+	// The value is one of "none", "thisServer" and "allServers". The client MAY ignore this request.
+	IncludeContext string `json:"includeContext,omitzero"`
+	// Temperature is the temperature to use for sampling.
+	Temperature *float64 `json:"temperature,omitzero"`
+	// MaxTokens is the maximum number of tokens to sample, as requested by the server.
+	// The client MAY choose to sample fewer tokens than requested.
+	MaxTokens int `json:"maxTokens"`
+	// StopSequences is a list of sequences that stop sampling.
+	StopSequences []string `json:"stopSequences,omitzero"`
+	// Metadata is optional metadata to pass through to the LLM provider. The format of this metadata is provider-specific.
+	Metadata map[string]any `json:"metadata,omitzero"`
+}
+
+// CreateMessageResult is the client's response to a sampling/createMessage request from the server.
+type CreateMessageResult struct {
+	// Role is the role of the sampled message, "user" or "assistant".
+	Role string `json:"role"`
+	// Content is the content of the sampled message.
+	Content SamplingContent `json:"content"`
+	// Model is the name of the model that generated the message.
+	Model string `json:"model"`
+	// StopReason is the reason why sampling stopped, if known, e.g. "endTurn", "stopSequence" or "maxTokens".
+	StopReason string `json:"stopReason,omitzero"`
+}
+
+// SamplingMessage describes a message issued to or received from an LLM API.
+type SamplingMessage struct {
+	// Role is the role of the message, "user" or "assistant".
+	Role string `json:"role"`
+	// Content is the content of the message.
+	Content SamplingContent `json:"content"`
+}
+
+// SamplingContent is the content of a sampling message.
+// Type is "text", "image" or "audio". Text is set for text content, and Data and MimeType are set for image and audio content.
+type SamplingContent struct {
+	// Type is the type of the content.
+	Type string `json:"type"`
+	// Text is the text content of the message.
+	Text string `json:"text,omitzero"`
+	// Data is the base64-encoded image or audio data.
+	Data string `json:"data,omitzero"`
+	// MimeType is the MIME type of the image or audio.
+	MimeType string `json:"mimeType,omitzero"`
+}
+
+// ModelPreferences is the server's preferences for model selection, requested of the client during sampling.
+// Priorities are values between 0 and 1.
+type ModelPreferences struct {
+	// Hints are optional hints to use for model selection, evaluated in order.
+	Hints []ModelHint `json:"hints,omitzero"`
+	// CostPriority is how much to prioritize cost when selecting a model.
+	CostPriority *float64 `json:"costPriority,omitzero"`
+	// SpeedPriority is how much to prioritize sampling speed (latency) when selecting a model.
+	SpeedPriority *float64 `json:"speedPriority,omitzero"`
+	// IntelligencePriority is how much to prioritize intelligence and capabilities when selecting a model.
+	IntelligencePriority *float64 `json:"intelligencePriority,omitzero"`
+}
+
+// ModelHint is a hint to use for model selection.
+type ModelHint struct {
+	// Name is a hint for a model name, e.g. "claude-3-5-sonnet". The client SHOULD treat this as a substring of a model name.
+	Name string `json:"name,omitzero"`
+}
+
+// Roots Types
+
+// Root represents a root directory or file that the server can operate on.
+type Root struct {
+	// URI is the URI identifying the root. This must start with file:// for now.
+	URI string `json:"uri"`
+	// Name is an optional name for the root.
+	Name string `json:"name,omitzero"`
+}
+
+// ListRootsResult is the client's response to a roots/list request from the server.
+type ListRootsResult struct {
+	// Roots is the list of roots.
+	Roots []Root `json:"roots"`
 }
@@ -0,0 +1,149 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/exp/jsonrpc2"
+)
+
+// MultiResourceHandler returns a ServerResourceHandler that routes requests to handlers by the URI scheme of resources,
+// e.g. {"weather": weatherHandler, "file": fileHandler}.
+//
+// resources/read requests are handled by the handler of the scheme of the requested URI.
+// If there is no handler for the scheme, it returns an error wrapping jsonrpc2.ErrInvalidParams.
+// resources/list requests are handled by all handlers in the order of their schemes, and the results are merged.
+// If a handler returns a next cursor, the merged result ends there and continues from the handler by the next request.
+// A nil result of a handler is treated as no resources.
+//
+// Schemes are case-insensitive like those of URIs, so the keys of handlers are normalized to lowercase.
+func MultiResourceHandler(handlers map[string]ServerResourceHandler) ServerResourceHandler {
+	normalized := make(map[string]ServerResourceHandler, len(handlers))
+	for scheme, handler := range handlers {
+		normalized[strings.ToLower(scheme)] = handler
+	}
+	return &multiResourceHandler{
+		handlers: normalized,
+		schemes:  slices.Sorted(maps.Keys(normalized)),
+	}
+}
+
+type multiResourceHandler struct {
+	handlers map[string]ServerResourceHandler
+	// schemes is the sorted list of the schemes of handlers, which determines the order of listed resources.
+	schemes []string
+}
+
+// multiResourceCursor is the decoded form of a cursor issued by multiResourceHandler.
+type multiResourceCursor struct {
+	// Scheme is the scheme of the handler to continue listing from.
+	Scheme string `json:"s"`
+	// Cursor is the cursor returned by the handler.
+	Cursor string `json:"c"`
+}
+
+func (h *multiResourceHandler) HandleResourcesList(ctx context.Context) (*ListResourcesResult, error) {
+	start := multiResourceCursor{}
+	if cursor, _ := NextCursor(ctx); cursor != "" {
+		b, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid cursor", jsonrpc2.ErrInvalidParams)
+		}
+		if err := json.Unmarshal(b, &start); err != nil {
+			return nil, fmt.Errorf("%w: invalid cursor", jsonrpc2.ErrInvalidParams)
+		}
+		if _, ok := h.handlers[start.Scheme]; !ok {
+			return nil, ErrStaleCursor
+		}
+	}
+
+	res := &ListResourcesResult{Resources: []Resource{}}
+	for _, scheme := range h.schemes {
+		if scheme < start.Scheme {
+			continue
+		}
+		var cursor string
+		if scheme == start.Scheme {
+			cursor = start.Cursor
+		}
+
+		r, err := h.handlers[scheme].HandleResourcesList(context.WithValue(ctx, nextCursorKey{}, cursor))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list resources of scheme %s: %w", scheme, err)
+		}
+		if r == nil {
+			continue
+		}
+		res.Resources = append(res.Resources, r.Resources...)
+		if r.NextCursor != "" {
+			b, err := json.Marshal(multiResourceCursor{Scheme: scheme, Cursor: r.NextCursor})
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal cursor: %w", err)
+			}
+			res.NextCursor = base64.RawURLEncoding.EncodeToString(b)
+			break
+		}
+	}
+	return res, nil
+}
+
+func (h *multiResourceHandler) HandleResourcesRead(ctx context.Context, req *ReadResourceRequest) (*ReadResourceResult, error) {
+	u, err := url.Parse(req.URI)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid resource URI %q: %w", jsonrpc2.ErrInvalidParams, req.URI, err)
+	}
+	handler, ok := h.handlers[u.Scheme]
+	if !ok {
+		return nil, fmt.Errorf("%w: no resource handler for scheme %q", jsonrpc2.ErrInvalidParams, u.Scheme)
+	}
+	return handler.HandleResourcesRead(ctx, req)
+}
+
+// FileResourceContent reads the file at path and returns it as the content of the resource of uri.
+// The MIME type is detected by the extension of path, or by http.DetectContentType if the extension is unknown.
+// Files of textual MIME types (text/*, JSON, XML, YAML and JavaScript) which are valid UTF-8 are returned as TextResourceContent,
+// and other files as BlobResourceContent. The whole file is read into memory, so large files should be read in parts by ByteRange.
+func FileResourceContent(uri, path string) (ResourceContent, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resource %s: %w", uri, err)
+	}
+
+	mimeType := mime.TypeByExtension(filepath.Ext(path))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(b)
+	}
+	if isTextMimeType(mimeType) && utf8.Valid(b) {
+		return TextResourceContent{URI: uri, MimeType: mimeType, Text: string(b)}, nil
+	}
+	return BlobResourceContent{URI: uri, MimeType: mimeType, Blob: bytes.NewReader(b)}, nil
+}
+
+// isTextMimeType reports whether contents of mimeType are text, e.g. "text/plain; charset=utf-8" or "application/ld+json".
+func isTextMimeType(mimeType string) bool {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/yaml", "application/x-yaml", "application/javascript":
+		return true
+	default:
+		return false
+	}
+}
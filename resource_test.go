@@ -0,0 +1,186 @@
+package mcp_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	mcp "github.com/ktr0731/go-mcp"
+	"github.com/ktr0731/go-mcp/protocol"
+	"golang.org/x/exp/jsonrpc2"
+)
+
+// schemeResourceHandler serves the resources of names under a scheme, paginated by pageSize.
+// If names is nil, resources/list returns a nil result.
+type schemeResourceHandler struct {
+	scheme   string
+	names    []string
+	pageSize int
+}
+
+func (h *schemeResourceHandler) HandleResourcesList(ctx context.Context) (*mcp.ListResourcesResult, error) {
+	if h.names == nil {
+		return nil, nil
+	}
+	var resources []mcp.Resource
+	for _, name := range h.names {
+		resources = append(resources, mcp.Resource{URI: h.scheme + "://" + name, Name: name})
+	}
+	cursor, _ := mcp.NextCursor(ctx)
+	page, next, err := mcp.Paginate(resources, cursor, h.pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.ListResourcesResult{Resources: page, NextCursor: next}, nil
+}
+
+func (h *schemeResourceHandler) HandleResourcesRead(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	return &mcp.ReadResourceResult{
+		Contents: []mcp.ResourceContent{mcp.TextResourceContent{URI: req.URI, Text: h.scheme}},
+	}, nil
+}
+
+func TestMultiResourceHandler(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Resources: &protocol.ResourceCapability{}},
+		ResourceHandler: mcp.MultiResourceHandler(map[string]mcp.ServerResourceHandler{
+			"weather": &schemeResourceHandler{scheme: "weather", names: []string{"tokyo", "osaka", "kyoto"}, pageSize: 2},
+			"file":    &schemeResourceHandler{scheme: "file", names: []string{"a.txt"}},
+			// The key is normalized to lowercase, and the nil result of resources/list is skipped.
+			"Memo": &schemeResourceHandler{scheme: "memo"},
+		}),
+	}
+	ctx := context.Background()
+
+	t.Run("list", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			pages  int
+			uris   []string
+			cursor string
+		)
+		for {
+			res, err := h.Handle(ctx, newCall(t, 1, protocol.MethodResourcesList, protocol.PaginationParams{Cursor: cursor}))
+			if err != nil {
+				t.Fatalf("resources/list returned an error: %v", err)
+			}
+			list := res.(*mcp.ListResourcesResult)
+			pages++
+			for _, r := range list.Resources {
+				uris = append(uris, r.URI)
+			}
+			if list.NextCursor == "" {
+				break
+			}
+			cursor = list.NextCursor
+		}
+
+		// Resources are listed in the order of schemes, and the pages of weather are followed.
+		want := []string{"file://a.txt", "weather://tokyo", "weather://osaka", "weather://kyoto"}
+		if !slices.Equal(want, uris) {
+			t.Errorf("want %v, got %v", want, uris)
+		}
+		if pages != 2 {
+			t.Errorf("want 2 pages, got %d", pages)
+		}
+	})
+
+	t.Run("read", func(t *testing.T) {
+		t.Parallel()
+
+		// url.Parse lowercases schemes, so MEMO is routed to the handler of Memo.
+		for _, scheme := range []string{"weather", "file", "MEMO"} {
+			res, err := h.Handle(ctx, newCall(t, 1, protocol.MethodResourcesRead, mcp.ReadResourceRequest{URI: scheme + "://x"}))
+			if err != nil {
+				t.Fatalf("resources/read returned an error: %v", err)
+			}
+			content := res.(*mcp.ReadResourceResult).Contents[0].(mcp.TextResourceContent)
+			if want := strings.ToLower(scheme); content.Text != want {
+				t.Errorf("want the resource to be read by the %s handler, got %s", want, content.Text)
+			}
+		}
+	})
+
+	t.Run("unknown scheme", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := h.Handle(ctx, newCall(t, 1, protocol.MethodResourcesRead, mcp.ReadResourceRequest{URI: "https://example.com"}))
+		if !errors.Is(err, jsonrpc2.ErrInvalidParams) {
+			t.Errorf("want %v, got %v", jsonrpc2.ErrInvalidParams, err)
+		}
+	})
+}
+
+func TestFileResourceContent(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	files := map[string]string{
+		"forecast.json": `{"city":"Tokyo"}`,
+		"notes":         "sunny all day",
+		"map.png":       png,
+		"binary.txt":    "\xff\xfe\x00",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write a file: %v", err)
+		}
+	}
+
+	cases := map[string]struct {
+		wantText     bool
+		wantMimeType string
+	}{
+		"forecast.json": {wantText: true, wantMimeType: "application/json"},
+		"notes":         {wantText: true, wantMimeType: "text/plain"},
+		"map.png":       {wantMimeType: "image/png"},
+		"binary.txt":    {wantMimeType: "text/plain"},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			uri := "file:///" + name
+			content, err := mcp.FileResourceContent(uri, filepath.Join(dir, name))
+			if err != nil {
+				t.Fatalf("FileResourceContent returned an error: %v", err)
+			}
+			switch content := content.(type) {
+			case mcp.TextResourceContent:
+				if !c.wantText {
+					t.Fatalf("want blob content, got text content %#v", content)
+				}
+				if content.URI != uri || content.Text != files[name] || !strings.HasPrefix(content.MimeType, c.wantMimeType) {
+					t.Errorf("unexpected content: %#v", content)
+				}
+			case mcp.BlobResourceContent:
+				if c.wantText {
+					t.Fatalf("want text content, got blob content %#v", content)
+				}
+				b, err := io.ReadAll(content.Blob)
+				if err != nil {
+					t.Fatalf("failed to read the blob: %v", err)
+				}
+				if content.URI != uri || string(b) != files[name] || !strings.HasPrefix(content.MimeType, c.wantMimeType) {
+					t.Errorf("unexpected content: %#v", content)
+				}
+			default:
+				t.Fatalf("unexpected content type %T", content)
+			}
+		})
+	}
+
+	if _, err := mcp.FileResourceContent("file:///missing", filepath.Join(dir, "missing")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v, got %v", fs.ErrNotExist, err)
+	}
+}
@@ -0,0 +1,33 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ktr0731/go-mcp/protocol"
+)
+
+// ErrRootsNotSupported is returned by ListRoots when the client didn't advertise the roots capability.
+var ErrRootsNotSupported = errors.New("client doesn't support roots")
+
+// ListRoots asks the client for the roots the server can operate on, e.g. directories authorized by the host.
+// ctx must be the context passed to a handler, which is bound to the connection of the client.
+//
+// See https://modelcontextprotocol.io/specification/2025-03-26/client/roots
+func ListRoots(ctx context.Context) ([]protocol.Root, error) {
+	s, ok := ctx.Value(sessionKey{}).(*session)
+	if !ok {
+		return nil, errors.New("no connection to send roots requests")
+	}
+	initParams := s.initializeParams.Load()
+	if initParams == nil || initParams.Capabilities.Roots == nil {
+		return nil, ErrRootsNotSupported
+	}
+
+	var res protocol.ListRootsResult
+	if err := s.call(ctx, protocol.MethodRootsList, struct{}{}, &res); err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", protocol.MethodRootsList, err)
+	}
+	return res.Roots, nil
+}
@@ -0,0 +1,65 @@
+package mcp_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	mcp "github.com/ktr0731/go-mcp"
+	"github.com/ktr0731/go-mcp/protocol"
+	"golang.org/x/exp/jsonrpc2"
+)
+
+func TestListRoots(t *testing.T) {
+	t.Parallel()
+
+	roots := []protocol.Root{{URI: "file:///home/user/project", Name: "project"}}
+	var (
+		got    []protocol.Root
+		gotErr error
+	)
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			got, gotErr = mcp.ListRoots(ctx)
+			return &mcp.CallToolResult{}, nil
+		}),
+	}
+	conn := serveWithClientHandler(t, h, jsonrpc2.HandlerFunc(func(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+		if req.Method == protocol.MethodRootsList {
+			return &protocol.ListRootsResult{Roots: roots}, nil
+		}
+		return nil, nil
+	}))
+
+	ctx := context.Background()
+	call := func(t *testing.T, caps protocol.ClientCapabilities) {
+		t.Helper()
+
+		params := protocol.InitializeRequestParams{ProtocolVersion: protocol.LatestProtocolVersion, Capabilities: caps}
+		if err := conn.Call(ctx, protocol.MethodInitialize, params).Await(ctx, nil); err != nil {
+			t.Fatalf("initialize returned an error: %v", err)
+		}
+		if err := conn.Call(ctx, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "list"}).Await(ctx, nil); err != nil {
+			t.Fatalf("tools/call returned an error: %v", err)
+		}
+	}
+
+	t.Run("roots are not supported", func(t *testing.T) {
+		call(t, protocol.ClientCapabilities{})
+		if !errors.Is(gotErr, mcp.ErrRootsNotSupported) {
+			t.Errorf("want %v, got %v", mcp.ErrRootsNotSupported, gotErr)
+		}
+	})
+
+	t.Run("roots are supported", func(t *testing.T) {
+		call(t, protocol.ClientCapabilities{Roots: &protocol.RootsCapability{}})
+		if gotErr != nil {
+			t.Fatalf("ListRoots returned an error: %v", gotErr)
+		}
+		if !slices.Equal(roots, got) {
+			t.Errorf("want %v, got %v", roots, got)
+		}
+	})
+}
@@ -0,0 +1,34 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ktr0731/go-mcp/protocol"
+)
+
+// ErrSamplingNotSupported is returned by RequestSampling when the client didn't advertise the sampling capability.
+var ErrSamplingNotSupported = errors.New("client doesn't support sampling")
+
+// RequestSampling asks the client to sample an LLM and waits for the result.
+// ctx must be the context passed to a handler, which is bound to the connection of the client.
+// Progress notifications of the request can be received by passing a context from WithProgressHandler.
+//
+// See https://modelcontextprotocol.io/specification/2025-03-26/client/sampling
+func RequestSampling(ctx context.Context, params *protocol.CreateMessageParams) (*protocol.CreateMessageResult, error) {
+	s, ok := ctx.Value(sessionKey{}).(*session)
+	if !ok {
+		return nil, errors.New("no connection to send sampling requests")
+	}
+	initParams := s.initializeParams.Load()
+	if initParams == nil || initParams.Capabilities.Sampling == nil {
+		return nil, ErrSamplingNotSupported
+	}
+
+	var res protocol.CreateMessageResult
+	if err := s.call(ctx, protocol.MethodSamplingCreateMessage, params, &res); err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", protocol.MethodSamplingCreateMessage, err)
+	}
+	return &res, nil
+}
@@ -0,0 +1,108 @@
+package mcp_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	mcp "github.com/ktr0731/go-mcp"
+	"github.com/ktr0731/go-mcp/protocol"
+	"golang.org/x/exp/jsonrpc2"
+)
+
+func TestRequestSampling(t *testing.T) {
+	t.Parallel()
+
+	progress := make(chan float64, 1)
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			ctx = mcp.WithProgressHandler(ctx, func(p *protocol.ProgressNotificationParams) {
+				progress <- p.Progress
+			})
+			res, err := mcp.RequestSampling(ctx, &protocol.CreateMessageParams{
+				Messages: []protocol.SamplingMessage{
+					{Role: "user", Content: protocol.SamplingContent{Type: "text", Text: "Hello"}},
+				},
+				MaxTokens: 100,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return &mcp.CallToolResult{
+				Content: []mcp.CallToolContent{mcp.TextContent{Text: res.Content.Text}},
+			}, nil
+		}),
+	}
+
+	var conn *jsonrpc2.Connection
+	conn = serveWithClientHandler(t, h, jsonrpc2.HandlerFunc(func(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+		if req.Method != protocol.MethodSamplingCreateMessage {
+			return nil, nil
+		}
+		var params struct {
+			protocol.CreateMessageParams
+			Meta struct {
+				ProgressToken any `json:"progressToken"`
+			} `json:"_meta"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		if err := conn.Notify(ctx, protocol.MethodNotificationsProgress, &protocol.ProgressNotificationParams{
+			ProgressToken: params.Meta.ProgressToken,
+			Progress:      1,
+		}); err != nil {
+			return nil, err
+		}
+		return &protocol.CreateMessageResult{
+			Role:    "assistant",
+			Content: protocol.SamplingContent{Type: "text", Text: params.Messages[0].Content.Text + ", world"},
+			Model:   "test-model",
+		}, nil
+	}))
+
+	ctx := context.Background()
+	initialize := func(t *testing.T, caps protocol.ClientCapabilities) {
+		t.Helper()
+
+		params := protocol.InitializeRequestParams{ProtocolVersion: protocol.LatestProtocolVersion, Capabilities: caps}
+		if err := conn.Call(ctx, protocol.MethodInitialize, params).Await(ctx, nil); err != nil {
+			t.Fatalf("initialize returned an error: %v", err)
+		}
+	}
+
+	t.Run("sampling is not supported", func(t *testing.T) {
+		initialize(t, protocol.ClientCapabilities{})
+
+		err := conn.Call(ctx, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "ask"}).Await(ctx, nil)
+		if err == nil {
+			t.Fatal("want an error, got nil")
+		}
+	})
+
+	t.Run("sampling is supported", func(t *testing.T) {
+		initialize(t, protocol.ClientCapabilities{Sampling: &protocol.SamplingCapability{}})
+
+		var res struct {
+			Content []struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		}
+		if err := conn.Call(ctx, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "ask"}).Await(ctx, &res); err != nil {
+			t.Fatalf("tools/call returned an error: %v", err)
+		}
+		if len(res.Content) != 1 || res.Content[0].Text != "Hello, world" {
+			t.Errorf("unexpected result: %+v", res)
+		}
+		select {
+		case p := <-progress:
+			if p != 1 {
+				t.Errorf("want progress 1, got %v", p)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("progress notification was not received")
+		}
+	})
+}
@@ -0,0 +1,475 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/jsonrpc2"
+)
+
+type SSETransportOptions struct {
+	// SSEPath is the path of the endpoint that opens an SSE stream.
+	// If this is not set, "/sse" is used.
+	SSEPath string
+	// MessagePath is the path of the endpoint that receives JSON-RPC messages from clients.
+	// It is advertised to clients by the endpoint event, so it must be the path seen by clients.
+	// If this is not set, "/messages" is used.
+	MessagePath string
+	// Preempter is the preempter for the transport.
+	// If this is not set, no preemption is done.
+	Preempter jsonrpc2.Preempter
+	// RequestTimeout is the maximum duration of handling a single request. See StdioTransportOptions.RequestTimeout.
+	RequestTimeout time.Duration
+	// MaxMessageSize is the maximum size in bytes of a message posted to the message endpoint.
+	// Larger messages are rejected with 413 Request Entity Too Large.
+	// If this is zero or negative, DefaultMaxMessageSize is used.
+	MaxMessageSize int64
+}
+
+// DefaultMaxMessageSize is the default of SSETransportOptions.MaxMessageSize.
+const DefaultMaxMessageSize = 4 << 20
+
+// NewSSETransport creates a new HTTP with SSE transport, which is used by the 2024-11-05 protocol version.
+// The returned http.Handler serves both the SSE endpoint and the message endpoint.
+// Each SSE stream is accepted by the returned listener as a connection, and log notifications are sent to the stream of the connection.
+// When a client disconnects, in-flight requests of the connection are canceled.
+//
+// See https://modelcontextprotocol.io/specification/2024-11-05/basic/transports#http-with-sse
+func NewSSETransport(
+	ctx context.Context,
+	handler *Handler,
+	opts *SSETransportOptions,
+) (context.Context, jsonrpc2.Listener, jsonrpc2.Binder, http.Handler) {
+	// Log notifications are sent to each connection, so there is no transport-wide log writer.
+	ctx = SetLogWriterToContext(ctx, io.Discard)
+
+	if opts == nil {
+		opts = &SSETransportOptions{}
+	}
+	if opts.SSEPath == "" {
+		opts.SSEPath = "/sse"
+	}
+	if opts.MessagePath == "" {
+		opts.MessagePath = "/messages"
+	}
+	if opts.MaxMessageSize <= 0 {
+		opts.MaxMessageSize = DefaultMaxMessageSize
+	}
+
+	listener := &sseListener{
+		conns:  make(chan *sseConn),
+		closed: make(chan struct{}),
+	}
+	binder := &binder{
+		handler:        handler,
+		preempter:      opts.Preempter,
+		logToConn:      handler.Capabilities.Logging != nil,
+		requestTimeout: opts.RequestTimeout,
+	}
+	server := &sseServer{
+		ctx:            ctx,
+		listener:       listener,
+		ssePath:        opts.SSEPath,
+		messagePath:    opts.MessagePath,
+		maxMessageSize: opts.MaxMessageSize,
+	}
+
+	return ctx, listener, binder, server
+}
+
+// sseServer is an http.Handler that serves the SSE endpoint and the message endpoint.
+type sseServer struct {
+	ctx      context.Context
+	listener *sseListener
+
+	ssePath     string
+	messagePath string
+	// maxMessageSize is the maximum size in bytes of a posted message.
+	maxMessageSize int64
+
+	// conns is a map of session IDs to connections.
+	conns sync.Map
+}
+
+func (s *sseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == s.ssePath && r.Method == http.MethodGet:
+		s.serveSSE(w, r)
+	case r.URL.Path == s.messagePath && r.Method == http.MethodPost:
+		s.serveMessage(w, r)
+	case r.URL.Path == s.ssePath || r.URL.Path == s.messagePath:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *sseServer) serveSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		http.Error(w, "failed to create a session", http.StatusInternalServerError)
+		return
+	}
+
+	pr, pw := io.Pipe()
+	conn := &sseConn{
+		pr:      pr,
+		pw:      pw,
+		w:       w,
+		flusher: flusher,
+		done:    make(chan struct{}),
+	}
+	s.conns.Store(id, conn)
+	defer s.conns.Delete(id)
+	// The response writer must not be used after ServeHTTP returns.
+	defer conn.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := conn.writeEvent("endpoint", []byte(s.messagePath+"?sessionId="+id)); err != nil {
+		return
+	}
+
+	select {
+	case s.listener.conns <- conn:
+	case <-s.listener.closed:
+		return
+	case <-s.ctx.Done():
+		return
+	case <-r.Context().Done():
+		return
+	}
+
+	select {
+	case <-conn.done:
+	case <-s.ctx.Done():
+	case <-r.Context().Done():
+		// The client disconnected.
+	}
+}
+
+func (s *sseServer) serveMessage(w http.ResponseWriter, r *http.Request) {
+	v, ok := s.conns.Load(r.URL.Query().Get("sessionId"))
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	conn := v.(*sseConn)
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxMessageSize))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, "message is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "failed to read the request body", http.StatusBadRequest)
+		return
+	}
+	if _, err := jsonrpc2.DecodeMessage(body); err != nil {
+		http.Error(w, fmt.Sprintf("invalid JSON-RPC message: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := conn.pw.Write(append(body, '\n')); err != nil {
+		http.Error(w, "session is closed", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// sseListener is an implementation of jsonrpc2.Listener that accepts SSE streams.
+type sseListener struct {
+	conns chan *sseConn
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func (l *sseListener) Accept(ctx context.Context) (io.ReadWriteCloser, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-l.closed:
+		return nil, io.EOF
+	case conn := <-l.conns:
+		return conn, nil
+	}
+}
+
+func (l *sseListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return nil
+}
+
+// Dialer returns nil because SSE connections are initiated by HTTP clients.
+func (l *sseListener) Dialer() jsonrpc2.Dialer { return nil }
+
+// sseConn is a connection over an SSE stream.
+// Messages from the client are POSTed to the message endpoint and written to pw,
+// and messages to the client are sent as message events.
+type sseConn struct {
+	pr *io.PipeReader
+	pw *io.PipeWriter
+
+	mu      sync.Mutex
+	w       io.Writer
+	flusher http.Flusher
+	closed  bool
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func (c *sseConn) Read(p []byte) (int, error) { return c.pr.Read(p) }
+
+// Write sends p as a message event. Each call of Write must contain a whole message.
+// Writes consisting only of whitespace (e.g. the newline that delimits messages) are ignored.
+func (c *sseConn) Write(p []byte) (int, error) {
+	data := bytes.TrimSpace(p)
+	if len(data) == 0 {
+		return len(p), nil
+	}
+	if err := c.writeEvent("message", data); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *sseConn) writeEvent(event string, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return errors.New("SSE stream is closed")
+	}
+	if _, err := fmt.Fprintf(c.w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	c.flusher.Flush()
+	return nil
+}
+
+func (c *sseConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		c.closed = true
+		c.mu.Unlock()
+
+		// Unblock the reader of the connection so that jsonrpc2.Connection notices the close.
+		c.pr.Close()
+		c.pw.Close()
+		close(c.done)
+	})
+	return nil
+}
+
+// SSEClientTransportOptions are options of SSEClientTransport.
+type SSEClientTransportOptions struct {
+	// HTTPClient is the HTTP client used for the SSE stream and messages.
+	// If this is not set, http.DefaultClient is used.
+	HTTPClient *http.Client
+}
+
+// SSEClientTransport connects to an MCP server over HTTP with SSE, which is used by the 2024-11-05 protocol version.
+// It is a jsonrpc2.Dialer to be passed to NewClient, and the counterpart of NewSSETransport.
+//
+// See https://modelcontextprotocol.io/specification/2024-11-05/basic/transports#http-with-sse
+type SSEClientTransport struct {
+	url    string
+	client *http.Client
+}
+
+// NewSSEClientTransport returns a transport connecting to the SSE endpoint at sseURL, e.g. "http://localhost:8080/sse".
+func NewSSEClientTransport(sseURL string, opts *SSEClientTransportOptions) *SSEClientTransport {
+	if opts == nil {
+		opts = &SSEClientTransportOptions{}
+	}
+	client := opts.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &SSEClientTransport{url: sseURL, client: client}
+}
+
+// Dial opens an SSE stream and waits for the endpoint event, which tells where to post messages.
+// The stream is closed when ctx is canceled or the returned connection is closed.
+func (t *SSEClientTransport) Dial(ctx context.Context) (io.ReadWriteCloser, error) {
+	base, err := url.Parse(t.url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSE URL: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.url, nil)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create a request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	res, err := t.client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open an SSE stream: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to open an SSE stream: unexpected status %s", res.Status)
+	}
+
+	events := bufio.NewReader(res.Body)
+	event, data, err := readSSEEvent(events)
+	if err != nil {
+		res.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to read the endpoint event: %w", err)
+	}
+	if event != "endpoint" {
+		res.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("want the endpoint event first, got %s", event)
+	}
+	endpoint, err := base.Parse(string(data))
+	if err != nil {
+		res.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	conn := &sseClientConn{
+		ctx:      ctx,
+		cancel:   cancel,
+		client:   t.client,
+		endpoint: endpoint.String(),
+		body:     res.Body,
+		pr:       pr,
+	}
+	go func() {
+		for {
+			event, data, err := readSSEEvent(events)
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if event != "message" {
+				continue
+			}
+			if _, err := pw.Write(append(data, '\n')); err != nil {
+				return
+			}
+		}
+	}()
+	return conn, nil
+}
+
+// readSSEEvent reads an event from r. Lines of data are joined by newlines, and comments and other fields are ignored.
+// The event is "message" if it isn't named.
+func readSSEEvent(r *bufio.Reader) (event string, data []byte, err error) {
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if event == "" && lines == nil {
+				// Skip blank lines between events.
+				continue
+			}
+			break
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			lines = append(lines, value)
+		}
+	}
+	if event == "" {
+		event = "message"
+	}
+	return event, []byte(strings.Join(lines, "\n")), nil
+}
+
+// sseClientConn is a client connection over an SSE stream.
+// Messages from the server are received as message events of the stream, and messages to the server are posted to the endpoint.
+type sseClientConn struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	client   *http.Client
+	endpoint string
+
+	body io.Closer
+	pr   *io.PipeReader
+
+	closeOnce sync.Once
+}
+
+func (c *sseClientConn) Read(p []byte) (int, error) { return c.pr.Read(p) }
+
+// Write posts p to the endpoint. Each call of Write must contain a whole message.
+// Writes consisting only of whitespace (e.g. the newline that delimits messages) are ignored.
+func (c *sseClientConn) Write(p []byte) (int, error) {
+	data := bytes.TrimSpace(p)
+	if len(data) == 0 {
+		return len(p), nil
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, c.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create a request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to post a message: %w", err)
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+	if res.StatusCode/100 != 2 {
+		return 0, fmt.Errorf("failed to post a message: unexpected status %s", res.Status)
+	}
+	return len(p), nil
+}
+
+func (c *sseClientConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.cancel()
+		c.body.Close()
+		c.pr.Close()
+	})
+	return nil
+}
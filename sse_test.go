@@ -0,0 +1,239 @@
+package mcp_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mcp "github.com/ktr0731/go-mcp"
+	"github.com/ktr0731/go-mcp/protocol"
+	"golang.org/x/exp/jsonrpc2"
+)
+
+func TestSSETransport(t *testing.T) {
+	t.Parallel()
+
+	canceled := make(chan struct{})
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			<-ctx.Done()
+			close(canceled)
+			return nil, ctx.Err()
+		}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	ctx, listener, binder, httpHandler := mcp.NewSSETransport(ctx, h, nil)
+	if _, err := jsonrpc2.Serve(ctx, listener, binder); err != nil {
+		t.Fatalf("failed to serve: %v", err)
+	}
+	srv := httptest.NewServer(httpHandler)
+	t.Cleanup(srv.Close)
+
+	sseCtx, disconnect := context.WithCancel(ctx)
+	defer disconnect()
+	req, err := http.NewRequestWithContext(sseCtx, http.MethodGet, srv.URL+"/sse", nil)
+	if err != nil {
+		t.Fatalf("failed to create a request: %v", err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to open an SSE stream: %v", err)
+	}
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("want text/event-stream, got %s", ct)
+	}
+	events := bufio.NewReader(res.Body)
+
+	event, endpoint := readSSEEvent(t, events)
+	if event != "endpoint" || !strings.HasPrefix(endpoint, "/messages?sessionId=") {
+		t.Fatalf("unexpected endpoint event: %s %s", event, endpoint)
+	}
+
+	post := func(msg *jsonrpc2.Request) {
+		t.Helper()
+
+		b, err := jsonrpc2.EncodeMessage(msg)
+		if err != nil {
+			t.Fatalf("failed to encode a message: %v", err)
+		}
+		res, err := http.Post(srv.URL+endpoint, "application/json", bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("failed to post a message: %v", err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusAccepted {
+			t.Fatalf("want status %d, got %d", http.StatusAccepted, res.StatusCode)
+		}
+	}
+
+	post(newCall(t, 1, protocol.MethodInitialize, protocol.InitializeRequestParams{ProtocolVersion: protocol.LatestProtocolVersion}))
+	if event, data := readSSEEvent(t, events); event != "message" {
+		t.Fatalf("want message event, got %s %s", event, data)
+	}
+	initialized, err := jsonrpc2.NewNotification(protocol.MethodNotificationsInitialized, struct{}{})
+	if err != nil {
+		t.Fatalf("failed to create a notification: %v", err)
+	}
+	post(initialized)
+
+	post(newCall(t, 1, protocol.MethodPing, struct{}{}))
+	event, data := readSSEEvent(t, events)
+	if event != "message" {
+		t.Fatalf("want message event, got %s", event)
+	}
+	var pong struct {
+		ID     int64           `json:"id"`
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(data), &pong); err != nil {
+		t.Fatalf("failed to decode the response: %v", err)
+	}
+	if pong.ID != 1 || string(pong.Result) != "{}" {
+		t.Errorf("unexpected response: %s", data)
+	}
+
+	// The in-flight tool call is canceled when the client disconnects.
+	post(newCall(t, 2, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "slow"}))
+	disconnect()
+
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the tool call was not canceled after the client disconnected")
+	}
+}
+
+func TestSSETransport_MaxMessageSize(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	ctx, listener, binder, httpHandler := mcp.NewSSETransport(ctx, toolsHandler(1), &mcp.SSETransportOptions{MaxMessageSize: 64})
+	if _, err := jsonrpc2.Serve(ctx, listener, binder); err != nil {
+		t.Fatalf("failed to serve: %v", err)
+	}
+	srv := httptest.NewServer(httpHandler)
+	t.Cleanup(srv.Close)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/sse", nil)
+	if err != nil {
+		t.Fatalf("failed to create a request: %v", err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to open an SSE stream: %v", err)
+	}
+	defer res.Body.Close()
+	_, endpoint := readSSEEvent(t, bufio.NewReader(res.Body))
+
+	post := func(msg *jsonrpc2.Request) int {
+		t.Helper()
+
+		b, err := jsonrpc2.EncodeMessage(msg)
+		if err != nil {
+			t.Fatalf("failed to encode a message: %v", err)
+		}
+		res, err := http.Post(srv.URL+endpoint, "application/json", bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("failed to post a message: %v", err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	if got := post(newCall(t, 1, protocol.MethodPing, struct{}{})); got != http.StatusAccepted {
+		t.Errorf("want status %d, got %d", http.StatusAccepted, got)
+	}
+	large := newCall(t, 2, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: strings.Repeat("a", 100)})
+	if got := post(large); got != http.StatusRequestEntityTooLarge {
+		t.Errorf("want status %d, got %d", http.StatusRequestEntityTooLarge, got)
+	}
+}
+
+func TestSSEClientTransport(t *testing.T) {
+	t.Parallel()
+
+	h := toolsHandler(1)
+	h.Capabilities.Logging = &protocol.LoggingCapability{}
+	h.ToolHandler = toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+		mcp.Logger(ctx, "tool").Info("called", "name", req.Name)
+		return mcp.NewTextToolResult("hello from " + req.Name), nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	ctx, listener, binder, httpHandler := mcp.NewSSETransport(ctx, h, nil)
+	if _, err := jsonrpc2.Serve(ctx, listener, binder); err != nil {
+		t.Fatalf("failed to serve: %v", err)
+	}
+	srv := httptest.NewServer(httpHandler)
+	t.Cleanup(srv.Close)
+
+	logs := make(chan *mcp.LogMessage, 1)
+	client, err := mcp.NewClient(ctx, mcp.NewSSEClientTransport(srv.URL+"/sse", nil), &mcp.ClientOptions{
+		OnLog: func(msg *mcp.LogMessage) { logs <- msg },
+	})
+	if err != nil {
+		t.Fatalf("failed to create a client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools returned an error: %v", err)
+	}
+	if len(tools) != 1 {
+		t.Errorf("want 1 tool, got %d", len(tools))
+	}
+
+	res, err := client.CallTool(ctx, "tool_0", map[string]any{})
+	if err != nil {
+		t.Fatalf("CallTool returned an error: %v", err)
+	}
+	if want := (mcp.TextContent{Text: "hello from tool_0"}); len(res.Content) != 1 || res.Content[0] != want {
+		t.Errorf("want [%+v], got %+v", want, res.Content)
+	}
+
+	// Log notifications are sent to the SSE stream of the connection.
+	select {
+	case msg := <-logs:
+		if msg.Logger != "tool" {
+			t.Errorf("want a log of the tool logger, got %+v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("log notification was not received")
+	}
+}
+
+func readSSEEvent(t *testing.T, r *bufio.Reader) (event, data string) {
+	t.Helper()
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read an SSE event: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			return event, data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
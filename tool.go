@@ -0,0 +1,181 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/invopop/jsonschema"
+	"github.com/ktr0731/go-mcp/protocol"
+	"golang.org/x/exp/jsonrpc2"
+)
+
+// ToolHandler handles calls of a single tool registered by Handler.AddTool.
+type ToolHandler interface {
+	HandleTool(ctx context.Context, req protocol.CallToolRequestParams) (*CallToolResult, error)
+}
+
+// ToolHandlerFunc returns a ToolHandler that decodes arguments into In and calls fn with them.
+// The input schema of the tool is reflected from In, in the same way as codegen does for Tool.InputSchema,
+// and arguments are validated against it before decoding.
+// This gives the same type safety as generated code without code generation.
+func ToolHandlerFunc[In any](fn func(ctx context.Context, in In) (*CallToolResult, error)) ToolHandler {
+	var in In
+	// Expand a named root type so that the schema is an object schema rather than a reference to a definition.
+	// Anonymous types are always expanded.
+	typ := reflect.TypeOf(in)
+	reflector := jsonschema.Reflector{ExpandedStruct: typ != nil && typ.Name() != ""}
+	schema, err := json.Marshal(reflector.Reflect(in))
+	if err != nil {
+		panic(fmt.Sprintf("mcp: failed to reflect the input schema of %T: %v", in, err))
+	}
+	return &typedToolHandler[In]{fn: fn, schema: schema}
+}
+
+type typedToolHandler[In any] struct {
+	fn     func(ctx context.Context, in In) (*CallToolResult, error)
+	schema json.RawMessage
+}
+
+func (h *typedToolHandler[In]) HandleTool(ctx context.Context, req protocol.CallToolRequestParams) (*CallToolResult, error) {
+	in, err := decodeToolArguments[In](req.Arguments, h.schema)
+	if err != nil {
+		return nil, err
+	}
+	return h.fn(ctx, in)
+}
+
+func (h *typedToolHandler[In]) inputSchema() json.RawMessage { return h.schema }
+
+// AddTool registers a tool handled by th. If a tool with the same name is already registered, it is replaced.
+// If th is created by ToolHandlerFunc, the input schema of the tool is reflected from its input type.
+// Otherwise, the tool accepts any object.
+// The Tools capability is enabled if it isn't yet. AddTool must be called before serving.
+//
+// Calls of tools registered by AddTool are dispatched to their handlers instead of ToolHandler.
+func (h *Handler) AddTool(name, description string, th ToolHandler) {
+	schema := json.RawMessage(`{"type":"object"}`)
+	if s, ok := th.(interface{ inputSchema() json.RawMessage }); ok {
+		schema = s.inputSchema()
+	}
+	h.registerTool(protocol.Tool{Name: name, Description: description, InputSchema: schema}, th)
+	if h.Capabilities.Tools == nil {
+		h.Capabilities.Tools = &protocol.ToolCapability{}
+	}
+}
+
+// ErrToolAlreadyRegistered is returned by RegisterTool when a tool with the same name is already registered.
+var ErrToolAlreadyRegistered = errors.New("tool is already registered")
+
+// RegisterTool registers tool handled by th at runtime.
+// Unlike AddTool, tool is offered as is, and RegisterTool may be called while serving.
+// If the Tools capability enables ListChanged, notifications/tools/list_changed is sent to clients.
+// Unlike AddTool, it doesn't enable the Tools capability, which must be enabled before serving.
+//
+// If a tool with the same name is already registered, it returns an error wrapping ErrToolAlreadyRegistered
+// so that a tool can't silently shadow another one. Call UnregisterTool first to replace a tool.
+//
+// See https://modelcontextprotocol.io/specification/2025-03-26/server/tools#list-changed-notification
+func (h *Handler) RegisterTool(ctx context.Context, tool protocol.Tool, th ToolHandler) error {
+	if h.Capabilities.Tools == nil {
+		return errors.New("the Tools capability is not enabled")
+	}
+	h.toolsMu.Lock()
+	if slices.ContainsFunc(h.Tools, func(t protocol.Tool) bool { return t.Name == tool.Name }) {
+		h.toolsMu.Unlock()
+		return fmt.Errorf("%w: %s", ErrToolAlreadyRegistered, tool.Name)
+	}
+	h.registerToolLocked(tool, th)
+	h.toolsMu.Unlock()
+
+	return h.notifyToolsListChanged(ctx)
+}
+
+// UnregisterTool removes the tool with the given name. It does nothing if no such tool is registered.
+// If the Tools capability enables ListChanged, notifications/tools/list_changed is sent to clients.
+func (h *Handler) UnregisterTool(ctx context.Context, name string) error {
+	h.toolsMu.Lock()
+	// Tools is replaced rather than modified in place because tools/list reads it after releasing toolsMu.
+	n := len(h.Tools)
+	h.Tools = slices.DeleteFunc(slices.Clone(h.Tools), func(t protocol.Tool) bool { return t.Name == name })
+	removed := len(h.Tools) != n
+	delete(h.toolHandlers, name)
+	h.toolsMu.Unlock()
+
+	if !removed {
+		return nil
+	}
+	return h.notifyToolsListChanged(ctx)
+}
+
+// registerTool registers tool handled by th, replacing the tool with the same name if any.
+func (h *Handler) registerTool(tool protocol.Tool, th ToolHandler) {
+	h.toolsMu.Lock()
+	defer h.toolsMu.Unlock()
+	h.registerToolLocked(tool, th)
+}
+
+// registerToolLocked is registerTool with toolsMu held.
+// Tools is replaced rather than modified in place because tools/list reads it after releasing toolsMu.
+func (h *Handler) registerToolLocked(tool protocol.Tool, th ToolHandler) {
+	tools := slices.Clone(h.Tools)
+	if idx := slices.IndexFunc(tools, func(t protocol.Tool) bool { return t.Name == tool.Name }); idx != -1 {
+		tools[idx] = tool
+	} else {
+		tools = append(tools, tool)
+	}
+	h.Tools = tools
+	if h.toolHandlers == nil {
+		h.toolHandlers = map[string]ToolHandler{}
+	}
+	h.toolHandlers[tool.Name] = th
+}
+
+// handlerKey is a key for retrieving the handler handling the request from the context
+type handlerKey struct{}
+
+// DecodeToolArguments decodes the arguments of a tools/call request into a value of type T.
+// If the tool is registered to the Handler handling ctx (i.e. it is in Handler.Tools) and has an input schema,
+// the arguments are validated against the schema before decoding, so that handlers which aren't generated by codegen
+// have the same safety as generated ones.
+// An error is wrapped with jsonrpc2.ErrInvalidParams, so it can be returned to the client as is.
+func DecodeToolArguments[T any](ctx context.Context, params protocol.CallToolRequestParams) (T, error) {
+	var schema any
+	if h, ok := ctx.Value(handlerKey{}).(*Handler); ok {
+		if tool, ok := h.tool(params.Name); ok {
+			schema = tool.InputSchema
+		}
+	}
+	return decodeToolArguments[T](params.Arguments, schema)
+}
+
+// decodeToolArguments validates args against schema if schema is not nil, and decodes args into a value of type T.
+func decodeToolArguments[T any](args json.RawMessage, schema any) (T, error) {
+	var v T
+	if len(args) == 0 {
+		// Arguments are optional.
+		args = json.RawMessage("{}")
+	}
+
+	if schema != nil {
+		s, ok := schema.(json.RawMessage)
+		if !ok {
+			b, err := json.Marshal(schema)
+			if err != nil {
+				return v, fmt.Errorf("failed to marshal the input schema: %w", err)
+			}
+			s = b
+		}
+		if err := protocol.ValidateByJSONSchema(string(s), args); err != nil {
+			return v, fmt.Errorf("%w: %w", jsonrpc2.ErrInvalidParams, err)
+		}
+	}
+
+	if err := JSONUnmarshal(args, &v); err != nil {
+		return v, fmt.Errorf("%w: %w: %w", jsonrpc2.ErrInvalidParams, protocol.ErrInvalidToolArguments, err)
+	}
+	return v, nil
+}
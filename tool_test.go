@@ -0,0 +1,253 @@
+package mcp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"testing"
+	"time"
+
+	mcp "github.com/ktr0731/go-mcp"
+	"github.com/ktr0731/go-mcp/protocol"
+	"golang.org/x/exp/jsonrpc2"
+)
+
+func TestDecodeToolArguments(t *testing.T) {
+	t.Parallel()
+
+	type forecastArgs struct {
+		City string `json:"city"`
+	}
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		Tools: []protocol.Tool{{
+			Name:        "get_forecast",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}`),
+		}},
+		ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+			args, err := mcp.DecodeToolArguments[forecastArgs](ctx, req)
+			if err != nil {
+				return nil, err
+			}
+			return &mcp.CallToolResult{Content: []mcp.CallToolContent{mcp.TextContent{Text: args.City}}}, nil
+		}),
+	}
+	ctx := mcp.SetLogWriterToContext(context.Background(), &bytes.Buffer{})
+
+	cases := map[string]struct {
+		name    string
+		args    string
+		wantErr bool
+	}{
+		"valid":         {name: "get_forecast", args: `{"city":"Tokyo"}`},
+		"missing field": {name: "get_forecast", args: `{}`, wantErr: true},
+		"wrong type":    {name: "get_forecast", args: `{"city":1}`, wantErr: true},
+		"no schema":     {name: "unregistered", args: `{"city":"Tokyo"}`},
+		"undecodable":   {name: "unregistered", args: `{"city":1}`, wantErr: true},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := h.Handle(ctx, newCall(t, 1, protocol.MethodToolsCall, protocol.CallToolRequestParams{
+				Name:      c.name,
+				Arguments: json.RawMessage(c.args),
+			}))
+			if c.wantErr && !errors.Is(err, jsonrpc2.ErrInvalidParams) {
+				t.Errorf("want %v, got %v", jsonrpc2.ErrInvalidParams, err)
+			}
+			if !c.wantErr && err != nil {
+				t.Errorf("tools/call returned an error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDecodeToolArguments_ValidationError(t *testing.T) {
+	t.Parallel()
+
+	schema := json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"},"days":{"type":"integer","minimum":1}},"required":["city"]}`)
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+		Tools:        []protocol.Tool{{Name: "get_forecast", InputSchema: schema}},
+	}
+	var err error
+	h.ToolHandler = toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+		_, err = mcp.DecodeToolArguments[map[string]any](ctx, req)
+		return &mcp.CallToolResult{}, nil
+	})
+	ctx := mcp.SetLogWriterToContext(context.Background(), &bytes.Buffer{})
+
+	if _, herr := h.Handle(ctx, newCall(t, 1, protocol.MethodToolsCall, protocol.CallToolRequestParams{
+		Name:      "get_forecast",
+		Arguments: json.RawMessage(`{"days":0}`),
+	})); herr != nil {
+		t.Fatalf("tools/call returned an error: %v", herr)
+	}
+
+	var verr *protocol.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("want a validation error, got %v", err)
+	}
+	got := map[string]string{}
+	for _, f := range verr.Failures {
+		got[f.Field] = f.Constraint
+	}
+	want := map[string]string{"(root)": "required", "days": "number_gte"}
+	if !maps.Equal(want, got) {
+		t.Errorf("want failures %v, got %v", want, got)
+	}
+}
+
+func TestHandler_AddTool(t *testing.T) {
+	t.Parallel()
+
+	type forecastArgs struct {
+		City string `json:"city" jsonschema:"description=City name"`
+	}
+
+	h := &mcp.Handler{}
+	h.AddTool("get_forecast", "Get the weather forecast", mcp.ToolHandlerFunc(func(ctx context.Context, in forecastArgs) (*mcp.CallToolResult, error) {
+		return &mcp.CallToolResult{Content: []mcp.CallToolContent{mcp.TextContent{Text: "sunny in " + in.City}}}, nil
+	}))
+	client := newInMemoryClient(t, h)
+	ctx := context.Background()
+
+	res, err := client.Call(ctx, protocol.MethodToolsList, struct{}{})
+	if err != nil {
+		t.Fatalf("tools/list returned an error: %v", err)
+	}
+	var list struct {
+		Tools []struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+			InputSchema struct {
+				Required []string `json:"required"`
+			} `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(res, &list); err != nil {
+		t.Fatalf("failed to unmarshal the result: %v", err)
+	}
+	if len(list.Tools) != 1 || list.Tools[0].Name != "get_forecast" || list.Tools[0].Description != "Get the weather forecast" {
+		t.Fatalf("unexpected tools: %s", res)
+	}
+	if got := list.Tools[0].InputSchema.Required; len(got) != 1 || got[0] != "city" {
+		t.Errorf("want the input schema to require city, got %v", got)
+	}
+
+	res, err = client.Call(ctx, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: "get_forecast", Arguments: json.RawMessage(`{"city":"Tokyo"}`)})
+	if err != nil {
+		t.Fatalf("tools/call returned an error: %v", err)
+	}
+	want := `{"content":[{"type":"text","text":"sunny in Tokyo"}]}`
+	if string(res) != want {
+		t.Errorf("want %s, got %s", want, res)
+	}
+
+	if _, err := client.CallTool(ctx, "get_forecast", struct{}{}); err == nil {
+		t.Error("want an error for missing arguments, got nil")
+	}
+}
+
+func TestHandler_RegisterTool(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{
+		Capabilities: protocol.ServerCapabilities{Tools: &protocol.ToolCapability{ListChanged: true}},
+	}
+	notified := make(chan struct{}, 2)
+	conn := serveWithClientHandler(t, h, jsonrpc2.HandlerFunc(func(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+		if req.Method == protocol.MethodNotificationsToolsListChanged {
+			notified <- struct{}{}
+		}
+		return nil, nil
+	}))
+
+	ctx := context.Background()
+	// Wait for the connection to be bound before sending notifications.
+	if err := conn.Call(ctx, protocol.MethodPing, struct{}{}).Await(ctx, nil); err != nil {
+		t.Fatalf("ping returned an error: %v", err)
+	}
+	waitNotification := func() {
+		t.Helper()
+		select {
+		case <-notified:
+		case <-time.After(5 * time.Second):
+			t.Fatal("notification was not received")
+		}
+	}
+
+	echo := mcp.ToolHandlerFunc(func(ctx context.Context, in struct {
+		Text string `json:"text"`
+	}) (*mcp.CallToolResult, error) {
+		return &mcp.CallToolResult{Content: []mcp.CallToolContent{mcp.TextContent{Text: in.Text}}}, nil
+	})
+	tool := protocol.Tool{Name: "echo", InputSchema: json.RawMessage(`{"type":"object"}`)}
+	if err := h.RegisterTool(ctx, tool, echo); err != nil {
+		t.Fatalf("RegisterTool returned an error: %v", err)
+	}
+	waitNotification()
+
+	// A tool with the same name can't shadow the registered one.
+	if err := h.RegisterTool(ctx, tool, echo); !errors.Is(err, mcp.ErrToolAlreadyRegistered) {
+		t.Errorf("want %v, got %v", mcp.ErrToolAlreadyRegistered, err)
+	}
+	if got := h.RegisteredTools(); len(got) != 1 {
+		t.Errorf("want 1 tool, got %+v", got)
+	}
+
+	var res json.RawMessage
+	params := map[string]any{"name": "echo", "arguments": map[string]any{"text": "hello"}}
+	if err := conn.Call(ctx, protocol.MethodToolsCall, params).Await(ctx, &res); err != nil {
+		t.Fatalf("tools/call returned an error: %v", err)
+	}
+	if want := `{"content":[{"type":"text","text":"hello"}]}`; string(res) != want {
+		t.Errorf("want %s, got %s", want, res)
+	}
+
+	if err := h.UnregisterTool(ctx, "echo"); err != nil {
+		t.Fatalf("UnregisterTool returned an error: %v", err)
+	}
+	waitNotification()
+	if got := h.RegisteredTools(); len(got) != 0 {
+		t.Errorf("want no tools, got %+v", got)
+	}
+	if err := conn.Call(ctx, protocol.MethodToolsCall, params).Await(ctx, nil); err == nil {
+		t.Error("want an error for the unregistered tool, got nil")
+	}
+
+	// Unregistering an unknown tool doesn't notify clients.
+	if err := h.UnregisterTool(ctx, "echo"); err != nil {
+		t.Fatalf("UnregisterTool returned an error: %v", err)
+	}
+	select {
+	case <-notified:
+		t.Error("want no notification for an unknown tool")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// The tool can be registered again after it is unregistered.
+	if err := h.RegisterTool(ctx, tool, echo); err != nil {
+		t.Fatalf("RegisterTool returned an error: %v", err)
+	}
+	waitNotification()
+}
+
+func TestHandler_RegisterTool_CapabilityDisabled(t *testing.T) {
+	t.Parallel()
+
+	h := &mcp.Handler{}
+	tool := protocol.Tool{Name: "echo", InputSchema: json.RawMessage(`{"type":"object"}`)}
+	if err := h.RegisterTool(context.Background(), tool, mcp.ToolHandlerFunc(func(ctx context.Context, in struct{}) (*mcp.CallToolResult, error) {
+		return &mcp.CallToolResult{}, nil
+	})); err == nil {
+		t.Error("want an error, got nil")
+	}
+	if got := h.RegisteredTools(); len(got) != 0 {
+		t.Errorf("want no tools, got %+v", got)
+	}
+}
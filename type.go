@@ -5,8 +5,14 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"iter"
+	"maps"
+	"math"
+	"strings"
+	"time"
 
 	"github.com/ktr0731/go-mcp/protocol"
 )
@@ -43,6 +49,19 @@ type ServerResourceHandler interface {
 	HandleResourcesRead(ctx context.Context, req *ReadResourceRequest) (*ReadResourceResult, error)
 }
 
+// ServerResourceStreamer is an optional interface of ServerResourceHandler for servers offering large catalogs of resources.
+// If ServerResourceHandler implements it, resources/list requests are handled by HandleResourcesListStream
+// instead of HandleResourcesList.
+//
+// Neither the stdio transport nor the SSE transport can stream a single response,
+// so resources are paginated by Handler.PageSize. Only the resources up to the end of the requested page are enumerated,
+// so the first page is returned without enumerating the whole catalog.
+type ServerResourceStreamer interface {
+	// HandleResourcesListStream returns an iterator over the resources the server offers.
+	// The order of the resources must be stable across requests so that cursors remain valid.
+	HandleResourcesListStream(ctx context.Context) iter.Seq2[Resource, error]
+}
+
 // ReadResourceRequest represents a request to read a specific resource.
 // ReadResourceRequest is sent from the client to the server, to read a specific resource URI.
 type ReadResourceRequest struct {
@@ -50,11 +69,92 @@ type ReadResourceRequest struct {
 	URI string `json:"uri"`
 }
 
+// ByteRange is a byte range of a resource requested by a client, similar to the HTTP Range header.
+// Clients request it by the "range" field of _meta of a resources/read request, e.g. {"_meta": {"range": {"offset": 0, "length": 1024}}}.
+// Use RequestedByteRange to retrieve it in ServerResourceHandler.HandleResourcesRead.
+type ByteRange struct {
+	// Offset is the offset of the first byte of the range.
+	Offset int64 `json:"offset"`
+	// Length is the number of bytes of the range. If this is zero, the range extends to the end of the resource.
+	Length int64 `json:"length,omitzero"`
+}
+
+// Section returns a reader that reads the range from r.
+// It can be used as BlobResourceContent.Blob, e.g. with an *os.File.
+func (r ByteRange) Section(ra io.ReaderAt) io.Reader {
+	n := r.Length
+	if n == 0 {
+		n = math.MaxInt64 - r.Offset
+	}
+	return io.NewSectionReader(ra, r.Offset, n)
+}
+
 // ReadResourceResult represents the response for a resource read operation.
 // ReadResourceResult is the server's response to a resources/read request from the client.
 type ReadResourceResult struct {
 	// Contents is a list of contents of the resource.
 	Contents []ResourceContent `json:"contents"`
+	// Cache is optional cache metadata of the resource. It is conveyed to clients in _meta of the result,
+	// e.g. {"_meta": {"etag": "v1", "maxAge": 60}}, so that they can skip reading an unchanged resource again.
+	Cache *CacheControl `json:"-"`
+	// Meta is optional implementation-specific metadata of the result, sent as _meta.
+	// The fields of Cache take precedence over the same keys of Meta.
+	Meta map[string]any `json:"_meta,omitzero"`
+}
+
+func (r ReadResourceResult) MarshalJSON() ([]byte, error) {
+	meta := r.Meta
+	if r.Cache != nil {
+		meta = maps.Clone(meta)
+		if meta == nil {
+			meta = map[string]any{}
+		}
+		if r.Cache.ETag != "" {
+			meta["etag"] = r.Cache.ETag
+		}
+		if r.Cache.MaxAge > 0 {
+			meta["maxAge"] = int64(r.Cache.MaxAge / time.Second)
+		}
+	}
+	return json.Marshal(struct {
+		Contents []ResourceContent `json:"contents"`
+		Meta     map[string]any    `json:"_meta,omitzero"`
+	}{
+		Contents: r.Contents,
+		Meta:     meta,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler for ReadResourceResult, which is used by clients.
+// Cache metadata is left in Meta.
+func (r *ReadResourceResult) UnmarshalJSON(b []byte) error {
+	var v struct {
+		Contents []json.RawMessage `json:"contents"`
+		Meta     map[string]any    `json:"_meta"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	contents := make([]ResourceContent, len(v.Contents))
+	for i, c := range v.Contents {
+		content, err := unmarshalResourceContent(c)
+		if err != nil {
+			return err
+		}
+		contents[i] = content
+	}
+	*r = ReadResourceResult{Contents: contents, Meta: v.Meta}
+	return nil
+}
+
+// CacheControl is cache metadata of a resource, similar to the ETag and Cache-Control: max-age headers of HTTP.
+type CacheControl struct {
+	// ETag is an opaque identifier of the current version of the resource.
+	// Clients may compare it with the one of the previous read to determine whether the resource has changed.
+	ETag string
+	// MaxAge is how long clients may reuse the result without reading the resource again.
+	// It is sent in seconds, truncating fractions.
+	MaxAge time.Duration
 }
 
 // Resource represents a resource handled by the server.
@@ -97,6 +197,11 @@ type ResourceTemplate struct {
 
 	// Annotations are optional annotations for the client.
 	Annotations *Annotations `json:"annotations,omitzero"`
+
+	// CompletableVariables is the names of the template variables whose values can be completed by completion/complete.
+	// If this is set, completion requests for other variables of the template are answered with no values
+	// without calling ServerCompletionHandler. It is not sent to clients.
+	CompletableVariables []string `json:"-"`
 }
 
 // ResourceContent is the interface for contents of a specific resource or sub-resource.
@@ -135,13 +240,12 @@ type BlobResourceContent struct {
 	// MimeType is the MIME type of this resource, if known.
 	MimeType string
 	// Blob is the binary data of the item.
+	// Its size is limited by Handler.MaxBinaryContentSize, which defaults to DefaultMaxBinaryContentSize.
 	Blob io.Reader
 }
 
 func (b BlobResourceContent) MarshalJSON() ([]byte, error) {
-	var buf bytes.Buffer
-	encoder := base64.NewEncoder(base64.StdEncoding, &buf)
-	_, err := io.Copy(encoder, b.Blob)
+	data, err := encodeBase64(b.Blob)
 	if err != nil {
 		return nil, fmt.Errorf("failed to encode blob: %w", err)
 	}
@@ -153,12 +257,211 @@ func (b BlobResourceContent) MarshalJSON() ([]byte, error) {
 	}{
 		URI:      b.URI,
 		MimeType: b.MimeType,
-		Data:     buf.String(),
+		Data:     data,
 	})
 }
 
 func (b BlobResourceContent) isResourceContent() {}
 
+// unmarshalResourceContent unmarshals a resource content into TextResourceContent or BlobResourceContent.
+// DirectoryResourceContent is unmarshaled as TextResourceContent with DirectoryMimeType.
+func unmarshalResourceContent(b []byte) (ResourceContent, error) {
+	var v struct {
+		URI      string  `json:"uri"`
+		MimeType string  `json:"mimeType"`
+		Text     string  `json:"text"`
+		Data     *string `json:"data"`
+		// Blob is the name of the binary data in the spec, which some servers use instead of data.
+		Blob *string `json:"blob"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal resource content: %w", err)
+	}
+	data := v.Data
+	if data == nil {
+		data = v.Blob
+	}
+	if data == nil {
+		return TextResourceContent{URI: v.URI, MimeType: v.MimeType, Text: v.Text}, nil
+	}
+	blob, err := base64.StdEncoding.DecodeString(*data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode blob: %w", err)
+	}
+	return BlobResourceContent{URI: v.URI, MimeType: v.MimeType, Blob: bytes.NewReader(blob)}, nil
+}
+
+// DefaultMaxBinaryContentSize is the default of Handler.MaxBinaryContentSize.
+const DefaultMaxBinaryContentSize = 32 << 20
+
+// ErrContentTooLarge is returned when binary content exceeds Handler.MaxBinaryContentSize.
+var ErrContentTooLarge = errors.New("content is too large")
+
+// encodeBase64 reads all data from r and returns it encoded by base64.
+func encodeBase64(r io.Reader) (string, error) {
+	var buf bytes.Buffer
+	encoder := base64.NewEncoder(base64.StdEncoding, &buf)
+	if _, err := io.Copy(encoder, r); err != nil {
+		return "", err
+	}
+	// Close flushes the last partial block.
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// limitBinaryContent returns res whose binary content fails to be marshaled with ErrContentTooLarge
+// as soon as the data exceeds limit bytes. Only results of tools/call, prompts/get and resources/read are limited,
+// and res is returned as is if limit is zero. The readers of the content are wrapped lazily, so nothing is read here.
+func limitBinaryContent(res any, limit int64) any {
+	if limit <= 0 {
+		return res
+	}
+	switch r := res.(type) {
+	case *CallToolResult:
+		if r == nil {
+			return res
+		}
+		return limitBinaryContent(*r, limit)
+	case CallToolResult:
+		content := make([]CallToolContent, len(r.Content))
+		for i, c := range r.Content {
+			content[i] = limitContent(c, limit).(CallToolContent)
+		}
+		if r.Content != nil {
+			r.Content = content
+		}
+		return &r
+	case *GetPromptResult:
+		if r == nil {
+			return res
+		}
+		return limitBinaryContent(*r, limit)
+	case GetPromptResult:
+		messages := make([]PromptMessage, len(r.Messages))
+		for i, m := range r.Messages {
+			if m.Content != nil {
+				m.Content = limitContent(m.Content, limit).(PromptMessageContent)
+			}
+			messages[i] = m
+		}
+		if r.Messages != nil {
+			r.Messages = messages
+		}
+		return &r
+	case *ReadResourceResult:
+		if r == nil {
+			return res
+		}
+		return limitBinaryContent(*r, limit)
+	case ReadResourceResult:
+		contents := make([]ResourceContent, len(r.Contents))
+		for i, c := range r.Contents {
+			contents[i] = limitContent(c, limit).(ResourceContent)
+		}
+		if r.Contents != nil {
+			r.Contents = contents
+		}
+		return &r
+	}
+	return res
+}
+
+// limitContent returns c whose binary data is wrapped by sizeLimitedReader.
+func limitContent(c any, limit int64) any {
+	switch c := c.(type) {
+	case BlobResourceContent:
+		if c.Blob != nil {
+			c.Blob = &sizeLimitedReader{r: c.Blob, n: limit, limit: limit}
+		}
+		return c
+	case ImageContent:
+		if c.Data != nil {
+			c.Data = &sizeLimitedReader{r: c.Data, n: limit, limit: limit}
+		}
+		return c
+	case AudioContent:
+		if c.Data != nil {
+			c.Data = &sizeLimitedReader{r: c.Data, n: limit, limit: limit}
+		}
+		return c
+	case EmbeddedResource:
+		if c.Resource != nil {
+			c.Resource = limitContent(c.Resource, limit).(ResourceContent)
+		}
+		return c
+	}
+	return c
+}
+
+// sizeLimitedReader is like io.LimitedReader, but fails with ErrContentTooLarge instead of returning io.EOF
+// when more than n bytes are read.
+type sizeLimitedReader struct {
+	r     io.Reader
+	n     int64 // remaining bytes
+	limit int64
+}
+
+func (l *sizeLimitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, fmt.Errorf("%w: more than %d bytes", ErrContentTooLarge, l.limit)
+	}
+	// Read one more byte than the limit to detect the excess.
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, fmt.Errorf("%w: more than %d bytes", ErrContentTooLarge, l.limit)
+	}
+	return n, err
+}
+
+// DirectoryMimeType is the MIME type of DirectoryResourceContent.
+const DirectoryMimeType = "inode/directory"
+
+// DirectoryResourceContent represents a directory-style listing of a collection resource, e.g. "weather://forecast/".
+// It is sent as textual content with DirectoryMimeType, whose text is a JSON object of the child resources,
+// e.g. {"resources": [{"uri": "weather://forecast/tokyo", "name": "Tokyo"}]}.
+// Use IsCollectionURI to distinguish collection resources from leaf resources.
+type DirectoryResourceContent struct {
+	// URI is the URI of the collection resource.
+	URI string
+	// Entries is a list of child resources of the collection.
+	Entries []Resource
+}
+
+func (d DirectoryResourceContent) MarshalJSON() ([]byte, error) {
+	entries := d.Entries
+	if entries == nil {
+		entries = []Resource{}
+	}
+	text, err := json.Marshal(struct {
+		Resources []Resource `json:"resources"`
+	}{
+		Resources: entries,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode entries: %w", err)
+	}
+
+	return json.Marshal(TextResourceContent{
+		URI:      d.URI,
+		MimeType: DirectoryMimeType,
+		Text:     string(text),
+	})
+}
+
+func (d DirectoryResourceContent) isResourceContent() {}
+
+// IsCollectionURI reports whether uri refers to a collection of resources rather than a leaf resource.
+// Like directories of file systems, collection URIs end with "/".
+func IsCollectionURI(uri string) bool {
+	return strings.HasSuffix(uri, "/")
+}
+
 // listPromptsResult represents the response for prompts list.
 // listPromptsResult is the server's response to a prompts/list request from the client.
 type listPromptsResult struct {
@@ -172,6 +475,8 @@ type GetPromptResult struct {
 	Description string `json:"description,omitzero"`
 	// Arguments is a list of arguments to use for templating the prompt.
 	Messages []PromptMessage `json:"messages"`
+	// Meta is optional implementation-specific metadata of the result, sent as _meta.
+	Meta map[string]any `json:"_meta,omitzero"`
 }
 
 // Role represents the sender or recipient of messages and data in a conversation.
@@ -189,10 +494,32 @@ type PromptMessage struct {
 	// Role represents the role of the message sender/recipient.
 	Role Role `json:"role"`
 	// Content represents the content of the message.
-	// TextContent, ImageContent, AudioContent, or EmbeddedResource.
+	// TextContent, ImageContent, AudioContent, EmbeddedResource, or ResourceLink.
 	Content PromptMessageContent `json:"content"`
 }
 
+// UnmarshalJSON implements json.Unmarshaler for PromptMessage, which is used by clients.
+func (m *PromptMessage) UnmarshalJSON(b []byte) error {
+	var v struct {
+		Role    Role            `json:"role"`
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	c, err := unmarshalContent(v.Content)
+	if err != nil {
+		return err
+	}
+	*m = PromptMessage{Role: v.Role, Content: c}
+	return nil
+}
+
+// NewTextPromptMessage returns a prompt message of role with a single text content.
+func NewTextPromptMessage(role Role, text string) PromptMessage {
+	return PromptMessage{Role: role, Content: TextContent{Text: text}}
+}
+
 // TextContent represents text data.
 type TextContent struct {
 	// Text is the text content of the message.
@@ -220,6 +547,7 @@ func (t TextContent) isPromptMessageContent() {}
 // ImageContent represents image data.
 type ImageContent struct {
 	// Data is the image data.
+	// Its size is limited by Handler.MaxBinaryContentSize, which defaults to DefaultMaxBinaryContentSize.
 	Data io.Reader
 	// MimeType is the MIME type of the image. Different providers may support different image types.
 	MimeType string
@@ -229,9 +557,7 @@ type ImageContent struct {
 }
 
 func (i ImageContent) MarshalJSON() ([]byte, error) {
-	var buf bytes.Buffer
-	encoder := base64.NewEncoder(base64.StdEncoding, &buf)
-	_, err := io.Copy(encoder, i.Data)
+	data, err := encodeBase64(i.Data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to encode image: %w", err)
 	}
@@ -244,16 +570,18 @@ func (i ImageContent) MarshalJSON() ([]byte, error) {
 	}{
 		Type:        "image",
 		MimeType:    i.MimeType,
-		Data:        buf.String(),
+		Data:        data,
 		Annotations: i.Annotations,
 	})
 }
 
+func (i ImageContent) isCallToolContent()      {}
 func (i ImageContent) isPromptMessageContent() {}
 
 // AudioContent represents audio data.
 type AudioContent struct {
 	// Data is the audio data.
+	// Its size is limited by Handler.MaxBinaryContentSize, which defaults to DefaultMaxBinaryContentSize.
 	Data io.Reader
 	// MimeType is the MIME type of the audio. Different providers may support different audio types.
 	MimeType string
@@ -263,9 +591,7 @@ type AudioContent struct {
 }
 
 func (a AudioContent) MarshalJSON() ([]byte, error) {
-	var buf bytes.Buffer
-	encoder := base64.NewEncoder(base64.StdEncoding, &buf)
-	_, err := io.Copy(encoder, a.Data)
+	data, err := encodeBase64(a.Data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to encode audio: %w", err)
 	}
@@ -278,11 +604,12 @@ func (a AudioContent) MarshalJSON() ([]byte, error) {
 	}{
 		Type:        "audio",
 		MimeType:    a.MimeType,
-		Data:        buf.String(),
+		Data:        data,
 		Annotations: a.Annotations,
 	})
 }
 
+func (a AudioContent) isCallToolContent()      {}
 func (a AudioContent) isPromptMessageContent() {}
 
 // EmbeddedResource represents the contents of a resource, embedded into a prompt or tool call result.
@@ -295,9 +622,59 @@ type EmbeddedResource struct {
 	Annotations *Annotations `json:"annotations,omitzero"`
 }
 
+func (e EmbeddedResource) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type        string          `json:"type"`
+		Resource    ResourceContent `json:"resource"`
+		Annotations *Annotations    `json:"annotations,omitzero"`
+	}{
+		Type:        "resource",
+		Resource:    e.Resource,
+		Annotations: e.Annotations,
+	})
+}
+
 func (e EmbeddedResource) isCallToolContent()      {}
 func (e EmbeddedResource) isPromptMessageContent() {}
 
+// ResourceLink represents a link to a resource, which clients can read by resources/read.
+// Unlike EmbeddedResource, the contents of the resource aren't included, so it is suitable for large resources.
+// ResourceLink was introduced in protocol version 2025-06-18. In tool results, it is sent to older clients as text content of the URI.
+type ResourceLink struct {
+	// URI is the URI of the resource.
+	URI string
+	// Name is a human-readable name of the resource.
+	Name string
+	// Description is an optional description of the resource.
+	Description string
+	// MimeType is the MIME type of the resource, if known.
+	MimeType string
+
+	// Annotations are optional annotations for the client.
+	Annotations *Annotations
+}
+
+func (l ResourceLink) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type        string       `json:"type"`
+		URI         string       `json:"uri"`
+		Name        string       `json:"name"`
+		Description string       `json:"description,omitzero"`
+		MimeType    string       `json:"mimeType,omitzero"`
+		Annotations *Annotations `json:"annotations,omitzero"`
+	}{
+		Type:        "resource_link",
+		URI:         l.URI,
+		Name:        l.Name,
+		Description: l.Description,
+		MimeType:    l.MimeType,
+		Annotations: l.Annotations,
+	})
+}
+
+func (l ResourceLink) isCallToolContent()      {}
+func (l ResourceLink) isPromptMessageContent() {}
+
 // listToolsResult represents the server's response to a tools/list request from the client.
 type listToolsResult struct {
 	NextCursor string          `json:"nextCursor,omitzero"`
@@ -305,13 +682,96 @@ type listToolsResult struct {
 }
 
 // CallToolContent is the interface for content that can be returned by a tool call.
-// TextContent and EmbeddedResource are the only valid types.
+// TextContent, ImageContent, AudioContent, EmbeddedResource and ResourceLink are the valid types.
 type CallToolContent interface {
 	isCallToolContent()
 }
 
+// ContentType is the type of content, which is the "type" field of marshaled content.
+type ContentType string
+
+const (
+	// ContentTypeText is the type of TextContent.
+	ContentTypeText ContentType = "text"
+	// ContentTypeImage is the type of ImageContent.
+	ContentTypeImage ContentType = "image"
+	// ContentTypeAudio is the type of AudioContent.
+	ContentTypeAudio ContentType = "audio"
+	// ContentTypeResource is the type of EmbeddedResource.
+	ContentTypeResource ContentType = "resource"
+	// ContentTypeResourceLink is the type of ResourceLink.
+	ContentTypeResourceLink ContentType = "resource_link"
+)
+
+// contentType returns the type of c. It returns an empty string for an unknown implementation.
+func contentType(c CallToolContent) ContentType {
+	switch c.(type) {
+	case TextContent, *TextContent:
+		return ContentTypeText
+	case ImageContent, *ImageContent:
+		return ContentTypeImage
+	case AudioContent, *AudioContent:
+		return ContentTypeAudio
+	case EmbeddedResource, *EmbeddedResource:
+		return ContentTypeResource
+	case ResourceLink, *ResourceLink:
+		return ContentTypeResourceLink
+	default:
+		return ""
+	}
+}
+
+// content is implemented by all types of content, which can be included in both tool results and prompt messages.
+type content interface {
+	CallToolContent
+	PromptMessageContent
+}
+
+// unmarshalContent unmarshals content of a tool result or a prompt message by its type.
+// The binary data of ImageContent and AudioContent is decoded into a bytes.Reader.
+func unmarshalContent(b []byte) (content, error) {
+	var v struct {
+		Type        ContentType     `json:"type"`
+		Text        string          `json:"text"`
+		Data        string          `json:"data"`
+		MimeType    string          `json:"mimeType"`
+		Resource    json.RawMessage `json:"resource"`
+		URI         string          `json:"uri"`
+		Name        string          `json:"name"`
+		Description string          `json:"description"`
+		Annotations *Annotations    `json:"annotations"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal content: %w", err)
+	}
+
+	switch v.Type {
+	case ContentTypeText:
+		return TextContent{Text: v.Text, Annotations: v.Annotations}, nil
+	case ContentTypeImage, ContentTypeAudio:
+		data, err := base64.StdEncoding.DecodeString(v.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s data: %w", v.Type, err)
+		}
+		if v.Type == ContentTypeImage {
+			return ImageContent{Data: bytes.NewReader(data), MimeType: v.MimeType, Annotations: v.Annotations}, nil
+		}
+		return AudioContent{Data: bytes.NewReader(data), MimeType: v.MimeType, Annotations: v.Annotations}, nil
+	case ContentTypeResource:
+		resource, err := unmarshalResourceContent(v.Resource)
+		if err != nil {
+			return nil, err
+		}
+		return EmbeddedResource{Resource: resource, Annotations: v.Annotations}, nil
+	case ContentTypeResourceLink:
+		return ResourceLink{URI: v.URI, Name: v.Name, Description: v.Description, MimeType: v.MimeType, Annotations: v.Annotations}, nil
+	default:
+		return nil, fmt.Errorf("unknown content type: %q", v.Type)
+	}
+}
+
 // PromptMessageContent is the interface for content that can be included in a prompt message.
-// TextContent, ImageContent, AudioContent, or EmbeddedResource.
+// TextContent, ImageContent, AudioContent, EmbeddedResource, or ResourceLink.
 type PromptMessageContent interface {
 	isPromptMessageContent()
 }
@@ -323,11 +783,116 @@ type PromptMessageContent interface {
 // and self-correct.
 type CallToolResult struct {
 	// Content is the content of the tool call.
-	// TextContent and EmbeddedResource are the only valid types.
+	// TextContent, ImageContent, AudioContent, EmbeddedResource and ResourceLink are the valid types.
+	// MarshalJSON emits a nil Content as an empty array.
 	Content []CallToolContent `json:"content"`
+	// StructuredContent is an optional JSON object that represents the structured result of the tool call.
+	// If the tool declares an output schema, this must be set and conform to the schema unless IsError is set.
+	// For backwards compatibility, tools returning structured content should also return the serialized JSON in a TextContent.
+	StructuredContent json.RawMessage `json:"structuredContent,omitzero"`
 	// IsError indicates whether the tool call ended in an error.
 	// If not set, this is assumed to be false (the call was successful).
 	IsError bool `json:"isError,omitzero"`
+	// Operations is an optional status of each sub-operation of a tool which performs multiple operations.
+	// It is conveyed to clients in _meta of the result, e.g. {"_meta": {"operations": [{"name": "a", "isError": true, "message": "..."}]}},
+	// so that agents can retry only the failed operations. See NewOperationsResult.
+	Operations []OperationStatus `json:"-"`
+	// Meta is optional implementation-specific metadata of the result, sent as _meta.
+	// Operations takes precedence over the same key of Meta.
+	Meta map[string]any `json:"_meta,omitzero"`
+}
+
+// MarshalJSON implements json.Marshaler for CallToolResult.
+// A nil Content is marshaled as an empty array instead of null because some clients reject null content.
+func (r CallToolResult) MarshalJSON() ([]byte, error) {
+	type alias CallToolResult
+	a := alias(r)
+	if a.Content == nil {
+		a.Content = []CallToolContent{}
+	}
+	if len(r.Operations) != 0 {
+		a.Meta = maps.Clone(a.Meta)
+		if a.Meta == nil {
+			a.Meta = map[string]any{}
+		}
+		a.Meta["operations"] = r.Operations
+	}
+	return json.Marshal(a)
+}
+
+// UnmarshalJSON implements json.Unmarshaler for CallToolResult, which is used by clients.
+// Operations are left in Meta.
+func (r *CallToolResult) UnmarshalJSON(b []byte) error {
+	var v struct {
+		Content           []json.RawMessage `json:"content"`
+		StructuredContent json.RawMessage   `json:"structuredContent"`
+		IsError           bool              `json:"isError"`
+		Meta              map[string]any    `json:"_meta"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	contents := make([]CallToolContent, len(v.Content))
+	for i, c := range v.Content {
+		content, err := unmarshalContent(c)
+		if err != nil {
+			return err
+		}
+		contents[i] = content
+	}
+	*r = CallToolResult{Content: contents, StructuredContent: v.StructuredContent, IsError: v.IsError, Meta: v.Meta}
+	return nil
+}
+
+// OperationStatus is the status of a sub-operation of a tool call.
+type OperationStatus struct {
+	// Name identifies the operation, e.g. the name of a file the operation processed.
+	Name string `json:"name"`
+	// IsError indicates whether the operation failed.
+	IsError bool `json:"isError,omitzero"`
+	// Message is an optional human-readable message of the operation, e.g. why it failed.
+	Message string `json:"message,omitzero"`
+}
+
+// NewTextToolResult returns a result of a tool call with a single text content.
+func NewTextToolResult(text string) *CallToolResult {
+	return &CallToolResult{Content: []CallToolContent{TextContent{Text: text}}}
+}
+
+// NewErrorToolResult returns an error result of a tool call with a single text content formatted by fmt.Sprintf,
+// so that the LLM can see what went wrong and self-correct.
+func NewErrorToolResult(format string, args ...any) *CallToolResult {
+	res := NewTextToolResult(fmt.Sprintf(format, args...))
+	res.IsError = true
+	return res
+}
+
+// NewOperationsResult returns a result of a tool call which performed the operations.
+// If some of the operations failed, the result is an error result, which reports a partial failure by Operations.
+// The content of the result summarizes the failed operations so that the LLM can see which parts to retry.
+// Additional content is appended after the summary.
+func NewOperationsResult(ops []OperationStatus, content ...CallToolContent) *CallToolResult {
+	var failed []string
+	for _, op := range ops {
+		if !op.IsError {
+			continue
+		}
+		if op.Message == "" {
+			failed = append(failed, op.Name)
+		} else {
+			failed = append(failed, fmt.Sprintf("%s (%s)", op.Name, op.Message))
+		}
+	}
+
+	summary := fmt.Sprintf("%d of %d operations succeeded.", len(ops)-len(failed), len(ops))
+	if len(failed) != 0 {
+		summary += " Failed: " + strings.Join(failed, ", ")
+	}
+	return &CallToolResult{
+		Content:    append([]CallToolContent{TextContent{Text: summary}}, content...),
+		IsError:    len(failed) != 0,
+		Operations: ops,
+	}
 }
 
 // Annotations represents optional annotations for the client.
@@ -348,6 +913,8 @@ type Annotations struct {
 type subscribeResourceRequest struct {
 	// URI is the URI of the resource to subscribe to. The URI can use any protocol; it is up to the server how to interpret it.
 	URI string `json:"uri"`
+	// URIs is a list of additional URIs to subscribe to at once. This is a non-standard extension.
+	URIs []string `json:"uris,omitzero"`
 }
 
 // unsubscribeResourceRequest represents the request to unsubscribe from a resource.
@@ -356,6 +923,14 @@ type subscribeResourceRequest struct {
 type unsubscribeResourceRequest struct {
 	// URI is the URI of the resource to unsubscribe from.
 	URI string `json:"uri"`
+	// URIs is a list of additional URIs to unsubscribe from at once. This is a non-standard extension.
+	URIs []string `json:"uris,omitzero"`
+}
+
+// resourceUpdatedNotificationParams represents the params of a notification that a resource has been updated.
+type resourceUpdatedNotificationParams struct {
+	// URI is the URI of the resource that has been updated.
+	URI string `json:"uri"`
 }
 
 // Reference represents a reference to a completion item.
@@ -363,6 +938,8 @@ type Reference struct {
 	Type CompletionReferenceType `json:"type"`
 	// Name is the name of the prompt or URI of the resource
 	Name string `json:"name"`
+	// URI is the URI or URI template of the resource, which is sent for ref/resource references.
+	URI string `json:"uri,omitzero"`
 }
 
 // CompletionReferenceType represents the type of a completion reference.
@@ -382,12 +959,27 @@ type CompleteRequestParams struct {
 	Ref Reference `json:"ref"`
 	// Argument contains the argument's information
 	Argument CompletionArgument `json:"argument"`
+	// Context is optional additional context for the completion, sent by clients supporting 2025-06-18 or later.
+	Context *CompletionContext `json:"context,omitzero"`
+}
+
+// CompletionContext is additional context for a completion request.
+type CompletionContext struct {
+	// Arguments is a map of the names of previously-resolved arguments to their values,
+	// e.g. the selected country when completing a city.
+	Arguments map[string]string `json:"arguments,omitzero"`
 }
 
 // CompleteResult represents the completion options for argument autocompletion.
 type CompleteResult struct {
 	// Values is an array of completion values. Must not exceed 100 items.
+	// If it does, Handler truncates it to 100 items and sets HasMore and Total.
 	Values []string `json:"values"`
+	// Items is an optional array of completion values with human-readable labels and descriptions, e.g. for opaque IDs.
+	// If this is set, Values is ignored. No protocol version defines labeled values yet,
+	// so Items are sent as they are only to clients declaring the experimental capability CompletionItemsCapability.
+	// Other clients receive only the values of Items. It is truncated in the same way as Values.
+	Items []CompletionItem `json:"-"`
 	// Total is the total number of completion options available. This can exceed the number of values actually sent in the response.
 	Total int `json:"total,omitzero"`
 	// HasMore indicates whether there are additional completion options beyond those provided in the current response,
@@ -395,6 +987,21 @@ type CompleteResult struct {
 	HasMore bool `json:"hasMore,omitzero"`
 }
 
+// CompletionItemsCapability is the name of the experimental client capability declaring that the client accepts
+// labeled completion values, i.e. {"capabilities": {"experimental": {"completionItems": {}}}} of the initialize request.
+// See CompleteResult.Items.
+const CompletionItemsCapability = "completionItems"
+
+// CompletionItem is a completion value with a human-readable label and description.
+type CompletionItem struct {
+	// Value is the value to complete the argument with.
+	Value string `json:"value"`
+	// Label is an optional human-readable label shown instead of the value.
+	Label string `json:"label,omitzero"`
+	// Description is an optional human-readable description of the value.
+	Description string `json:"description,omitzero"`
+}
+
 // ServerCompletionHandler is the interface for a server that can handle completion requests.
 type ServerCompletionHandler interface {
 	// HandleComplete handles a completion (completion/complete) request.
@@ -0,0 +1,210 @@
+package mcp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	mcp "github.com/ktr0731/go-mcp"
+	"github.com/ktr0731/go-mcp/protocol"
+)
+
+func TestCallToolResult_MarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		result *mcp.CallToolResult
+		want   string
+	}{
+		"content": {
+			result: &mcp.CallToolResult{
+				Content: []mcp.CallToolContent{mcp.TextContent{Text: "hello"}},
+			},
+			want: `{"content":[{"type":"text","text":"hello"}]}`,
+		},
+		"empty content": {
+			result: &mcp.CallToolResult{Content: []mcp.CallToolContent{}},
+			want:   `{"content":[]}`,
+		},
+		"structured content only": {
+			result: &mcp.CallToolResult{
+				StructuredContent: json.RawMessage(`{"temperature":22.5}`),
+			},
+			want: `{"content":[],"structuredContent":{"temperature":22.5}}`,
+		},
+		"nil content": {
+			result: &mcp.CallToolResult{IsError: true},
+			want:   `{"content":[],"isError":true}`,
+		},
+		"operations": {
+			result: mcp.NewOperationsResult([]mcp.OperationStatus{
+				{Name: "a.txt"},
+				{Name: "b.txt", IsError: true, Message: "permission denied"},
+				{Name: "c.txt", IsError: true},
+			}),
+			want: `{"content":[{"type":"text","text":"1 of 3 operations succeeded. Failed: b.txt (permission denied), c.txt"}],"isError":true,` +
+				`"_meta":{"operations":[{"name":"a.txt"},{"name":"b.txt","isError":true,"message":"permission denied"},{"name":"c.txt","isError":true}]}}`,
+		},
+		"meta": {
+			result: &mcp.CallToolResult{IsError: true, Meta: map[string]any{"traceId": "abc"}},
+			want:   `{"content":[],"isError":true,"_meta":{"traceId":"abc"}}`,
+		},
+		"text": {
+			result: mcp.NewTextToolResult("hello"),
+			want:   `{"content":[{"type":"text","text":"hello"}]}`,
+		},
+		"error": {
+			result: mcp.NewErrorToolResult("city not found: %s", "Atlantis"),
+			want:   `{"content":[{"type":"text","text":"city not found: Atlantis"}],"isError":true}`,
+		},
+		"operations succeeded": {
+			result: mcp.NewOperationsResult([]mcp.OperationStatus{{Name: "a.txt"}}, mcp.TextContent{Text: "done"}),
+			want:   `{"content":[{"type":"text","text":"1 of 1 operations succeeded."},{"type":"text","text":"done"}],"_meta":{"operations":[{"name":"a.txt"}]}}`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := json.Marshal(c.result)
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+			if string(b) != c.want {
+				t.Errorf("want %s, got %s", c.want, string(b))
+			}
+		})
+	}
+}
+
+func TestHandler_MaxBinaryContentSize(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		content func(data string) mcp.CallToolContent
+	}{
+		"blob": {content: func(data string) mcp.CallToolContent {
+			return mcp.EmbeddedResource{Resource: mcp.BlobResourceContent{URI: "file:///a.bin", Blob: strings.NewReader(data)}}
+		}},
+		"image": {content: func(data string) mcp.CallToolContent {
+			return mcp.ImageContent{Data: strings.NewReader(data), MimeType: "image/png"}
+		}},
+		"audio": {content: func(data string) mcp.CallToolContent {
+			return mcp.AudioContent{Data: strings.NewReader(data), MimeType: "audio/wav"}
+		}},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			h := &mcp.Handler{
+				Capabilities:         protocol.ServerCapabilities{Tools: &protocol.ToolCapability{}},
+				MaxBinaryContentSize: 4,
+				ToolHandler: toolHandlerFunc(func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
+					return mcp.CallToolResult{Content: []mcp.CallToolContent{c.content(req.Name)}}, nil
+				}),
+			}
+			call := func(data string) (json.RawMessage, error) {
+				res, err := h.Handle(context.Background(), newCall(t, 1, protocol.MethodToolsCall, protocol.CallToolRequestParams{Name: data}))
+				if err != nil {
+					t.Fatalf("tools/call returned an error: %v", err)
+				}
+				return json.Marshal(res)
+			}
+
+			b, err := call("abcd")
+			if err != nil {
+				t.Fatalf("want no error for content within the limit, got %v", err)
+			}
+			if !strings.Contains(string(b), `"YWJjZA=="`) {
+				t.Errorf("want the encoded data, got %s", b)
+			}
+
+			if _, err := call("abcde"); !errors.Is(err, mcp.ErrContentTooLarge) {
+				t.Errorf("want %v, got %v", mcp.ErrContentTooLarge, err)
+			}
+
+			h.MaxBinaryContentSize = -1
+			if _, err := call("abcde"); err != nil {
+				t.Errorf("want no error for unlimited content, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDirectoryResourceContent_MarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	content := mcp.DirectoryResourceContent{
+		URI:     "weather://forecast/",
+		Entries: []mcp.Resource{{URI: "weather://forecast/tokyo", Name: "Tokyo"}},
+	}
+	b, err := json.Marshal(content)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	want := `{"uri":"weather://forecast/","mimeType":"inode/directory","text":"{\"resources\":[{\"uri\":\"weather://forecast/tokyo\",\"name\":\"Tokyo\"}]}"}`
+	if string(b) != want {
+		t.Errorf("want %s, got %s", want, string(b))
+	}
+}
+
+func TestReadResourceResult_MarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	contents := []mcp.ResourceContent{mcp.TextResourceContent{URI: "weather://forecast/tokyo", Text: "sunny"}}
+	cases := map[string]struct {
+		cache *mcp.CacheControl
+		meta  map[string]any
+		want  string
+	}{
+		"no cache": {
+			want: `{"contents":[{"uri":"weather://forecast/tokyo","text":"sunny"}]}`,
+		},
+		"etag and max-age": {
+			cache: &mcp.CacheControl{ETag: "v1", MaxAge: 90 * time.Second},
+			want:  `{"contents":[{"uri":"weather://forecast/tokyo","text":"sunny"}],"_meta":{"etag":"v1","maxAge":90}}`,
+		},
+		"etag only": {
+			cache: &mcp.CacheControl{ETag: "v1"},
+			want:  `{"contents":[{"uri":"weather://forecast/tokyo","text":"sunny"}],"_meta":{"etag":"v1"}}`,
+		},
+		"meta and cache": {
+			cache: &mcp.CacheControl{ETag: "v1"},
+			meta:  map[string]any{"etag": "v0", "source": "jma"},
+			want:  `{"contents":[{"uri":"weather://forecast/tokyo","text":"sunny"}],"_meta":{"etag":"v1","source":"jma"}}`,
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := json.Marshal(&mcp.ReadResourceResult{Contents: contents, Cache: c.cache, Meta: c.meta})
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+			if string(b) != c.want {
+				t.Errorf("want %s, got %s", c.want, string(b))
+			}
+		})
+	}
+}
+
+func TestIsCollectionURI(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]bool{
+		"weather://forecast/":      true,
+		"weather://forecast/tokyo": false,
+		"file:///":                 true,
+	}
+	for uri, want := range cases {
+		if got := mcp.IsCollectionURI(uri); got != want {
+			t.Errorf("IsCollectionURI(%q): want %t, got %t", uri, want, got)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package mcp
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// URITemplate is a parsed URI template (according to RFC 6570).
+type URITemplate struct {
+	raw   string
+	parts []uriTemplatePart
+
+	re   *regexp.Regexp
+	vars []string
+}
+
+// uriTemplatePart is either a literal or an expression of a URI template.
+type uriTemplatePart struct {
+	literal string
+	expr    *uriTemplateExpr
+}
+
+// uriTemplateExpr is an expression enclosed by braces, e.g. "{city}" or "{?lat,lon}".
+type uriTemplateExpr struct {
+	operator byte
+	vars     []uriTemplateVar
+}
+
+// uriTemplateVar is a variable specification of an expression.
+type uriTemplateVar struct {
+	name      string
+	prefixLen int
+	explode   bool
+}
+
+// ParseURITemplate parses a URI template (according to RFC 6570).
+// It returns an error if the template is malformed, e.g. braces are unbalanced or a variable name is invalid.
+func ParseURITemplate(template string) (*URITemplate, error) {
+	t := &URITemplate{raw: template}
+
+	rest := template
+	for rest != "" {
+		open := strings.IndexByte(rest, '{')
+		closing := strings.IndexByte(rest, '}')
+		if closing != -1 && (open == -1 || closing < open) {
+			return nil, fmt.Errorf("invalid URI template %q: unexpected '}'", template)
+		}
+		if open == -1 {
+			t.parts = append(t.parts, uriTemplatePart{literal: rest})
+			break
+		}
+		if open > 0 {
+			t.parts = append(t.parts, uriTemplatePart{literal: rest[:open]})
+		}
+		rest = rest[open+1:]
+
+		end := strings.IndexByte(rest, '}')
+		if end == -1 {
+			return nil, fmt.Errorf("invalid URI template %q: unclosed '{'", template)
+		}
+		expr, err := parseURITemplateExpr(rest[:end])
+		if err != nil {
+			return nil, fmt.Errorf("invalid URI template %q: %w", template, err)
+		}
+		t.parts = append(t.parts, uriTemplatePart{expr: expr})
+		rest = rest[end+1:]
+	}
+
+	if err := t.compile(); err != nil {
+		return nil, fmt.Errorf("invalid URI template %q: %w", template, err)
+	}
+	return t, nil
+}
+
+// MustParseURITemplate is like ParseURITemplate but panics if the template is malformed.
+// It simplifies safe initialization of global variables holding parsed templates.
+func MustParseURITemplate(template string) *URITemplate {
+	t, err := ParseURITemplate(template)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func parseURITemplateExpr(s string) (*uriTemplateExpr, error) {
+	if s == "" {
+		return nil, fmt.Errorf("empty expression")
+	}
+	if strings.ContainsRune(s, '{') {
+		return nil, fmt.Errorf("nested '{' in expression %q", s)
+	}
+
+	expr := &uriTemplateExpr{}
+	switch s[0] {
+	case '+', '#', '.', '/', ';', '?', '&':
+		expr.operator = s[0]
+		s = s[1:]
+	case '=', ',', '!', '@', '|':
+		return nil, fmt.Errorf("reserved operator %q in expression", s[0])
+	}
+
+	for _, spec := range strings.Split(s, ",") {
+		v := uriTemplateVar{name: spec}
+		if name, ok := strings.CutSuffix(spec, "*"); ok {
+			v.name = name
+			v.explode = true
+		} else if name, prefix, ok := strings.Cut(spec, ":"); ok {
+			n, err := strconv.Atoi(prefix)
+			if err != nil || n <= 0 || n >= 10000 {
+				return nil, fmt.Errorf("invalid prefix modifier %q", spec)
+			}
+			v.name = name
+			v.prefixLen = n
+		}
+		if !isValidURITemplateVarName(v.name) {
+			return nil, fmt.Errorf("invalid variable name %q", v.name)
+		}
+		expr.vars = append(expr.vars, v)
+	}
+	return expr, nil
+}
+
+// isValidURITemplateVarName reports whether name is a valid varname: ALPHA / DIGIT / "_" / pct-encoded, joined by ".".
+func isValidURITemplateVarName(name string) bool {
+	if name == "" || name[0] == '.' || name[len(name)-1] == '.' {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '_':
+		case c == '.':
+			if name[i-1] == '.' {
+				return false
+			}
+		case c == '%':
+			if i+2 >= len(name) || !isHex(name[i+1]) || !isHex(name[i+2]) {
+				return false
+			}
+			i += 2
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// compile builds the regular expression used by Match.
+func (t *URITemplate) compile() error {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, part := range t.parts {
+		if part.expr == nil {
+			b.WriteString(regexp.QuoteMeta(part.literal))
+			continue
+		}
+		for i, v := range part.expr.vars {
+			t.vars = append(t.vars, v.name)
+			switch part.expr.operator {
+			case 0:
+				if i > 0 {
+					b.WriteString(",")
+				}
+				b.WriteString("([^/?#,]*)")
+			case '+':
+				if i > 0 {
+					b.WriteString(",")
+				}
+				b.WriteString("([^?#,]*)")
+			case '#':
+				if i == 0 {
+					b.WriteString("#")
+				} else {
+					b.WriteString(",")
+				}
+				b.WriteString("([^,]*)")
+			case '.':
+				b.WriteString(`(?:\.([^./?#]*))?`)
+			case '/':
+				b.WriteString("(?:/([^/?#]*))?")
+			case ';':
+				b.WriteString("(?:;" + regexp.QuoteMeta(v.name) + "=?([^;/?#]*))?")
+			case '?', '&':
+				sep := "&"
+				if i == 0 && part.expr.operator == '?' {
+					sep = `\?`
+				}
+				b.WriteString("(?:" + sep + regexp.QuoteMeta(v.name) + "=([^&#]*))?")
+			}
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return err
+	}
+	t.re = re
+	return nil
+}
+
+// String returns the original template string.
+func (t *URITemplate) String() string { return t.raw }
+
+// Variables returns the names of the variables in the template in order of appearance.
+func (t *URITemplate) Variables() []string {
+	vars := make([]string, len(t.vars))
+	copy(vars, t.vars)
+	return vars
+}
+
+// Match matches uri against the template and returns the values of the variables.
+// Values are percent-decoded. If uri doesn't match the template, it returns false.
+func (t *URITemplate) Match(uri string) (map[string]string, bool) {
+	m := t.re.FindStringSubmatch(uri)
+	if m == nil {
+		return nil, false
+	}
+
+	values := make(map[string]string, len(t.vars))
+	for i, name := range t.vars {
+		v, err := url.PathUnescape(m[i+1])
+		if err != nil {
+			return nil, false
+		}
+		values[name] = v
+	}
+	return values, true
+}
@@ -0,0 +1,112 @@
+package mcp_test
+
+import (
+	"maps"
+	"testing"
+
+	mcp "github.com/ktr0731/go-mcp"
+)
+
+func TestParseURITemplate(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		template string
+		wantErr  bool
+	}{
+		"literal only":     {template: "weather://forecast"},
+		"simple":           {template: "weather://forecast/{city}"},
+		"multiple":         {template: "weather://historical/{city}/{date}"},
+		"operators":        {template: "file://{+path}{?lat,lon}"},
+		"modifiers":        {template: "file:///{dir:3}/{files*}"},
+		"unclosed":         {template: "weather://forecast/{city", wantErr: true},
+		"unopened":         {template: "weather://forecast/city}", wantErr: true},
+		"nested":           {template: "weather://{forecast/{city}}", wantErr: true},
+		"empty expression": {template: "weather://forecast/{}", wantErr: true},
+		"invalid name":     {template: "weather://forecast/{ci-ty}", wantErr: true},
+		"reserved op":      {template: "weather://forecast/{=city}", wantErr: true},
+		"invalid prefix":   {template: "weather://forecast/{city:0}", wantErr: true},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := mcp.ParseURITemplate(c.template)
+			if c.wantErr && err == nil {
+				t.Error("want an error, got nil")
+			}
+			if !c.wantErr && err != nil {
+				t.Errorf("want no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestURITemplate_Match(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		template string
+		uri      string
+		want     map[string]string
+		wantOK   bool
+	}{
+		"simple": {
+			template: "weather://forecast/{city}",
+			uri:      "weather://forecast/tokyo",
+			want:     map[string]string{"city": "tokyo"},
+			wantOK:   true,
+		},
+		"percent-encoded": {
+			template: "weather://forecast/{city}",
+			uri:      "weather://forecast/new%20york",
+			want:     map[string]string{"city": "new york"},
+			wantOK:   true,
+		},
+		"multiple": {
+			template: "weather://historical/{city}/{date}",
+			uri:      "weather://historical/tokyo/2025-01-01",
+			want:     map[string]string{"city": "tokyo", "date": "2025-01-01"},
+			wantOK:   true,
+		},
+		"query": {
+			template: "weather://forecast{?lat,lon}",
+			uri:      "weather://forecast?lat=35.6&lon=139.7",
+			want:     map[string]string{"lat": "35.6", "lon": "139.7"},
+			wantOK:   true,
+		},
+		"reserved": {
+			template: "file:///{+path}",
+			uri:      "file:///a/b/c.txt",
+			want:     map[string]string{"path": "a/b/c.txt"},
+			wantOK:   true,
+		},
+		"different prefix": {
+			template: "weather://forecast/{city}",
+			uri:      "weather://historical/tokyo",
+		},
+		"extra segment": {
+			template: "weather://forecast/{city}",
+			uri:      "weather://forecast/tokyo/today",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			tmpl, err := mcp.ParseURITemplate(c.template)
+			if err != nil {
+				t.Fatalf("failed to parse the template: %v", err)
+			}
+			got, ok := tmpl.Match(c.uri)
+			if ok != c.wantOK {
+				t.Fatalf("want ok %t, got %t", c.wantOK, ok)
+			}
+			if !maps.Equal(c.want, got) {
+				t.Errorf("want %v, got %v", c.want, got)
+			}
+		})
+	}
+}